@@ -6,8 +6,11 @@ import (
 	"github.com/skatteetaten/architect/v2/pkg/sporingslogger"
 	"net/url"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/skatteetaten/architect/v2/pkg/config"
 	"github.com/skatteetaten/architect/v2/pkg/docker"
@@ -17,6 +20,9 @@ import (
 	nodejs "github.com/skatteetaten/architect/v2/pkg/nodejs/prepare"
 	process "github.com/skatteetaten/architect/v2/pkg/process/build"
 	"github.com/skatteetaten/architect/v2/pkg/process/retag"
+	"github.com/skatteetaten/architect/v2/pkg/results"
+	"github.com/skatteetaten/architect/v2/pkg/telemetry"
+	"github.com/skatteetaten/architect/v2/pkg/webhook"
 )
 
 var verbose bool
@@ -33,8 +39,16 @@ type RunConfiguration struct {
 // RunArchitect main
 func RunArchitect(configuration RunConfiguration) {
 	c := configuration.Config
-	ctx := context.Background()
 	startTimer := time.Now()
+	if c.TelemetrySpec.Enabled() {
+		telemetry.SetDefault(telemetry.NewRecorder(
+			telemetry.Attr("apptype", string(c.ApplicationType)),
+			telemetry.Attr("repository", c.DockerSpec.OutputRepository)))
+	}
+	// SIGTERM, e.g. when the build pod is deleted, cancels the running stage
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	ctx, span := telemetry.StartSpan(ctx, "build")
 	logrus.Debugf("Config %+v", c)
 	logrus.Infof("ARCHITECT_APP_VERSION=%s,ARCHITECT_AURORA_VERSION=%s", os.Getenv("APP_VERSION"), os.Getenv("AURORA_VERSION"))
 
@@ -72,18 +86,56 @@ func RunArchitect(configuration RunConfiguration) {
 	pushRegistry := docker.NewRegistryClient(pushRegistryConn)
 	pullRegistry := docker.NewRegistryClient(pullRegistryConn)
 
+	if c.ApplicationSpec.BaseImageSpec.IsLocal() && c.DockerSpec.RetagWith == "" {
+		archive, err := docker.NewArchiveRegistry(c.ApplicationSpec.BaseImageSpec.LocalSource)
+		if err != nil {
+			logrus.Fatalf("Unable to read local base image: %s", err)
+		}
+		defer archive.Close()
+		// logrus.Fatal exits without running the deferred functions
+		logrus.RegisterExitHandler(archive.Close)
+		if c.ApplicationSpec.BaseImageSpec.BaseImage == "" {
+			c.ApplicationSpec.BaseImageSpec.BaseImage, c.ApplicationSpec.BaseImageSpec.BaseVersion = archive.ImageName()
+		}
+		pullRegistry = archive
+		if c.NoPush {
+			// Nothing is pushed, so the build does not need a registry at all
+			pushRegistry = archive
+		}
+	}
+
 	sporingsLoggerClient := sporingslogger.NewClient(c.Sporingstjeneste)
+	notifier := webhook.NewClient(c)
+	if c.ResultsDir != "" {
+		notifier = results.NewNotifier(notifier, c)
+	}
+	notifier.Notify(ctx, webhook.Event{Type: webhook.Started})
 
 	var builder process.Builder
 	builder = process.NewLayerBuilder(c, pushRegistry, pullRegistry)
 
+	targets, err := configuration.outputTargets()
+	if err != nil {
+		logrus.Fatalf("Could not set up the output targets: %s", err)
+	}
+
 	if c.DockerSpec.RetagWith != "" {
 		logrus.Info("Perform retag")
-		if err := retag.Retag(ctx, c, registryCredentials, pullRegistry, builder); err != nil {
+		err := retag.Retag(ctx, c, registryCredentials, configuration.RegistryCredentialsFunc, pullRegistry, notifier,
+			targets)
+		span.Finish(err)
+		exportTelemetry(c.TelemetrySpec)
+		if err != nil {
+			reportFailure(ctx, notifier, err, process.ErrorCategoryRetag)
 			logrus.Fatalf("Failed to retag temporary image %s", err)
 		}
 	} else {
-		if err := performBuild(ctx, &configuration, c, pullRegistry, pushRegistry, builder, sporingsLoggerClient); err != nil {
+		err := performBuild(ctx, &configuration, c, pullRegistry, pushRegistry, builder, sporingsLoggerClient, notifier,
+			targets)
+		span.Finish(err)
+		exportTelemetry(c.TelemetrySpec)
+		if err != nil {
+			reportFailure(ctx, notifier, err, process.ErrorCategoryUnknown)
 			var errorMessage string
 			if logrus.GetLevel() >= logrus.DebugLevel {
 				errorMessage = "Failed to build image: %+v, Terminating"
@@ -97,7 +149,8 @@ func RunArchitect(configuration RunConfiguration) {
 	logrus.Infof("Timer stage=RunArchitect apptype=%s registry=%s repository=%s timetaken=%.3fs", c.ApplicationType, c.DockerSpec.OutputRegistry, c.DockerSpec.OutputRepository, time.Since(startTimer).Seconds())
 }
 func performBuild(ctx context.Context, configuration *RunConfiguration, c *config.Config, pullRegistry docker.Registry,
-	pushRegistry docker.Registry, builder process.Builder, sporingsLoggerClient sporingslogger.Sporingslogger) error {
+	pushRegistry docker.Registry, builder process.Builder, sporingsLoggerClient sporingslogger.Sporingslogger,
+	notifier webhook.Notifier, targets []process.Target) error {
 	var prepper process.Prepper
 	if c.ApplicationType == config.JavaLeveransepakke {
 		logrus.Info("Perform Java build")
@@ -117,7 +170,71 @@ func performBuild(ctx context.Context, configuration *RunConfiguration, c *confi
 	ctx, cancel := context.WithTimeout(ctx, c.BuildTimeout*time.Second)
 	defer cancel()
 
-	return process.Build(ctx, pullRegistry, pushRegistry, c, configuration.NexusDownloader, prepper, builder, sporingsLoggerClient)
+	return process.Build(ctx, pullRegistry, pushRegistry, c, configuration.NexusDownloader, prepper, builder, sporingsLoggerClient, notifier,
+		targets)
+}
+
+// reportFailure log the stage that failed, and send the failed event
+func reportFailure(ctx context.Context, notifier webhook.Notifier, err error, defaultCategory string) {
+	category := process.ErrorCategory(err)
+	if category == process.ErrorCategoryUnknown {
+		category = defaultCategory
+	}
+	stage := process.FailedStage(err)
+	if stage != "" && stage != category {
+		logrus.Errorf("Stage %s failed: %s", stage, category)
+	}
+	notifier.Notify(ctx, webhook.Event{
+		Type:          webhook.Failed,
+		ErrorCategory: category,
+		Stage:         stage,
+		Error:         err.Error(),
+	})
+}
+
+// Export failures are logged. They never fail the build
+func exportTelemetry(spec config.TelemetrySpec) {
+	recorder := telemetry.Default()
+	if recorder == nil {
+		return
+	}
+	if spec.PrometheusTextfile != "" {
+		if err := recorder.WritePrometheusTextfile(spec.PrometheusTextfile); err != nil {
+			logrus.Warnf("Unable to write metrics: %v", err)
+		}
+	}
+	if spec.OTLPEndpoint != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		if err := telemetry.NewOTLPExporter(spec.OTLPEndpoint).Export(ctx, recorder); err != nil {
+			logrus.Warnf("Unable to export telemetry: %v", err)
+		}
+	}
+}
+
+// outputTargets a registry client for every output target, with the credentials of the target registry
+func (c RunConfiguration) outputTargets() ([]process.Target, error) {
+	var targets []process.Target
+	for _, spec := range c.Config.DockerSpec.OutputTargets {
+		credentials, err := c.RegistryCredentialsFunc(spec.Registry)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Output target %s", spec)
+		}
+		targetURL := url.URL{
+			Host:   spec.Registry,
+			Scheme: "https",
+		}
+		targets = append(targets, process.Target{
+			Spec: spec,
+			Registry: docker.NewRegistryClient(docker.RegistryConnectionInfo{
+				Port:        docker.GetPortOrDefault(targetURL.Port()),
+				Insecure:    docker.InsecureOrDefault(c.Config),
+				Host:        targetURL.Hostname(),
+				Credentials: credentials,
+			}),
+		})
+	}
+	return targets, nil
 }
 
 func (c RunConfiguration) getRegistryCredentials() (*docker.RegistryCredentials, error) {
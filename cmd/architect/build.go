@@ -1,6 +1,8 @@
 package architect
 
 import (
+	"os"
+
 	"github.com/sirupsen/logrus"
 	"github.com/skatteetaten/architect/v2/pkg/config"
 	"github.com/skatteetaten/architect/v2/pkg/docker"
@@ -12,16 +14,33 @@ import (
 var noPush bool
 
 func init() {
+	Build.PersistentFlags().StringP("config", "", "", "Settings file (default is $HOME/.architect.yaml)")
+	Build.PersistentFlags().StringP("profile", "", "", "Profile in the settings file e.g utv")
 	Build.Flags().StringP("file", "f", "", "Path to the compressed leveransepakke")
 	Build.Flags().StringP("type", "t", "java", "Application type [java, doozer, nodejs]")
 	Build.Flags().StringP("output", "o", "", "Output repository with tag e.g aurora/architect:latest")
-	Build.Flags().StringP("from", "", "", "Base image e.g aurora/wingnut11:latest")
-	Build.Flags().StringP("push-registry", "", "container-registry-internal.aurora.skead.no", "Push registry")
-	Build.Flags().StringP("pull-registry", "", "container-registry-internal-private-pull.aurora.skead.no", "Pull registry")
+	Build.Flags().StringP("from", "", "", "Base image e.g aurora/wingnut11:latest, oci-layout:/path or docker-archive:/path.tar")
+	Build.Flags().StringP("push-registry", "", "", "Push registry")
+	Build.Flags().StringP("pull-registry", "", "", "Pull registry. Not used with a local base image")
+	Build.Flags().StringP("ownership", "", "preserve", "File ownership in the application layer [preserve, arbitrary-uid, uid:gid]")
 	Build.Flags().BoolVarP(&noPush, "no-push", "", false, "If true the image is not pushed")
+	Build.Flags().BoolP("force-rebuild", "", false, "Build a new image even if an identical image exists")
+	Build.Flags().StringP("otlp-endpoint", "", "", "Export stage spans and metrics to an OTLP/HTTP collector e.g http://localhost:4318")
+	Build.Flags().StringP("metrics-textfile", "", "", "Write stage metrics to a Prometheus text file")
+	Build.Flags().StringArrayP("tag-template", "", nil, "Additional tag from a template e.g {{.Version}}-{{.GitCommitShort}}. Can be repeated")
+	Build.Flags().StringArrayP("output-target", "", nil, "Additional registry/repository[=extra,tags] the image is pushed to. Can be repeated")
+	Build.Flags().StringP("stage-timeouts", "", "", "Timeouts in seconds for the build stages e.g download=300,push=600")
+	Build.Flags().StringP("max-application-layer-size", "", "", "Size limit of the compressed application layers e.g 1GiB. No limit if empty")
+	Build.Flags().StringP("max-image-size", "", "", "Size limit of the compressed image including the base image e.g 3GiB. No limit if empty")
+	Build.Flags().StringP("max-file-size", "", "", "Size limit of a single file in the application layer e.g 512MiB. No limit if empty")
 	Build.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose logging")
 	Bc.Flags().StringP("file", "f", "", "Path to a build configuration file")
 	Bc.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose logging")
+	BuildFromFile.Flags().StringP("file", "f", "architect.yaml", "Path to an architect.yaml or architect.json build file")
+	BuildFromFile.Flags().StringP("deliverable", "d", "", "Path to the compressed leveransepakke. Downloaded from Nexus if empty")
+	BuildFromFile.Flags().BoolVarP(&noPush, "no-push", "", false, "If true the image is not pushed")
+	BuildFromFile.Flags().BoolP("force-rebuild", "", false, "Build a new image even if an identical image exists")
+	BuildFromFile.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose logging")
 
 }
 
@@ -30,6 +49,11 @@ var Build = &cobra.Command{
 	Use:   "build",
 	Short: "build file --file <file> --from <baseimage:version> --output <repository:tag> --type [java | nodejs | doozer]",
 	Long:  "build images from source",
+	// The settings apply to build and every build subcommand
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		_, err := ApplySettings(cmd, cmd)
+		return err
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 
 		var nexusDownloader nexus.Downloader
@@ -97,6 +121,12 @@ var Bc = &cobra.Command{
 		configPath := cmd.Flag("file").Value.String()
 		logrus.Debugf("Building from %s", configPath)
 
+		buildConfig, err := os.ReadFile(configPath)
+		if err != nil {
+			logrus.Fatalf("Could not read configuration: %s", err)
+		}
+		ValidateBuildConfig(buildConfig)
+
 		// Read build config
 		var configReader = config.NewFileConfigReader(configPath)
 
@@ -109,7 +139,55 @@ var Bc = &cobra.Command{
 			logrus.Fatalf("Unable to get Nexus credentials: %s", err)
 		}
 
-		nexusDownloader = nexus.NewMavenDownloader(nexusAccess.NexusURL, nexusAccess.Username, nexusAccess.Password)
+		nexusDownloader = nexus.NewMavenDownloader(*nexusAccess)
+
+		RunArchitect(RunConfiguration{
+			NexusDownloader:         nexusDownloader,
+			Config:                  c,
+			RegistryCredentialsFunc: docker.LocalRegistryCredentials(),
+		})
+	},
+}
+
+// BuildFromFile build command using an architect.yaml build file as input
+var BuildFromFile = &cobra.Command{
+	Use:   "from-file",
+	Short: "build from-file --file architect.yaml [--deliverable <file>]",
+	Long:  "Build images from an architect.yaml or architect.json build file",
+	Run: func(cmd *cobra.Command, args []string) {
+
+		var nexusDownloader nexus.Downloader
+		if verbose {
+			logrus.SetLevel(logrus.DebugLevel)
+		} else {
+			logrus.SetLevel(logrus.InfoLevel)
+		}
+
+		configPath := cmd.Flag("file").Value.String()
+		logrus.Debugf("Building from %s", configPath)
+
+		c, err := config.NewArchitectFileConfigReader(configPath).ReadConfig()
+		if err != nil {
+			logrus.Fatalf("Could not read configuration: %s", err)
+		}
+		c.NoPush = noPush
+		if cmd.Flag("force-rebuild").Value.String() == "true" {
+			c.SkipIdenticalBuilds = false
+		}
+
+		if deliverable := cmd.Flag("deliverable").Value.String(); deliverable != "" {
+			binaryInput, err := util.ExtractBinaryFromFile(deliverable)
+			if err != nil {
+				logrus.Fatalf("Could not read binary input: %s", err)
+			}
+			nexusDownloader = nexus.NewBinaryDownloader(binaryInput)
+		} else {
+			nexusAccess, err := config.ReadNexusAccessFromEnvVars()
+			if err != nil {
+				logrus.Fatalf("Unable to get Nexus credentials: %s", err)
+			}
+			nexusDownloader = nexus.NewMavenDownloader(*nexusAccess)
+		}
 
 		RunArchitect(RunConfiguration{
 			NexusDownloader:         nexusDownloader,
@@ -0,0 +1,138 @@
+package architect
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/skatteetaten/architect/v2/pkg/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	ConfigValidate.Flags().StringP("file", "f", "", "Path to an OpenShift build config, or an architect.yaml build file")
+	ConfigValidate.Flags().BoolP("skip-registries", "", false, "Do not check that the registries are reachable")
+	ConfigValidate.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose logging")
+	Config.AddCommand(ConfigValidate)
+	ConfigView.Flags().StringP("config", "", "", "Settings file (default is $HOME/.architect.yaml)")
+	ConfigView.Flags().StringP("profile", "", "", "Profile in the settings file e.g utv")
+	Config.AddCommand(ConfigView)
+}
+
+// Config command
+var Config = &cobra.Command{
+	Use:   "config",
+	Short: "Build configuration tools",
+}
+
+// ConfigValidate report every problem in a build configuration
+var ConfigValidate = &cobra.Command{
+	Use:   "validate",
+	Short: "validate --file <bc.json | architect.yaml>",
+	Long:  "Validate a build configuration and report every problem with a hint on how to fix it",
+	Run: func(cmd *cobra.Command, args []string) {
+		if verbose {
+			logrus.SetLevel(logrus.DebugLevel)
+		} else {
+			logrus.SetLevel(logrus.InfoLevel)
+		}
+
+		path := cmd.Flag("file").Value.String()
+		if path == "" {
+			if err := cmd.Help(); err != nil {
+				panic(err)
+			}
+			return
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			logrus.Fatalf("Could not read %s: %s", path, err)
+		}
+
+		var checkRegistry config.RegistryCheck = config.CheckRegistry
+		if cmd.Flag("skip-registries").Value.String() == "true" {
+			checkRegistry = nil
+		}
+
+		var problems config.Problems
+		if isBuildConfig(data) {
+			problems = config.ValidateBuildConfig(data, checkRegistry)
+		} else {
+			problems = config.ValidateArchitectFile(data, checkRegistry)
+		}
+		if len(problems) > 0 {
+			fmt.Println(problems.Error())
+			os.Exit(1)
+		}
+		fmt.Printf("%s is valid\n", path)
+	},
+}
+
+// ConfigView show the effective value of every option of a command, and where it comes from
+var ConfigView = &cobra.Command{
+	Use:   "view [command...]",
+	Short: "view build bc --profile utv",
+	Long: "Show the effective options of a command from the defaults, the settings file, the profile and the " +
+		config.SettingsEnvPrefix + "* environment variables",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			args = []string{"build"}
+		}
+		target, _, err := cmd.Root().Find(args)
+		if err != nil || target == cmd.Root() {
+			logrus.Fatalf("Unknown command %s", strings.Join(args, " "))
+		}
+		settings, err := ApplySettings(cmd, target)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		for _, setting := range settings {
+			fmt.Println(setting)
+		}
+	},
+}
+
+// ApplySettings set the options of the target command that are not given as flags from the environment, the profile
+// and the settings file selected by the --config and --profile flags of cmd
+func ApplySettings(cmd *cobra.Command, target *cobra.Command) ([]config.Setting, error) {
+	path := cmd.Flag("config").Value.String()
+	required := path != ""
+	if !required {
+		path = config.DefaultSettingsPath()
+	}
+	settings, err := config.LoadSettings(path, required)
+	if err != nil {
+		return nil, err
+	}
+	// The flags of a command that is not executed are not merged with the persistent flags of its parents yet
+	flags := pflag.NewFlagSet(target.Name(), pflag.ContinueOnError)
+	flags.AddFlagSet(target.LocalFlags())
+	flags.AddFlagSet(target.InheritedFlags())
+	return config.ApplySettings(flags, settings, cmd.Flag("profile").Value.String(), os.LookupEnv)
+}
+
+// An OpenShift build config is JSON with a kind or a spec
+func isBuildConfig(data []byte) bool {
+	var object struct {
+		Kind string          `json:"kind"`
+		Spec json.RawMessage `json:"spec"`
+	}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return false
+	}
+	return object.Kind == "Build" || object.Kind == "BuildConfig" || object.Spec != nil
+}
+
+// ValidateBuildConfig stop before the build when the build config has problems
+func ValidateBuildConfig(buildConfig []byte) {
+	problems := config.ValidateBuildConfig(buildConfig, config.CheckRegistry)
+	for _, warning := range problems.Warnings() {
+		logrus.Warn(warning)
+	}
+	if errs := problems.Errors(); len(errs) > 0 {
+		logrus.Fatal(errs.Error())
+	}
+}
@@ -0,0 +1,98 @@
+package architect
+
+import (
+	"context"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+	"github.com/skatteetaten/architect/v2/pkg/docker"
+	"github.com/skatteetaten/architect/v2/pkg/process/promote"
+	"github.com/skatteetaten/architect/v2/pkg/process/tagger"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	Promote.Flags().StringP("from", "", "", "Image to promote e.g registry-test.example.com/aurora/app:1.2.3")
+	Promote.Flags().StringP("to", "", "", "Destination repository e.g registry.example.com/aurora/app. A tag pushes only that tag")
+	Promote.Flags().StringP("versioning-scheme", "", "semver", "Versioning scheme of the application [semver, four-part, calver]")
+	Promote.Flags().BoolP("insecure", "", false, "Skip TLS verification of the registries")
+	Promote.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose logging")
+}
+
+// Promote command
+var Promote = &cobra.Command{
+	Use:   "promote",
+	Short: "promote --from <registry/repository:tag> --to <registry/repository>",
+	Long:  "Copy an image to another repository or registry, and tag it for the destination",
+	Run: func(cmd *cobra.Command, args []string) {
+		if verbose {
+			logrus.SetLevel(logrus.DebugLevel)
+		} else {
+			logrus.SetLevel(logrus.InfoLevel)
+		}
+
+		if cmd.Flag("from").Value.String() == "" || cmd.Flag("to").Value.String() == "" {
+			if err := cmd.Help(); err != nil {
+				panic(err)
+			}
+			return
+		}
+
+		from, err := promote.ParseImage(cmd.Flag("from").Value.String())
+		if err != nil {
+			logrus.Fatalf("--from: %s", err)
+		}
+		to, err := promote.ParseImage(cmd.Flag("to").Value.String())
+		if err != nil {
+			logrus.Fatalf("--to: %s", err)
+		}
+		scheme, err := tagger.NewVersionScheme(cmd.Flag("versioning-scheme").Value.String())
+		if err != nil {
+			logrus.Fatalf("--versioning-scheme: %s", err)
+		}
+		insecure := cmd.Flag("insecure").Value.String() == "true"
+
+		source, err := registryClient(from.Registry, insecure)
+		if err != nil {
+			logrus.Fatalf("Could not read registry credentials for %s: %s", from.Registry, err)
+		}
+		destination, err := registryClient(to.Registry, insecure)
+		if err != nil {
+			logrus.Fatalf("Could not read registry credentials for %s: %s", to.Registry, err)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+		defer stop()
+
+		promoter := &promote.Promoter{
+			Source:      source,
+			Destination: destination,
+			Scheme:      scheme,
+		}
+		tags, err := promoter.Promote(ctx, from, to)
+		if err != nil {
+			logrus.Fatalf("Failed to promote %s: %s", from, err)
+		}
+		logrus.Infof("Promoted %s to %s with tags %v", from, to, tags)
+	},
+}
+
+func registryClient(registry string, insecure bool) (docker.Registry, error) {
+	host, port, err := net.SplitHostPort(registry)
+	if err != nil {
+		host, port = registry, ""
+	}
+	credentials, err := docker.LocalRegistryCredentials()(registry)
+	if err != nil {
+		return nil, err
+	}
+	return docker.NewRegistryClient(docker.RegistryConnectionInfo{
+		Port:        docker.GetPortOrDefault(port),
+		Host:        host,
+		Insecure:    insecure,
+		Credentials: credentials,
+	}), nil
+}
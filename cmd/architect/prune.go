@@ -0,0 +1,78 @@
+package architect
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/skatteetaten/architect/v2/pkg/process/promote"
+	"github.com/skatteetaten/architect/v2/pkg/process/prune"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	Prune.Flags().StringP("repository", "r", "", "Repository to prune e.g registry.example.com/aurora/app")
+	Prune.Flags().IntP("keep", "", 5, "Number of unique snapshot tags to keep per snapshot version")
+	Prune.Flags().IntP("keep-days", "", 14, "Keep unique snapshot tags built within this number of days")
+	Prune.Flags().BoolP("dry-run", "", false, "Only log the tags that would be deleted")
+	Prune.Flags().BoolP("insecure", "", false, "Skip TLS verification of the registry")
+	Prune.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose logging")
+}
+
+// Prune command
+var Prune = &cobra.Command{
+	Use:   "prune",
+	Short: "prune --repository <registry/repository> --keep <n> --keep-days <days> [--dry-run]",
+	Long:  "Delete old unique snapshot tags from a repository. Tags that look like releases are never deleted",
+	Run: func(cmd *cobra.Command, args []string) {
+		if verbose {
+			logrus.SetLevel(logrus.DebugLevel)
+		} else {
+			logrus.SetLevel(logrus.InfoLevel)
+		}
+
+		if cmd.Flag("repository").Value.String() == "" {
+			if err := cmd.Help(); err != nil {
+				panic(err)
+			}
+			return
+		}
+
+		repository, err := promote.ParseImage(cmd.Flag("repository").Value.String())
+		if err != nil {
+			logrus.Fatalf("--repository: %s", err)
+		}
+		keep, err := cmd.Flags().GetInt("keep")
+		if err != nil || keep < 0 {
+			logrus.Fatalf("--keep must be zero or more")
+		}
+		keepDays, err := cmd.Flags().GetInt("keep-days")
+		if err != nil || keepDays < 0 {
+			logrus.Fatalf("--keep-days must be zero or more")
+		}
+		dryRun := cmd.Flag("dry-run").Value.String() == "true"
+
+		registry, err := registryClient(repository.Registry, cmd.Flag("insecure").Value.String() == "true")
+		if err != nil {
+			logrus.Fatalf("Could not read registry credentials for %s: %s", repository.Registry, err)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+		defer stop()
+
+		policy := prune.Policy{
+			KeepLast:        keep,
+			KeepYoungerThan: time.Duration(keepDays) * 24 * time.Hour,
+		}
+		plan, err := prune.NewPlan(ctx, registry, repository.Repository, policy, time.Now())
+		if err != nil {
+			logrus.Fatalf("Failed to apply the retention policy to %s: %s", repository, err)
+		}
+		if err := prune.Prune(ctx, registry, repository.Repository, plan, dryRun); err != nil {
+			logrus.Fatalf("Failed to prune %s: %s", repository, err)
+		}
+	},
+}
@@ -0,0 +1,97 @@
+package architect
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/skatteetaten/architect/v2/pkg/config"
+	"github.com/skatteetaten/architect/v2/pkg/config/runtime"
+	"github.com/skatteetaten/architect/v2/pkg/process/promote"
+	"github.com/skatteetaten/architect/v2/pkg/process/tagger"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	TagsExplain.Flags().StringP("version", "", "", "Version of the deliverable e.g 2.3.5")
+	TagsExplain.Flags().StringP("complete-version", "", "", "Complete version of the image. Defaults to the version")
+	TagsExplain.Flags().StringP("repository", "r", "", "Repository with the existing tags e.g registry.example.com/aurora/app")
+	TagsExplain.Flags().StringP("tags", "", "", "Comma separated list of existing tags, instead of the repository")
+	TagsExplain.Flags().StringP("extra-tags", "", "latest,major,minor,patch", "The extra tags of the build")
+	TagsExplain.Flags().StringP("versioning-scheme", "", "semver", "Versioning scheme of the application [semver, four-part, calver]")
+	TagsExplain.Flags().BoolP("insecure", "", false, "Skip TLS verification of the registry")
+	TagsExplain.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose logging")
+	Tags.AddCommand(TagsExplain)
+}
+
+// Tags command
+var Tags = &cobra.Command{
+	Use:   "tags",
+	Short: "Image tag tools",
+}
+
+// TagsExplain explain which tags a build pushes, and why
+var TagsExplain = &cobra.Command{
+	Use:   "explain",
+	Short: "explain --version <version> [--repository <registry/repository> | --tags <tag,tag>]",
+	Long:  "Explain for every candidate tag of a version if it is pushed or excluded, and why",
+	Run: func(cmd *cobra.Command, args []string) {
+		if verbose {
+			logrus.SetLevel(logrus.DebugLevel)
+		} else {
+			logrus.SetLevel(logrus.InfoLevel)
+		}
+
+		version := cmd.Flag("version").Value.String()
+		if version == "" {
+			if err := cmd.Help(); err != nil {
+				panic(err)
+			}
+			return
+		}
+		completeVersion := cmd.Flag("complete-version").Value.String()
+		if completeVersion == "" {
+			completeVersion = version
+		}
+		appVersion := runtime.NewAuroraVersion(version, strings.Contains(version, "SNAPSHOT"), version,
+			runtime.CompleteVersion(completeVersion))
+		extraTags := config.ParseExtraTags(cmd.Flag("extra-tags").Value.String())
+
+		scheme, err := tagger.NewVersionScheme(cmd.Flag("versioning-scheme").Value.String())
+		if err != nil {
+			logrus.Fatalf("--versioning-scheme: %s", err)
+		}
+
+		var repositoryTags []string
+		if repository := cmd.Flag("repository").Value.String(); repository != "" {
+			image, err := promote.ParseImage(repository)
+			if err != nil {
+				logrus.Fatalf("--repository: %s", err)
+			}
+			registry, err := registryClient(image.Registry, cmd.Flag("insecure").Value.String() == "true")
+			if err != nil {
+				logrus.Fatalf("Could not read registry credentials for %s: %s", image.Registry, err)
+			}
+			tagsInRepo, err := registry.GetTags(context.Background(), image.Repository)
+			if err != nil {
+				logrus.Fatalf("Failed to get tags of %s: %s", repository, err)
+			}
+			repositoryTags = tagsInRepo.Tags
+		} else {
+			for _, tag := range strings.Split(cmd.Flag("tags").Value.String(), ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					repositoryTags = append(repositoryTags, tag)
+				}
+			}
+		}
+
+		decisions, err := tagger.ExplainTags(appVersion, extraTags, repositoryTags, scheme)
+		if err != nil {
+			logrus.Fatalf("Failed to explain the tags of %s: %s", version, err)
+		}
+		for _, decision := range decisions {
+			fmt.Println(decision)
+		}
+	},
+}
@@ -7,8 +7,6 @@ import (
 	"os"
 )
 
-var cfgFile string
-
 // RootCmd represents the base command when called without any subcommands
 var RootCmd = &cobra.Command{
 	Use:   "architect",
@@ -32,20 +30,11 @@ func Execute() {
 }
 
 func init() {
-	cobra.OnInitialize(initConfig)
 	architect.Build.AddCommand(architect.Bc)
+	architect.Build.AddCommand(architect.BuildFromFile)
 	RootCmd.AddCommand(architect.Build)
-	// Here you will define your flags and configuration settings.
-	// Cobra supports Persistent Flags, which, if defined here,
-	// will be global for your application.
-
-	//RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.architect.yaml)")
-	// Cobra also supports local flags, which will only run
-	// when this action is called directly.
-	//RootCmd.Flags().BoolP("verbose", "v", false, "Verbose logging")
-}
-
-// initConfig reads in config file and ENV variables if set.
-func initConfig() {
-	//TODO
+	RootCmd.AddCommand(architect.Promote)
+	RootCmd.AddCommand(architect.Prune)
+	RootCmd.AddCommand(architect.Tags)
+	RootCmd.AddCommand(architect.Config)
 }
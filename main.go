@@ -15,7 +15,6 @@
 package main
 
 import (
-	"errors"
 	"github.com/sirupsen/logrus"
 	"github.com/skatteetaten/architect/v2/cmd"
 	"github.com/skatteetaten/architect/v2/cmd/architect"
@@ -45,8 +44,25 @@ func initializeAndRunOnOpenShift() {
 		logrus.Debugf("Environment %s", env)
 	}
 
+	// Without the OpenShift BUILD env, the build is described by Tekton or Shipwright parameters
+	var configReader config.Reader
+	registryCredentialsFunc := docker.ClusterRegistryCredentials()
+	if _, ok := os.LookupEnv("BUILD"); ok {
+		architect.ValidateBuildConfig([]byte(os.Getenv("BUILD")))
+		configReader = config.NewInClusterConfigReader()
+	} else {
+		logrus.Info("No BUILD env. Reading the build parameters")
+		paramsReader := config.NewParamsConfigReader()
+		buildConfig, err := paramsReader.BuildConfig()
+		if err != nil {
+			logrus.Fatalf("Could not read parameters: %s", err)
+		}
+		architect.ValidateBuildConfig(buildConfig)
+		configReader = paramsReader
+		registryCredentialsFunc = docker.LocalRegistryCredentials()
+	}
+
 	// Read build config
-	configReader := config.NewInClusterConfigReader()
 	c, err := configReader.ReadConfig()
 	if err != nil {
 		logrus.Fatalf("Could not read configuration: %s", err)
@@ -64,17 +80,22 @@ func initializeAndRunOnOpenShift() {
 		}
 		nexusDownloader = nexus.NewBinaryDownloader(binaryInput)
 	} else {
-		nexusAccess, err := config.ReadNexusConfigFromFileSystem()
-		if err != nil {
-			logrus.Fatalf("Error reading NexusAccess, and build is not binary: %s", errors.Unwrap(err))
+		nexusAccess, fileErr := config.ReadNexusConfigFromFileSystem()
+		if fileErr != nil {
+			// Tekton tasks usually get the Nexus credentials as env variables
+			var envErr error
+			nexusAccess, envErr = config.ReadNexusAccessFromEnvVars()
+			if envErr != nil {
+				logrus.Fatalf("Error reading NexusAccess, and build is not binary: %s. From env: %s", fileErr, envErr)
+			}
 		}
 		logrus.Debugf("Using Maven repo on %s", nexusAccess.NexusURL)
-		nexusDownloader = nexus.NewMavenDownloader(nexusAccess.NexusURL, nexusAccess.Username, nexusAccess.Password)
+		nexusDownloader = nexus.NewMavenDownloader(*nexusAccess)
 	}
 	runConfig := architect.RunConfiguration{
 		Config:                  c,
 		NexusDownloader:         nexusDownloader,
-		RegistryCredentialsFunc: docker.ClusterRegistryCredentials(),
+		RegistryCredentialsFunc: registryCredentialsFunc,
 	}
 	architect.RunArchitect(runConfig)
 }
@@ -1,19 +1,20 @@
 package config
 
 import (
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"github.com/docker/distribution/reference"
 	buildv1 "github.com/openshift/api/build/v1"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"github.com/skatteetaten/architect/v2/pkg/util"
 	"github.com/spf13/cobra"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -76,9 +77,17 @@ func (m *CmdConfigReader) ReadConfig() (*Config, error) {
 	}
 
 	fromraw := m.Cmd.Flag("from").Value.String()
-	from := strings.Split(fromraw, ":")
-	if len(from) != 2 {
-		return nil, errors.New("--from: baseimage is malformed: " + fromraw)
+	baseImageSpec := DockerBaseImageSpec{}
+	if IsLocalBaseImageReference(fromraw) {
+		// Name and version are read from the archive
+		baseImageSpec.LocalSource = fromraw
+	} else {
+		from := strings.Split(fromraw, ":")
+		if len(from) != 2 {
+			return nil, errors.New("--from: baseimage is malformed: " + fromraw)
+		}
+		baseImageSpec.BaseImage = from[0]
+		baseImageSpec.BaseVersion = from[1]
 	}
 
 	outputraw := m.Cmd.Flag("output").Value.String()
@@ -87,13 +96,61 @@ func (m *CmdConfigReader) ReadConfig() (*Config, error) {
 		return nil, errors.New("--output: repository is malformed: " + outputraw)
 	}
 
+	// The registries have no defaults. They are set in the settings file, a profile, the environment or with flags
 	pushRegistry := m.Cmd.Flag("push-registry").Value.String()
+	if pushRegistry == "" {
+		return nil, errors.Errorf("--push-registry is not set. Set it with the flag, %s or push-registry in the settings",
+			SettingsEnvName("push-registry"))
+	}
 	pullRegistry := m.Cmd.Flag("pull-registry").Value.String()
+	if pullRegistry == "" && baseImageSpec.LocalSource == "" {
+		return nil, errors.Errorf("--pull-registry is not set. Set it with the flag, %s or pull-registry in the settings",
+			SettingsEnvName("pull-registry"))
+	}
 
-	if !strings.Contains(pullRegistry, "http") {
+	if pullRegistry != "" && !strings.Contains(pullRegistry, "http") {
 		pullRegistry = fmt.Sprintf("https://%s", pullRegistry)
 	}
 
+	ownershipPolicy, err := util.ParseOwnershipPolicy(m.Cmd.Flag("ownership").Value.String())
+	if err != nil {
+		return nil, errors.Wrap(err, "--ownership")
+	}
+
+	tagTemplates, err := m.Cmd.Flags().GetStringArray("tag-template")
+	if err != nil {
+		return nil, errors.Wrap(err, "--tag-template")
+	}
+
+	stageTimeouts, err := ParseStageTimeouts(m.Cmd.Flag("stage-timeouts").Value.String())
+	if err != nil {
+		return nil, errors.Wrap(err, "--stage-timeouts")
+	}
+
+	outputTargetValues, err := m.Cmd.Flags().GetStringArray("output-target")
+	if err != nil {
+		return nil, errors.Wrap(err, "--output-target")
+	}
+	var outputTargets []OutputTarget
+	for _, value := range outputTargetValues {
+		target, err := ParseOutputTarget(value, PushExtraTags{})
+		if err != nil {
+			return nil, errors.Wrap(err, "--output-target")
+		}
+		outputTargets = append(outputTargets, target)
+	}
+
+	sizeLimits := make(map[string]string)
+	for flag, name := range sizeLimitFlags {
+		if value := m.Cmd.Flag(flag).Value.String(); value != "" {
+			sizeLimits[name] = value
+		}
+	}
+	sizePolicy, err := findSizePolicy(sizeLimits)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
 		NoPush:          m.NoPush,
 		BinaryBuild:     true,
@@ -103,10 +160,7 @@ func (m *CmdConfigReader) ReadConfig() (*Config, error) {
 			MavenGav: MavenGav{
 				Version: output[1],
 			},
-			BaseImageSpec: DockerBaseImageSpec{
-				BaseImage:   from[0],
-				BaseVersion: from[1],
-			},
+			BaseImageSpec: baseImageSpec,
 		},
 		DockerSpec: DockerSpec{
 			ExternalDockerRegistry: pushRegistry,
@@ -114,13 +168,24 @@ func (m *CmdConfigReader) ReadConfig() (*Config, error) {
 			OutputRegistry:         pushRegistry,
 			OutputRepository:       output[0],
 			TagWith:                output[1],
+			TagTemplates:           tagTemplates,
+			OutputTargets:          outputTargets,
+		},
+		BuildTimeout:        900,
+		OwnershipPolicy:     ownershipPolicy,
+		SizePolicy:          sizePolicy,
+		SkipIdenticalBuilds: m.Cmd.Flag("force-rebuild").Value.String() != "true",
+		TelemetrySpec: TelemetrySpec{
+			OTLPEndpoint:       m.Cmd.Flag("otlp-endpoint").Value.String(),
+			PrometheusTextfile: m.Cmd.Flag("metrics-textfile").Value.String(),
 		},
-		BuildTimeout: 900,
+		StageTimeouts: stageTimeouts,
 	}, nil
 
 }
 
-// ReadNexusConfigFromFileSystem read nexusUrl, nexusUser, and nexusPassword from file and return NexusAccess
+// ReadNexusConfigFromFileSystem read nexusUrl, nexusUser, nexusPassword and the optional repositoryPath and
+// snapshotRepositoryPath from file and return NexusAccess
 func ReadNexusConfigFromFileSystem() (*NexusAccess, error) {
 	nexusAccess := NexusAccess{}
 	secretPath := "/u01/nexus/nexus.json"
@@ -134,18 +199,23 @@ func ReadNexusConfigFromFileSystem() (*NexusAccess, error) {
 		nexusAccess.NexusURL = data["nexusUrl"].(string)
 		nexusAccess.Username = data["username"].(string)
 		nexusAccess.Password = data["password"].(string)
+		nexusAccess.RepositoryPath, _ = data["repositoryPath"].(string)
+		nexusAccess.SnapshotRepositoryPath, _ = data["snapshotRepositoryPath"].(string)
 	} else {
 		return nil, errors.Errorf("Could not read nexus config at %s, error: %s", secretPath, err)
 	}
 	return &nexusAccess, nil
 }
 
-// ReadNexusAccessFromEnvVars read nexusUrl, nesusUser, and nexusPassword from env variables and return NexusAccess
+// ReadNexusAccessFromEnvVars read nexusUrl, nesusUser, nexusPassword and the optional repository paths from env
+// variables and return NexusAccess
 func ReadNexusAccessFromEnvVars() (*NexusAccess, error) {
 	nexusAccess := NexusAccess{}
 	nexusAccess.Username, _ = os.LookupEnv("NEXUS_USERNAME")
 	nexusAccess.Password, _ = os.LookupEnv("NEXUS_PASSWORD")
 	nexusAccess.NexusURL, _ = os.LookupEnv("NEXUS_URL")
+	nexusAccess.RepositoryPath, _ = os.LookupEnv("NEXUS_REPOSITORY_PATH")
+	nexusAccess.SnapshotRepositoryPath, _ = os.LookupEnv("NEXUS_SNAPSHOT_REPOSITORY_PATH")
 	if nexusAccess.IsValid() {
 		return &nexusAccess, nil
 	}
@@ -208,17 +278,16 @@ func newConfig(buildConfig []byte, rewriteDockerRepositoryName bool) (*Config, e
 
 	var tlsVerify = true
 	if value, err := findEnv(env, "TLS_VERIFY"); err == nil {
-		if strings.Contains(strings.ToLower(value), "false") {
-			tlsVerify = false
-		}
+		tlsVerify = parseTLSVerify(value)
 	}
 
 	var buildTimeout time.Duration = 900
 	if value, err := findEnv(env, "BUILD_TIMEOUT_IN_S"); err == nil {
 		i, err := strconv.Atoi(value)
-		if err == nil {
-			buildTimeout = time.Duration(i)
+		if err != nil {
+			return nil, errors.Wrap(err, "BUILD_TIMEOUT_IN_S")
 		}
+		buildTimeout = time.Duration(i)
 	}
 
 	applicationSpec := ApplicationSpec{}
@@ -254,7 +323,17 @@ func newConfig(buildConfig []byte, rewriteDockerRepositoryName bool) (*Config, e
 		applicationSpec.MavenGav.Type = TgzPackaging
 	}
 
-	if baseSpec, err := findBaseImage(env); err == nil {
+	if baseImages, err := findEnv(env, "DOCKER_BASE_IMAGES"); err == nil {
+		// A matrix build, where the first base image is the base image of the configuration
+		applicationSpec.BaseImageMatrix, err = ParseBaseImageMatrix(baseImages)
+		if err != nil {
+			return nil, errors.Wrap(err, "DOCKER_BASE_IMAGES")
+		}
+		if err := checkMatrixVersion(applicationSpec.MavenGav.Version); err != nil {
+			return nil, errors.Wrap(err, "DOCKER_BASE_IMAGES")
+		}
+		applicationSpec.BaseImageSpec = applicationSpec.BaseImageMatrix[0]
+	} else if baseSpec, err := findBaseImage(env); err == nil {
 		applicationSpec.BaseImageSpec = baseSpec
 	} else {
 		return nil, err
@@ -262,11 +341,6 @@ func newConfig(buildConfig []byte, rewriteDockerRepositoryName bool) (*Config, e
 
 	dockerSpec := DockerSpec{}
 
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
-	client := &http.Client{Transport: tr}
-
 	if externalRegistry, err := findEnv(env, "BASE_IMAGE_REGISTRY"); err == nil {
 		if strings.HasPrefix(externalRegistry, "https://") {
 			dockerSpec.ExternalDockerRegistry = externalRegistry
@@ -279,12 +353,9 @@ func newConfig(buildConfig []byte, rewriteDockerRepositoryName bool) (*Config, e
 			logrus.Errorf("Failed to parse dockerimage-url from BC for ExternalDockerRegistry")
 		} else {
 			base := registryURL.Host
-			if err := checkURL(client, "https://", base, "/v2/"); err == nil {
-				dockerSpec.ExternalDockerRegistry = "https://" + base
-				logrus.Debugf("Using https: %s", dockerSpec.ExternalDockerRegistry)
-			} else if err := checkURL(client, "http://", base, "/v2/"); err == nil {
-				dockerSpec.ExternalDockerRegistry = "http://" + base
-				logrus.Debugf("Using insecure registry: %s", dockerSpec.ExternalDockerRegistry)
+			if externalRegistry, err := probeRegistry(base, tlsVerify); err == nil {
+				dockerSpec.ExternalDockerRegistry = externalRegistry
+				logrus.Debugf("Using registry: %s", dockerSpec.ExternalDockerRegistry)
 			} else {
 				logrus.Errorf("Failed to access url %s from BC for ExternalDockerRegistry", base)
 			}
@@ -295,13 +366,9 @@ func newConfig(buildConfig []byte, rewriteDockerRepositoryName bool) (*Config, e
 	}
 
 	if internalPullRegistry, err := findEnv(env, "INTERNAL_PULL_REGISTRY"); err == nil {
-		base := internalPullRegistry
-		if err := checkURL(client, "https://", base, "/v2/"); err == nil {
-			dockerSpec.InternalPullRegistry = "https://" + base
-			logrus.Debugf("Using https: %s", dockerSpec.ExternalDockerRegistry)
-		} else if err := checkURL(client, "http://", base, "/v2/"); err == nil {
-			dockerSpec.InternalPullRegistry = "http://" + base
-			logrus.Debugf("Using insecure registry: %s", dockerSpec.ExternalDockerRegistry)
+		if pullRegistry, err := probeRegistry(internalPullRegistry, tlsVerify); err == nil {
+			dockerSpec.InternalPullRegistry = pullRegistry
+			logrus.Debugf("Using registry: %s", dockerSpec.InternalPullRegistry)
 		} else {
 			logrus.Errorf("Failed to access url %s for InternalPullRegistry.", internalPullRegistry)
 		}
@@ -323,6 +390,34 @@ func newConfig(buildConfig []byte, rewriteDockerRepositoryName bool) (*Config, e
 		dockerSpec.RetagWith = temporaryTag
 	}
 
+	if retagDigest, err := findEnv(env, "RETAG_DIGEST"); err == nil {
+		dockerSpec.RetagDigest = retagDigest
+	}
+
+	if value, err := findEnv(env, "RETAG_DRY_RUN"); err == nil {
+		dockerSpec.RetagDryRun, err = strconv.ParseBool(value)
+		if err != nil {
+			return nil, errors.Wrap(err, "RETAG_DRY_RUN")
+		}
+	}
+
+	if tagTemplates, err := findEnv(env, "TAG_TEMPLATES"); err == nil {
+		dockerSpec.TagTemplates = ParseTagTemplates(tagTemplates)
+	}
+
+	if outputTargets, err := findEnv(env, "OUTPUT_TARGETS"); err == nil {
+		dockerSpec.OutputTargets, err = ParseOutputTargets(outputTargets, dockerSpec.PushExtraTags)
+		if err != nil {
+			return nil, errors.Wrap(err, "OUTPUT_TARGETS")
+		}
+	}
+
+	versioningScheme, err := ParseVersioningScheme(env["VERSIONING_SCHEME"])
+	if err != nil {
+		return nil, errors.Wrap(err, "VERSIONING_SCHEME")
+	}
+	dockerSpec.VersioningScheme = versioningScheme
+
 	// TagOverwrite has been removed since 10.2021. Kept to logg usage
 	if _, err := findEnv(env, "TAG_OVERWRITE"); err == nil {
 		logrus.Warning("Functionality for TAG_OVERWRITE has been removed")
@@ -333,11 +428,46 @@ func newConfig(buildConfig []byte, rewriteDockerRepositoryName bool) (*Config, e
 		buildType = BinaryBuildType(envBuildType)
 	}
 
+	ownershipPolicy := util.OwnershipPolicy{}
+	if value, err := findEnv(env, "FILE_OWNERSHIP"); err == nil {
+		ownershipPolicy, err = util.ParseOwnershipPolicy(value)
+		if err != nil {
+			return nil, errors.Wrap(err, "FILE_OWNERSHIP")
+		}
+	}
+
 	var nexusIqReportURL string
 	if envNexusIqReportURL, err := findEnv(env, "IMAGE_LABEL_NEXUS_IQ_REPORT_URL"); err == nil {
 		nexusIqReportURL = envNexusIqReportURL
 	}
 
+	sourceSpec := findSource(build, env)
+
+	sizePolicy, err := findSizePolicy(env)
+	if err != nil {
+		return nil, err
+	}
+
+	skipIdenticalBuilds := true
+	if value, err := findEnv(env, "SKIP_IDENTICAL_BUILDS"); err == nil {
+		skipIdenticalBuilds, err = strconv.ParseBool(value)
+		if err != nil {
+			return nil, errors.Wrap(err, "SKIP_IDENTICAL_BUILDS")
+		}
+	}
+
+	telemetrySpec := TelemetrySpec{
+		OTLPEndpoint:       findEnvOrProcessEnv(env, "OTEL_EXPORTER_OTLP_ENDPOINT"),
+		PrometheusTextfile: findEnvOrProcessEnv(env, "METRICS_TEXTFILE"),
+	}
+
+	webhooks := findWebhooks(env)
+
+	stageTimeouts, err := ParseStageTimeouts(findEnvOrProcessEnv(env, "STAGE_TIMEOUTS_IN_S"))
+	if err != nil {
+		return nil, errors.Wrap(err, "STAGE_TIMEOUTS_IN_S")
+	}
+
 	builderSpec := BuilderSpec{}
 
 	if builderVersion, present := os.LookupEnv("APP_VERSION"); present {
@@ -403,21 +533,34 @@ func newConfig(buildConfig []byte, rewriteDockerRepositoryName bool) (*Config, e
 	logrus.Debugf("Pushing to %s/%s:%s", dockerSpec.OutputRegistry, dockerSpec.OutputRepository, dockerSpec.TagWith)
 
 	c := &Config{
-		ApplicationType:    applicationType,
-		ApplicationSpec:    applicationSpec,
-		DockerSpec:         dockerSpec,
-		BuilderSpec:        builderSpec,
-		BinaryBuild:        binaryBuild,
-		TLSVerify:          tlsVerify,
-		BuildTimeout:       buildTimeout,
-		Sporingstjeneste:   sporingstjeneste,
-		OwnerReferenceUUID: string(build.UID),
-		BinaryBuildType:    buildType,
-		NexusIQReportURL:   nexusIqReportURL,
+		ApplicationType:     applicationType,
+		ApplicationSpec:     applicationSpec,
+		DockerSpec:          dockerSpec,
+		BuilderSpec:         builderSpec,
+		BinaryBuild:         binaryBuild,
+		TLSVerify:           tlsVerify,
+		BuildTimeout:        buildTimeout,
+		Sporingstjeneste:    sporingstjeneste,
+		OwnerReferenceUUID:  string(build.UID),
+		BinaryBuildType:     buildType,
+		NexusIQReportURL:    nexusIqReportURL,
+		OwnershipPolicy:     ownershipPolicy,
+		SourceSpec:          sourceSpec,
+		SizePolicy:          sizePolicy,
+		SkipIdenticalBuilds: skipIdenticalBuilds,
+		TelemetrySpec:       telemetrySpec,
+		Webhooks:            webhooks,
+		StageTimeouts:       stageTimeouts,
+		BuildEnv:            env,
 	}
 	return c, nil
 }
 
+// parseTLSVerify TLS is verified unless the value contains false
+func parseTLSVerify(value string) bool {
+	return !strings.Contains(strings.ToLower(value), "false")
+}
+
 func checkURL(client *http.Client, protocol string, base string, path string) error {
 	res, err := client.Get(protocol + base + path)
 	if err == nil {
@@ -463,8 +606,57 @@ func resolveIPIfInternalRegistry(registryWithPort string, rewrite bool) (string,
 	return registryWithPort, nil
 }
 
+// Binary builds have no git source, so the source can be given with IMAGE_LABEL_SOURCE and IMAGE_LABEL_REVISION
+func findSource(build buildv1.Build, env map[string]string) SourceSpec {
+	sourceSpec := SourceSpec{}
+	if build.Spec.Source.Git != nil {
+		sourceSpec.URL = build.Spec.Source.Git.URI
+	}
+	if build.Spec.Revision != nil && build.Spec.Revision.Git != nil {
+		sourceSpec.Revision = build.Spec.Revision.Git.Commit
+	}
+	if url, err := findEnv(env, "IMAGE_LABEL_SOURCE"); err == nil {
+		sourceSpec.URL = url
+	}
+	if revision, err := findEnv(env, "IMAGE_LABEL_REVISION"); err == nil {
+		sourceSpec.Revision = revision
+	}
+	return sourceSpec
+}
+
+// The flags of the local build that set the size limits, and the build variable of each
+var sizeLimitFlags = map[string]string{
+	"max-application-layer-size": "MAX_APPLICATION_LAYER_SIZE",
+	"max-image-size":             "MAX_IMAGE_SIZE",
+	"max-file-size":              "MAX_FILE_SIZE",
+}
+
+func findSizePolicy(env map[string]string) (SizePolicy, error) {
+	sizePolicy := SizePolicy{}
+	limits := map[string]*int64{
+		"MAX_APPLICATION_LAYER_SIZE": &sizePolicy.MaxApplicationLayerSize,
+		"MAX_IMAGE_SIZE":             &sizePolicy.MaxImageSize,
+		"MAX_FILE_SIZE":              &sizePolicy.MaxFileSize,
+	}
+	for name, limit := range limits {
+		if value, err := findEnv(env, name); err == nil {
+			size, err := util.ParseByteSize(value)
+			if err != nil {
+				return SizePolicy{}, errors.Wrap(err, name)
+			}
+			*limit = size
+		}
+	}
+	return sizePolicy, nil
+}
+
 func findBaseImage(env map[string]string) (DockerBaseImageSpec, error) {
 	baseSpec := DockerBaseImageSpec{}
+	if baseImage, err := findEnv(env, "DOCKER_BASE_IMAGE"); err == nil && IsLocalBaseImageReference(baseImage) {
+		// Name and version are read from the archive
+		baseSpec.LocalSource = baseImage
+		return baseSpec, nil
+	}
 	if baseImage, err := findEnv(env, "DOCKER_BASE_IMAGE"); err == nil {
 		baseSpec.BaseImage = baseImage
 	} else if baseImage, err := findEnv(env, "DOCKER_BASE_NAME"); err == nil {
@@ -513,6 +705,172 @@ func findOutputTag(dockerName string) (string, error) {
 	return "", errors.Errorf("Could not parse tag from %s", dockerName)
 }
 
+// WEBHOOK_URLS is a comma separated list. The secret is usually mounted in the builder pod, and not in the build config
+func findWebhooks(env map[string]string) []WebhookEndpoint {
+	secret := findEnvOrProcessEnv(env, "WEBHOOK_SECRET")
+	var webhooks []WebhookEndpoint
+	for _, url := range strings.Split(findEnvOrProcessEnv(env, "WEBHOOK_URLS"), ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			webhooks = append(webhooks, WebhookEndpoint{URL: url, Secret: secret})
+		}
+	}
+	return webhooks
+}
+
+// ParseTagTemplates split a comma separated list of tag templates
+func ParseTagTemplates(value string) []string {
+	var templates []string
+	for _, tagTemplate := range strings.Split(value, ",") {
+		if tagTemplate = strings.TrimSpace(tagTemplate); tagTemplate != "" {
+			templates = append(templates, tagTemplate)
+		}
+	}
+	return templates
+}
+
+// The version metadata the tagger accepts
+var validTagMetadata = regexp.MustCompile(`^[0-9A-Za-z]+$`)
+
+// ParseBaseImageMatrix parse a comma separated list of name:version[=metadata], e.g.
+// aurora/wingnut11:1,aurora/wingnut17:2=jdk17. The tag metadata is the last part of the name by default
+func ParseBaseImageMatrix(value string) ([]DockerBaseImageSpec, error) {
+	var matrix []DockerBaseImageSpec
+	metadata := make(map[string]bool)
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		image, tagMetadata, _ := strings.Cut(entry, "=")
+		i := strings.LastIndex(image, ":")
+		if i <= 0 || i == len(image)-1 {
+			return nil, errors.Errorf("Expected name:version[=metadata], got %s", entry)
+		}
+		name := image[:i]
+		if tagMetadata == "" {
+			tagMetadata = name[strings.LastIndex(name, "/")+1:]
+		}
+		if !validTagMetadata.MatchString(tagMetadata) {
+			return nil, errors.Errorf("Tag metadata %s may only contain [0-9A-Za-z]. Set it with name:version=metadata", tagMetadata)
+		}
+		if metadata[tagMetadata] {
+			return nil, errors.Errorf("The base images must have different tag metadata, %s is repeated", tagMetadata)
+		}
+		metadata[tagMetadata] = true
+		matrix = append(matrix, DockerBaseImageSpec{BaseImage: name, BaseVersion: image[i+1:], TagMetadata: tagMetadata})
+	}
+	if len(matrix) == 0 {
+		return nil, errors.New("Expected at least one base image")
+	}
+	return matrix, nil
+}
+
+// checkMatrixVersion the images of a matrix build get the tag metadata of their base image. A version that already
+// has build metadata can not get another
+func checkMatrixVersion(version string) error {
+	if strings.Contains(version, "+") {
+		return errors.Errorf("The version %s has build metadata. A matrix build adds the tag metadata of the base images",
+			version)
+	}
+	return nil
+}
+
+// ParseOutputTargets parse a semicolon separated list of registry/repository[=extra,tags], e.g.
+// dr-registry.example.com/aurora/minarch;partner.example.com/minarch=latest. A target without extra tags gets
+// defaultExtraTags
+func ParseOutputTargets(value string, defaultExtraTags PushExtraTags) ([]OutputTarget, error) {
+	var targets []OutputTarget
+	for _, entry := range strings.Split(value, ";") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		target, err := ParseOutputTarget(entry, defaultExtraTags)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// ParseOutputTarget parse registry/repository[=extra,tags]
+func ParseOutputTarget(value string, defaultExtraTags PushExtraTags) (OutputTarget, error) {
+	image, extraTags, hasExtraTags := strings.Cut(strings.TrimSpace(value), "=")
+	named, err := reference.ParseNamed(image)
+	if err != nil {
+		return OutputTarget{}, errors.Errorf("Expected registry/repository[=extra,tags], got %s", value)
+	}
+	if _, tagged := named.(reference.Tagged); tagged {
+		return OutputTarget{}, errors.Errorf("Output target %s must not have a tag. The tags are resolved per target", value)
+	}
+	target := OutputTarget{
+		Registry:      reference.Domain(named),
+		Repository:    reference.Path(named),
+		PushExtraTags: defaultExtraTags,
+	}
+	if hasExtraTags {
+		target.PushExtraTags = ParseExtraTags(extraTags)
+	}
+	return target, nil
+}
+
+// ParseVersioningScheme check the name of the versioning scheme. Empty is semver
+func ParseVersioningScheme(value string) (string, error) {
+	switch scheme := strings.ToLower(strings.TrimSpace(value)); scheme {
+	case "":
+		return VersioningSchemeSemver, nil
+	case VersioningSchemeSemver, VersioningSchemeFourPart, VersioningSchemeCalver:
+		return scheme, nil
+	default:
+		return "", errors.Errorf("Unknown versioning scheme %s. Valid schemes are %s, %s and %s", value,
+			VersioningSchemeSemver, VersioningSchemeFourPart, VersioningSchemeCalver)
+	}
+}
+
+// TimeoutStages the build stages that can have their own timeout
+var TimeoutStages = []string{"download", "base_image", "prepare", "build", "push", "sporingslogger"}
+
+// ParseStageTimeouts parse a comma separated list of stage=seconds, e.g. download=300,push=600
+func ParseStageTimeouts(value string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("Expected stage=seconds, got %s", entry)
+		}
+		stage := strings.TrimSpace(parts[0])
+		if !isTimeoutStage(stage) {
+			return nil, errors.Errorf("Unknown stage %s. Valid stages are %s", stage, strings.Join(TimeoutStages, ", "))
+		}
+		seconds, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || seconds <= 0 {
+			return nil, errors.Errorf("Timeout for stage %s must be a positive number of seconds, got %s", stage, parts[1])
+		}
+		timeouts[stage] = time.Duration(seconds) * time.Second
+	}
+	return timeouts, nil
+}
+
+func isTimeoutStage(stage string) bool {
+	for _, timeoutStage := range TimeoutStages {
+		if stage == timeoutStage {
+			return true
+		}
+	}
+	return false
+}
+
+// The build config wins over the environment of the builder pod
+func findEnvOrProcessEnv(env map[string]string, name string) string {
+	if value, err := findEnv(env, name); err == nil {
+		return value
+	}
+	return os.Getenv(name)
+}
+
 func findEnv(env map[string]string, name string) (string, error) {
 	value, ok := env[name]
 	if ok {
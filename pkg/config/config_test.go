@@ -3,7 +3,10 @@ package config_test
 import (
 	"github.com/skatteetaten/architect/v2/pkg/config"
 	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestJavaLeveransePakkeConfig(t *testing.T) {
@@ -46,6 +49,23 @@ func TestTagWithConfig(t *testing.T) {
 	assert.Equal(t, "supertaggen", c.DockerSpec.TagWith)
 }
 
+func TestInvalidBuildTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "build.json")
+	err := os.WriteFile(path, buildWithEnv("ImageStreamTag", map[string]string{
+		"ARTIFACT_ID":         "app",
+		"GROUP_ID":            "no.skatteetaten.aurora",
+		"VERSION":             "1.0.0",
+		"DOCKER_BASE_NAME":    "aurora/wingnut11",
+		"DOCKER_BASE_VERSION": "1",
+		"BUILD_TIMEOUT_IN_S":  "ten",
+	}), 0644)
+	assert.NoError(t, err)
+
+	_, err = config.NewFileConfigReader(path).ReadConfig()
+
+	assert.EqualError(t, err, `BUILD_TIMEOUT_IN_S: strconv.Atoi: parsing "ten": invalid syntax`)
+}
+
 func TestHidingPasswordWhenGettingNExusAccessString(t *testing.T) {
 	nexusAccess := config.NexusAccess{}
 	nexusAccess.Username = "username"
@@ -56,6 +76,18 @@ func TestHidingPasswordWhenGettingNExusAccessString(t *testing.T) {
 	assert.Contains(t, nexusAccess.String(), "http://testurl")
 	assert.Contains(t, nexusAccess.String(), "******")
 }
+func TestNexusAccessMavenRepositoryPath(t *testing.T) {
+	nexusAccess := config.NexusAccess{}
+	assert.Equal(t, config.DefaultMavenRepositoryPath, nexusAccess.MavenRepositoryPath(false))
+	assert.Equal(t, config.DefaultMavenRepositoryPath, nexusAccess.MavenRepositoryPath(true))
+
+	nexusAccess.RepositoryPath = "/repository/maven-releases"
+	assert.Equal(t, "/repository/maven-releases", nexusAccess.MavenRepositoryPath(true))
+	nexusAccess.SnapshotRepositoryPath = "/repository/maven-snapshots"
+	assert.Equal(t, "/repository/maven-releases", nexusAccess.MavenRepositoryPath(false))
+	assert.Equal(t, "/repository/maven-snapshots", nexusAccess.MavenRepositoryPath(true))
+}
+
 func TestGetUrlFromOutput(t *testing.T) {
 	r := config.NewFileConfigReader("../../testdata/bug-sitj-650.json")
 	c, err := r.ReadConfig()
@@ -63,3 +95,56 @@ func TestGetUrlFromOutput(t *testing.T) {
 	completeDockerName := c.DockerSpec.OutputRegistry + "/" + c.DockerSpec.OutputRepository
 	assert.Equal(t, "container-registry-internal-snapshot.aurora.skead.no:443/no_skatteetaten_aurora_openshift/openshift-reference-springboot-server-kotlin", completeDockerName)
 }
+
+func TestParseStageTimeouts(t *testing.T) {
+	timeouts, err := config.ParseStageTimeouts("download=300, push=600")
+	assert.NoError(t, err)
+	assert.Equal(t, 300*time.Second, timeouts["download"])
+	assert.Equal(t, 600*time.Second, timeouts["push"])
+
+	timeouts, err = config.ParseStageTimeouts("")
+	assert.NoError(t, err)
+	assert.Empty(t, timeouts)
+
+	_, err = config.ParseStageTimeouts("compile=10")
+	assert.Error(t, err)
+	_, err = config.ParseStageTimeouts("push=0")
+	assert.Error(t, err)
+	_, err = config.ParseStageTimeouts("push")
+	assert.Error(t, err)
+}
+
+func TestParseOutputTargets(t *testing.T) {
+	defaultExtraTags := config.PushExtraTags{Major: true}
+	targets, err := config.ParseOutputTargets(
+		"dr-registry.example.com/aurora/minarch; partner.example.com:5000/minarch=latest,patch;", defaultExtraTags)
+	assert.NoError(t, err)
+	assert.Equal(t, []config.OutputTarget{
+		{Registry: "dr-registry.example.com", Repository: "aurora/minarch", PushExtraTags: defaultExtraTags},
+		{Registry: "partner.example.com:5000", Repository: "minarch",
+			PushExtraTags: config.PushExtraTags{Latest: true, Patch: true}},
+	}, targets)
+
+	_, err = config.ParseOutputTargets("aurora/minarch", defaultExtraTags)
+	assert.Error(t, err)
+	_, err = config.ParseOutputTargets("registry.example.com/aurora/minarch:1.0.0", defaultExtraTags)
+	assert.Error(t, err)
+}
+
+func TestParseBaseImageMatrix(t *testing.T) {
+	matrix, err := config.ParseBaseImageMatrix("aurora/wingnut11:1, registry:5000/aurora/wingnut17:2.1=jdk17")
+	assert.NoError(t, err)
+	assert.Equal(t, []config.DockerBaseImageSpec{
+		{BaseImage: "aurora/wingnut11", BaseVersion: "1", TagMetadata: "wingnut11"},
+		{BaseImage: "registry:5000/aurora/wingnut17", BaseVersion: "2.1", TagMetadata: "jdk17"},
+	}, matrix)
+
+	_, err = config.ParseBaseImageMatrix("aurora/wingnut11")
+	assert.Error(t, err)
+	_, err = config.ParseBaseImageMatrix("aurora/wingnut11:1,other/wingnut11:2")
+	assert.EqualError(t, err, "The base images must have different tag metadata, wingnut11 is repeated")
+	_, err = config.ParseBaseImageMatrix("aurora/wingnut-17:1")
+	assert.Error(t, err)
+	_, err = config.ParseBaseImageMatrix(" , ")
+	assert.Error(t, err)
+}
@@ -0,0 +1,303 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/skatteetaten/architect/v2/pkg/util"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ArchitectFile the architect.yaml build file. JSON is valid YAML, so architect.json is read the same way
+type ArchitectFile struct {
+	// Type java, nodejs or doozer. Default java
+	Type      string        `yaml:"type"`
+	Gav       FileGav       `yaml:"gav"`
+	BaseImage FileBaseImage `yaml:"baseImage"`
+	// BaseImages a matrix build on several base images in the registry of baseImage, instead of baseImage
+	BaseImages []FileMatrixBaseImage `yaml:"baseImages"`
+	Output     FileOutput            `yaml:"output"`
+	// TLSVerify default true
+	TLSVerify *bool `yaml:"tlsVerify"`
+	// BuildTimeout seconds. Default 900
+	BuildTimeout     int               `yaml:"buildTimeout"`
+	Sporingstjeneste string            `yaml:"sporingstjeneste"`
+	Labels           map[string]string `yaml:"labels"`
+	Source           FileSource        `yaml:"source"`
+	// Ownership preserve, arbitrary-uid or uid:gid. Default preserve
+	Ownership string `yaml:"ownership"`
+	// SkipIdenticalBuilds retag an identical image instead of building a new one. Default true
+	SkipIdenticalBuilds *bool `yaml:"skipIdenticalBuilds"`
+}
+
+// FileGav the Maven coordinates of the deliverable
+type FileGav struct {
+	GroupID    string `yaml:"groupId"`
+	ArtifactID string `yaml:"artifactId"`
+	Version    string `yaml:"version"`
+	// Classifier default Leveransepakke, Webleveransepakke or Doozerleveransepakke from the type
+	Classifier string `yaml:"classifier"`
+}
+
+// FileBaseImage the base image in the base image registry, or a local archive
+type FileBaseImage struct {
+	Registry string `yaml:"registry"`
+	Name     string `yaml:"name"`
+	Version  string `yaml:"version"`
+	// Local oci-layout:/path or docker-archive:/path.tar instead of name and version
+	Local string `yaml:"local"`
+}
+
+// FileMatrixBaseImage a base image of a matrix build
+type FileMatrixBaseImage struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+	// TagMetadata default the last part of the name
+	TagMetadata string `yaml:"tagMetadata"`
+}
+
+// FileOutput where the image is pushed, and how it is tagged
+type FileOutput struct {
+	Registry string `yaml:"registry"`
+	// PullRegistry the registry the base image is pulled from. Default the output registry
+	PullRegistry string `yaml:"pullRegistry"`
+	Repository   string `yaml:"repository"`
+	// Tag a temporary tag. The version tags are pushed when it is empty
+	Tag string `yaml:"tag"`
+	// ExtraTags default latest, major, minor and patch
+	ExtraTags        []string `yaml:"extraTags"`
+	TagTemplates     []string `yaml:"tagTemplates"`
+	VersioningScheme string   `yaml:"versioningScheme"`
+	// Targets registries and repositories that get a copy of the image
+	Targets []FileOutputTarget `yaml:"targets"`
+}
+
+// FileOutputTarget an additional registry and repository of the image
+type FileOutputTarget struct {
+	Registry   string `yaml:"registry"`
+	Repository string `yaml:"repository"`
+	// ExtraTags default the extra tags of the output
+	ExtraTags []string `yaml:"extraTags"`
+}
+
+// FileSource the source of the application, for the OCI labels
+type FileSource struct {
+	URL      string `yaml:"url"`
+	Revision string `yaml:"revision"`
+}
+
+// ArchitectFileConfigReader reads build configuration from an architect.yaml or architect.json file
+type ArchitectFileConfigReader struct {
+	pathToConfigFile string
+}
+
+// NewArchitectFileConfigReader returns a Reader of type ArchitectFileConfigReader
+func NewArchitectFileConfigReader(filepath string) Reader {
+	return &ArchitectFileConfigReader{pathToConfigFile: filepath}
+}
+
+// ReadConfig from the architect file
+func (m *ArchitectFileConfigReader) ReadConfig() (*Config, error) {
+	data, err := os.ReadFile(m.pathToConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	file, err := ParseArchitectFile(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid architect file %s", m.pathToConfigFile)
+	}
+	return file.Config()
+}
+
+// ParseArchitectFile parse YAML or JSON. Unknown fields are rejected, so a misspelled field is not silently ignored
+func ParseArchitectFile(data []byte) (*ArchitectFile, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var file ArchitectFile
+	if err := decoder.Decode(&file); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// Config the build configuration of the file
+func (m *ArchitectFile) Config() (*Config, error) {
+	applicationType, err := parseApplicationType(m.Type)
+	if err != nil {
+		return nil, errors.Wrap(err, "type")
+	}
+
+	gav := MavenGav{
+		GroupID:    m.Gav.GroupID,
+		ArtifactID: m.Gav.ArtifactID,
+		Version:    m.Gav.Version,
+		Classifier: Classifier(m.Gav.Classifier),
+		Type:       ZipPackaging,
+	}
+	if gav.Version == "" {
+		return nil, errors.New("gav.version is required")
+	}
+	if applicationType == NodeJsLeveransepakke {
+		gav.Type = TgzPackaging
+	}
+	if gav.Classifier == "" {
+		switch applicationType {
+		case NodeJsLeveransepakke:
+			gav.Classifier = Webleveransepakke
+		case DoozerLeveranse:
+			gav.Classifier = Doozerleveransepakke
+		default:
+			gav.Classifier = Leveransepakke
+		}
+	}
+
+	baseImageSpec := DockerBaseImageSpec{}
+	var baseImageMatrix []DockerBaseImageSpec
+	if len(m.BaseImages) > 0 {
+		var entries []string
+		for i, baseImage := range m.BaseImages {
+			if baseImage.Name == "" || baseImage.Version == "" {
+				return nil, errors.Errorf("baseImages[%d].name and baseImages[%d].version are required", i, i)
+			}
+			entry := baseImage.Name + ":" + baseImage.Version
+			if baseImage.TagMetadata != "" {
+				entry += "=" + baseImage.TagMetadata
+			}
+			entries = append(entries, entry)
+		}
+		baseImageMatrix, err = ParseBaseImageMatrix(strings.Join(entries, ","))
+		if err != nil {
+			return nil, errors.Wrap(err, "baseImages")
+		}
+		if err := checkMatrixVersion(gav.Version); err != nil {
+			return nil, errors.Wrap(err, "baseImages")
+		}
+		baseImageSpec = baseImageMatrix[0]
+	} else if m.BaseImage.Local != "" {
+		if !IsLocalBaseImageReference(m.BaseImage.Local) {
+			return nil, errors.Errorf("baseImage.local must start with %s or %s, was %s", OCILayoutTransport,
+				DockerArchiveTransport, m.BaseImage.Local)
+		}
+		baseImageSpec.LocalSource = m.BaseImage.Local
+	} else if m.BaseImage.Name == "" || m.BaseImage.Version == "" {
+		return nil, errors.New("baseImage.name and baseImage.version, baseImage.local or baseImages are required")
+	} else {
+		baseImageSpec.BaseImage = m.BaseImage.Name
+		baseImageSpec.BaseVersion = m.BaseImage.Version
+	}
+
+	if m.Output.Registry == "" || m.Output.Repository == "" {
+		return nil, errors.New("output.registry and output.repository are required")
+	}
+	pullRegistry := m.Output.PullRegistry
+	if pullRegistry == "" {
+		pullRegistry = m.Output.Registry
+	}
+	baseImageRegistry := m.BaseImage.Registry
+	if baseImageRegistry == "" {
+		baseImageRegistry = pullRegistry
+	}
+
+	extraTags := "latest,major,minor,patch"
+	if m.Output.ExtraTags != nil {
+		extraTags = strings.Join(m.Output.ExtraTags, ",")
+	}
+	versioningScheme, err := ParseVersioningScheme(m.Output.VersioningScheme)
+	if err != nil {
+		return nil, errors.Wrap(err, "output.versioningScheme")
+	}
+
+	var outputTargets []OutputTarget
+	for i, target := range m.Output.Targets {
+		if target.Registry == "" || target.Repository == "" {
+			return nil, errors.Errorf("output.targets[%d].registry and output.targets[%d].repository are required", i, i)
+		}
+		outputTarget := OutputTarget{
+			Registry:      target.Registry,
+			Repository:    target.Repository,
+			PushExtraTags: ParseExtraTags(extraTags),
+		}
+		if target.ExtraTags != nil {
+			outputTarget.PushExtraTags = ParseExtraTags(strings.Join(target.ExtraTags, ","))
+		}
+		outputTargets = append(outputTargets, outputTarget)
+	}
+
+	ownershipPolicy, err := util.ParseOwnershipPolicy(m.Ownership)
+	if err != nil {
+		return nil, errors.Wrap(err, "ownership")
+	}
+
+	tlsVerify := true
+	if m.TLSVerify != nil {
+		tlsVerify = *m.TLSVerify
+	}
+	var buildTimeout time.Duration = 900
+	if m.BuildTimeout < 0 {
+		return nil, errors.Errorf("buildTimeout must be a positive number of seconds, was %d", m.BuildTimeout)
+	} else if m.BuildTimeout > 0 {
+		buildTimeout = time.Duration(m.BuildTimeout)
+	}
+	skipIdenticalBuilds := true
+	if m.SkipIdenticalBuilds != nil {
+		skipIdenticalBuilds = *m.SkipIdenticalBuilds
+	}
+
+	builderSpec := BuilderSpec{Version: "local"}
+	if builderVersion, present := os.LookupEnv("APP_VERSION"); present {
+		builderSpec.Version = builderVersion
+	}
+
+	return &Config{
+		ApplicationType: applicationType,
+		ApplicationSpec: ApplicationSpec{
+			MavenGav:        gav,
+			BaseImageSpec:   baseImageSpec,
+			BaseImageMatrix: baseImageMatrix,
+		},
+		DockerSpec: DockerSpec{
+			OutputRegistry:         m.Output.Registry,
+			OutputRepository:       m.Output.Repository,
+			InternalPullRegistry:   withScheme(pullRegistry),
+			ExternalDockerRegistry: withScheme(baseImageRegistry),
+			PushExtraTags:          ParseExtraTags(extraTags),
+			TagWith:                m.Output.Tag,
+			TagTemplates:           m.Output.TagTemplates,
+			VersioningScheme:       versioningScheme,
+			OutputTargets:          outputTargets,
+		},
+		BuilderSpec:         builderSpec,
+		LocalBuild:          true,
+		TLSVerify:           tlsVerify,
+		BuildTimeout:        buildTimeout,
+		Sporingstjeneste:    m.Sporingstjeneste,
+		OwnershipPolicy:     ownershipPolicy,
+		SourceSpec:          SourceSpec{URL: m.Source.URL, Revision: m.Source.Revision},
+		SkipIdenticalBuilds: skipIdenticalBuilds,
+		Labels:              m.Labels,
+	}, nil
+}
+
+func parseApplicationType(value string) (ApplicationType, error) {
+	switch strings.ToUpper(value) {
+	case "", "JAVA", "MAVEN":
+		return JavaLeveransepakke, nil
+	case NodeJs:
+		return NodeJsLeveransepakke, nil
+	case Doozer:
+		return DoozerLeveranse, nil
+	default:
+		return "", errors.Errorf("Unknown application type %s. Valid types are java, nodejs and doozer", value)
+	}
+}
+
+func withScheme(registry string) string {
+	if strings.HasPrefix(registry, "http://") || strings.HasPrefix(registry, "https://") {
+		return registry
+	}
+	return fmt.Sprintf("https://%s", registry)
+}
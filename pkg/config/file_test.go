@@ -0,0 +1,117 @@
+package config_test
+
+import (
+	"github.com/skatteetaten/architect/v2/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestArchitectFileYaml(t *testing.T) {
+	c, err := config.NewArchitectFileConfigReader("../../testdata/architect.yaml").ReadConfig()
+	assert.NoError(t, err)
+
+	assert.Equal(t, config.JavaLeveransepakke, c.ApplicationType)
+	assert.Equal(t, config.MavenGav{
+		GroupID:    "no.skatteetaten.aurora",
+		ArtifactID: "minarch",
+		Version:    "2.3.5",
+		Classifier: config.Leveransepakke,
+		Type:       config.ZipPackaging,
+	}, c.ApplicationSpec.MavenGav)
+	assert.Equal(t, "aurora/wingnut11", c.ApplicationSpec.BaseImageSpec.BaseImage)
+	assert.Equal(t, "1.0.0", c.ApplicationSpec.BaseImageSpec.BaseVersion)
+	assert.Equal(t, "registry.example.com:5000", c.DockerSpec.OutputRegistry)
+	assert.Equal(t, "aurora/minarch", c.DockerSpec.OutputRepository)
+	assert.Equal(t, "https://registry-pull.example.com", c.DockerSpec.InternalPullRegistry)
+	assert.Equal(t, "https://registry-pull.example.com", c.DockerSpec.ExternalDockerRegistry)
+	assert.Equal(t, config.PushExtraTags{Latest: true, Major: true}, c.DockerSpec.PushExtraTags)
+	assert.Equal(t, "", c.DockerSpec.TagWith)
+	assert.Equal(t, []config.OutputTarget{
+		{Registry: "dr-registry.example.com", Repository: "aurora/minarch",
+			PushExtraTags: config.PushExtraTags{Latest: true, Major: true}},
+		{Registry: "partner.example.com", Repository: "minarch", PushExtraTags: config.PushExtraTags{Latest: true}},
+	}, c.DockerSpec.OutputTargets)
+	assert.False(t, c.TLSVerify)
+	assert.Equal(t, time.Duration(600), c.BuildTimeout)
+	assert.Equal(t, "http://sporingslogger.example.com", c.Sporingstjeneste)
+	assert.Equal(t, map[string]string{"team": "aurora"}, c.Labels)
+	assert.Equal(t, "abc123", c.SourceSpec.Revision)
+	assert.True(t, c.LocalBuild)
+}
+
+func TestArchitectFileJSON(t *testing.T) {
+	c, err := config.NewArchitectFileConfigReader("../../testdata/architect.json").ReadConfig()
+	assert.NoError(t, err)
+
+	assert.Equal(t, config.NodeJsLeveransepakke, c.ApplicationType)
+	assert.Equal(t, config.Webleveransepakke, c.ApplicationSpec.MavenGav.Classifier)
+	assert.Equal(t, config.TgzPackaging, c.ApplicationSpec.MavenGav.Type)
+	assert.Equal(t, "oci-layout:/tmp/base", c.ApplicationSpec.BaseImageSpec.LocalSource)
+	assert.Equal(t, "https://registry.example.com", c.DockerSpec.InternalPullRegistry)
+	assert.Equal(t, "temp-1", c.DockerSpec.TagWith)
+	assert.Equal(t, config.ParseExtraTags("latest,major,minor,patch"), c.DockerSpec.PushExtraTags)
+	assert.True(t, c.TLSVerify)
+	assert.Equal(t, time.Duration(900), c.BuildTimeout)
+}
+
+func TestArchitectFileRejectsUnknownFields(t *testing.T) {
+	_, err := config.ParseArchitectFile([]byte("gav:\n  versoin: 1.0.0\n"))
+	assert.Error(t, err)
+}
+
+func TestArchitectFileRequiredFields(t *testing.T) {
+	file, err := config.ParseArchitectFile([]byte("gav:\n  version: 1.0.0\n"))
+	assert.NoError(t, err)
+	_, err = file.Config()
+	assert.EqualError(t, err, "baseImage.name and baseImage.version, baseImage.local or baseImages are required")
+}
+
+func TestArchitectFileBaseImages(t *testing.T) {
+	file, err := config.ParseArchitectFile([]byte(`
+gav:
+  groupId: no.skatteetaten.aurora
+  artifactId: minarch
+  version: 2.3.5
+baseImages:
+  - name: aurora/wingnut11
+    version: "1"
+  - name: aurora/wingnut17
+    version: "2"
+    tagMetadata: jdk17
+output:
+  registry: registry.example.com
+  repository: aurora/minarch
+`))
+	assert.NoError(t, err)
+	c, err := file.Config()
+	assert.NoError(t, err)
+	assert.Equal(t, []config.DockerBaseImageSpec{
+		{BaseImage: "aurora/wingnut11", BaseVersion: "1", TagMetadata: "wingnut11"},
+		{BaseImage: "aurora/wingnut17", BaseVersion: "2", TagMetadata: "jdk17"},
+	}, c.ApplicationSpec.BaseImages())
+	assert.Equal(t, "aurora/wingnut11", c.ApplicationSpec.BaseImageSpec.BaseImage)
+}
+
+func TestArchitectFileSkipIdenticalBuilds(t *testing.T) {
+	fileWith := func(extra string) *config.Config {
+		file, err := config.ParseArchitectFile([]byte(`
+gav:
+  version: 2.3.5
+baseImage:
+  name: aurora/wingnut11
+  version: "1"
+output:
+  registry: registry.example.com
+  repository: aurora/minarch
+` + extra))
+		assert.NoError(t, err)
+		c, err := file.Config()
+		assert.NoError(t, err)
+		return c
+	}
+
+	assert.True(t, fileWith("").SkipIdenticalBuilds)
+	assert.True(t, fileWith("skipIdenticalBuilds: true\n").SkipIdenticalBuilds)
+	assert.False(t, fileWith("skipIdenticalBuilds: false\n").SkipIdenticalBuilds)
+}
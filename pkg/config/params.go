@@ -0,0 +1,108 @@
+package config
+
+import (
+	"encoding/json"
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ParamImage the parameter with the output image, e.g. registry.example.com/aurora/minarch:1.2.3
+const ParamImage = "IMAGE"
+
+// DefaultParamsDir the directory of the parameter files. Can be changed with PARAMS_DIR
+const DefaultParamsDir = "/workspace/params"
+
+// DefaultResultsDir the Tekton results directory. Can be changed with RESULTS_DIR
+const DefaultResultsDir = "/tekton/results"
+
+// ParamsConfigReader reads build configuration from Tekton or Shipwright parameters. The parameters have the names of
+// the custom strategy env, and are given as env variables or as files in the params directory
+type ParamsConfigReader struct {
+	paramsDir string
+}
+
+// NewParamsConfigReader returns a Reader of type ParamsConfigReader
+func NewParamsConfigReader() *ParamsConfigReader {
+	paramsDir := os.Getenv("PARAMS_DIR")
+	if paramsDir == "" {
+		paramsDir = DefaultParamsDir
+	}
+	return &ParamsConfigReader{paramsDir: paramsDir}
+}
+
+// ReadConfig from the parameters
+func (m *ParamsConfigReader) ReadConfig() (*Config, error) {
+	buildConfig, err := m.BuildConfig()
+	if err != nil {
+		return nil, err
+	}
+	c, err := newConfig(buildConfig, false)
+	if err != nil {
+		return nil, err
+	}
+	c.ResultsDir = os.Getenv("RESULTS_DIR")
+	if c.ResultsDir == "" {
+		c.ResultsDir = DefaultResultsDir
+	}
+	return c, nil
+}
+
+// BuildConfig the parameters as an OpenShift build with a custom strategy, so they are read and validated the same way
+func (m *ParamsConfigReader) BuildConfig() ([]byte, error) {
+	params, err := m.params()
+	if err != nil {
+		return nil, err
+	}
+	image, ok := params[ParamImage]
+	if !ok || image == "" {
+		return nil, errors.Errorf("The parameter %s with the output image is required", ParamImage)
+	}
+	delete(params, ParamImage)
+
+	var names []string
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var env []corev1.EnvVar
+	for _, name := range names {
+		env = append(env, corev1.EnvVar{Name: name, Value: params[name]})
+	}
+
+	build := buildv1.Build{}
+	build.Kind = "Build"
+	build.Spec.Strategy.CustomStrategy = &buildv1.CustomBuildStrategy{Env: env}
+	build.Spec.Output.To = &corev1.ObjectReference{Kind: "DockerImage", Name: image}
+	return json.Marshal(build)
+}
+
+// The files of the params directory, overridden by the env variables with the names of known parameters
+func (m *ParamsConfigReader) params() (map[string]string, error) {
+	params := make(map[string]string)
+	files, err := os.ReadDir(m.paramsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "Could not read parameters in %s", m.paramsDir)
+	}
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(m.paramsDir, file.Name()))
+		if err != nil {
+			return nil, errors.Wrapf(err, "Could not read parameter %s", file.Name())
+		}
+		params[file.Name()] = strings.TrimSpace(string(data))
+	}
+
+	for _, name := range append([]string{ParamImage}, KnownBuildVariables...) {
+		if value, ok := os.LookupEnv(name); ok {
+			params[name] = value
+		}
+	}
+	return params, nil
+}
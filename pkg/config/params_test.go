@@ -0,0 +1,76 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeParam(t *testing.T, dir string, name string, value string) {
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(value+"\n"), 0644))
+}
+
+func TestParamsConfigReader(t *testing.T) {
+	dir := t.TempDir()
+	writeParam(t, dir, "GROUP_ID", "no.skatteetaten.aurora")
+	writeParam(t, dir, "ARTIFACT_ID", "minarch")
+	writeParam(t, dir, "VERSION", "1.0.0")
+	writeParam(t, dir, "DOCKER_BASE_NAME", "aurora/wingnut11")
+	writeParam(t, dir, "DOCKER_BASE_VERSION", "1")
+	writeParam(t, dir, "BASE_IMAGE_REGISTRY", "registry.example.com")
+	t.Setenv("VERSION", "2.0.0")
+	t.Setenv("IMAGE", "registry.example.com:5000/aurora/minarch:2.0.0")
+	t.Setenv("RESULTS_DIR", "/results")
+
+	c, err := (&ParamsConfigReader{paramsDir: dir}).ReadConfig()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "no.skatteetaten.aurora", c.ApplicationSpec.MavenGav.GroupID)
+	assert.Equal(t, "2.0.0", c.ApplicationSpec.MavenGav.Version)
+	assert.Equal(t, Leveransepakke, c.ApplicationSpec.MavenGav.Classifier)
+	assert.Equal(t, "aurora/wingnut11", c.ApplicationSpec.BaseImageSpec.BaseImage)
+	assert.Equal(t, "https://registry.example.com", c.DockerSpec.ExternalDockerRegistry)
+	assert.Equal(t, "registry.example.com:5000", c.DockerSpec.OutputRegistry)
+	assert.Equal(t, "aurora/minarch", c.DockerSpec.OutputRepository)
+	assert.Equal(t, "2.0.0", c.DockerSpec.TagWith)
+	assert.False(t, c.BinaryBuild)
+	assert.Equal(t, "/results", c.ResultsDir)
+	assert.Equal(t, SizePolicy{}, c.SizePolicy)
+}
+
+func TestParamsConfigReaderRequiresImage(t *testing.T) {
+	dir := t.TempDir()
+	writeParam(t, dir, "VERSION", "1.0.0")
+
+	_, err := (&ParamsConfigReader{paramsDir: dir}).BuildConfig()
+
+	assert.EqualError(t, err, "The parameter IMAGE with the output image is required")
+}
+
+func TestParamsConfigReaderBaseImageMatrix(t *testing.T) {
+	dir := t.TempDir()
+	writeParam(t, dir, "GROUP_ID", "no.skatteetaten.aurora")
+	writeParam(t, dir, "ARTIFACT_ID", "minarch")
+	writeParam(t, dir, "VERSION", "1.0.0")
+	writeParam(t, dir, "BASE_IMAGE_REGISTRY", "registry.example.com")
+	writeParam(t, dir, "DOCKER_BASE_IMAGES", "aurora/wingnut11:1,aurora/wingnut17:2=jdk17")
+	t.Setenv("IMAGE", "registry.example.com:5000/aurora/minarch:1.0.0")
+
+	c, err := (&ParamsConfigReader{paramsDir: dir}).ReadConfig()
+
+	assert.NoError(t, err)
+	assert.Len(t, c.ApplicationSpec.BaseImageMatrix, 2)
+	assert.Equal(t, c.ApplicationSpec.BaseImageMatrix[0], c.ApplicationSpec.BaseImageSpec)
+
+	writeParam(t, dir, "DOCKER_BASE_IMAGES", "aurora/wingnut11:1,other/wingnut11:2")
+	_, err = (&ParamsConfigReader{paramsDir: dir}).ReadConfig()
+	assert.Error(t, err)
+
+	writeParam(t, dir, "DOCKER_BASE_IMAGES", "aurora/wingnut11:1,aurora/wingnut17:2=jdk17")
+	writeParam(t, dir, "VERSION", "1.0.0+build")
+	_, err = (&ParamsConfigReader{paramsDir: dir}).ReadConfig()
+	assert.EqualError(t, err, "DOCKER_BASE_IMAGES: The version 1.0.0+build has build metadata. "+
+		"A matrix build adds the tag metadata of the base images")
+}
@@ -117,6 +117,23 @@ func NewAuroraVersionFromBuilderAndBase(
 	}
 }
 
+// WithMetadata a copy with build metadata on the app version and the given version. The complete versions already
+// name the base image, and are kept
+func (m *AuroraVersion) WithMetadata(metadata string) (*AuroraVersion, error) {
+	appVersion, err := util.AddVersionMetadata(string(m.appVersion), metadata)
+	if err != nil {
+		return nil, err
+	}
+	givenVersion, err := util.AddVersionMetadata(string(m.givenVersion), metadata)
+	if err != nil {
+		return nil, err
+	}
+	v := *m
+	v.appVersion = AppVersion(appVersion)
+	v.givenVersion = GivenVersion(givenVersion)
+	return &v, nil
+}
+
 // GetGivenVersion returns the version set in the build config
 func (m *AuroraVersion) GetGivenVersion() string {
 	return string(m.givenVersion)
@@ -145,6 +162,14 @@ func (m *AuroraVersion) GetUniqueSnapshotVersion() string {
 	return fmt.Sprintf("%s-%s", m.GetGivenVersion(), m.hash[len(m.hash)-8:])
 }
 
+// IsPreReleaseVersion check if version is a semantic pre-release, e.g. 2.3.0-rc.1
+func (m *AuroraVersion) IsPreReleaseVersion() bool {
+	if m.Snapshot {
+		return false
+	}
+	return util.IsPreReleaseVersion(string(m.appVersion))
+}
+
 // IsSemanticReleaseVersion check if version is a semantic version
 func (m *AuroraVersion) IsSemanticReleaseVersion() bool {
 	if m.Snapshot {
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SettingsEnvPrefix prefix of the environment variables that set command line options, e.g. ARCHITECT_PUSH_REGISTRY
+const SettingsEnvPrefix = "ARCHITECT_"
+
+// Sources of a setting, from the weakest to the strongest
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceProfile = "profile"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// Options that select the settings, and can not be set by them
+var settingsOptions = map[string]bool{"config": true, "profile": true, "help": true}
+
+// Settings command line options from a settings file. The keys are the names of the options, e.g. push-registry.
+// A profile overrides the top level values
+type Settings struct {
+	Path     string                            `yaml:"-"`
+	Values   map[string]interface{}            `yaml:",inline"`
+	Profiles map[string]map[string]interface{} `yaml:"profiles"`
+}
+
+// Setting the effective value of an option, and where it comes from
+type Setting struct {
+	Name   string
+	Value  string
+	Source string
+}
+
+// String name=value (source)
+func (m Setting) String() string {
+	return fmt.Sprintf("%s=%s (%s)", m.Name, m.Value, m.Source)
+}
+
+// DefaultSettingsPath ~/.architect.yaml
+func DefaultSettingsPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".architect.yaml")
+}
+
+// LoadSettings read the settings file. A missing file is only an error when it is required
+func LoadSettings(path string, required bool) (*Settings, error) {
+	settings := &Settings{Path: path}
+	if path == "" {
+		return settings, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && !required {
+		return settings, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "Could not read settings %s", path)
+	}
+	if err := yaml.Unmarshal(data, settings); err != nil {
+		return nil, errors.Wrapf(err, "Invalid settings %s", path)
+	}
+	return settings, nil
+}
+
+// ApplySettings set every option that is not given as a flag from the environment, the profile or the settings
+// file, in that order. Returns the effective value and source of every option
+func ApplySettings(flags *pflag.FlagSet, settings *Settings, profile string, lookupEnv func(string) (string, bool)) ([]Setting, error) {
+	profileValues := map[string]interface{}{}
+	if profile != "" {
+		values, ok := settings.Profiles[profile]
+		if !ok {
+			return nil, errors.Errorf("Unknown profile %s. Profiles in %s: %s", profile, settings.Path,
+				strings.Join(settings.profileNames(), ", "))
+		}
+		profileValues = values
+	}
+
+	var result []Setting
+	var err error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if err != nil || settingsOptions[flag.Name] {
+			return
+		}
+		source := SourceDefault
+		if flag.Changed {
+			source = SourceFlag
+		} else if value, ok := lookupEnv(SettingsEnvName(flag.Name)); ok {
+			source = fmt.Sprintf("%s %s", SourceEnv, SettingsEnvName(flag.Name))
+			err = setFlag(flags, flag.Name, value)
+		} else if value, ok := profileValues[flag.Name]; ok {
+			source = fmt.Sprintf("%s %s in %s", SourceProfile, profile, settings.Path)
+			err = setFlag(flags, flag.Name, value)
+		} else if value, ok := settings.Values[flag.Name]; ok {
+			source = fmt.Sprintf("%s %s", SourceFile, settings.Path)
+			err = setFlag(flags, flag.Name, value)
+		}
+		result = append(result, Setting{Name: flag.Name, Value: flag.Value.String(), Source: source})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SettingsEnvName the environment variable of an option, e.g. ARCHITECT_PUSH_REGISTRY for push-registry
+func SettingsEnvName(option string) string {
+	return SettingsEnvPrefix + strings.ToUpper(strings.ReplaceAll(option, "-", "_"))
+}
+
+// A list in the settings file sets a repeatable option once per element
+func setFlag(flags *pflag.FlagSet, name string, value interface{}) error {
+	values, ok := value.([]interface{})
+	if !ok {
+		values = []interface{}{value}
+	}
+	for _, v := range values {
+		if err := flags.Set(name, fmt.Sprint(v)); err != nil {
+			return errors.Wrapf(err, "Invalid value for %s", name)
+		}
+	}
+	return nil
+}
+
+func (m *Settings) profileNames() []string {
+	var names []string
+	for name := range m.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+const settingsFile = `
+push-registry: file.example.com
+pull-registry: file-pull.example.com
+ownership: arbitrary-uid
+tag-template:
+  - "{{.Version}}-a"
+  - "{{.Version}}-b"
+profiles:
+  utv:
+    push-registry: utv.example.com
+`
+
+func newSettingsFlags() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("build", pflag.ContinueOnError)
+	flags.String("push-registry", "default.example.com", "")
+	flags.String("pull-registry", "default-pull.example.com", "")
+	flags.String("ownership", "preserve", "")
+	flags.String("type", "java", "")
+	flags.StringArray("tag-template", nil, "")
+	flags.String("profile", "", "")
+	return flags
+}
+
+func loadTestSettings(t *testing.T) *Settings {
+	path := filepath.Join(t.TempDir(), ".architect.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(settingsFile), 0644))
+	settings, err := LoadSettings(path, true)
+	assert.NoError(t, err)
+	return settings
+}
+
+func noEnv(string) (string, bool) {
+	return "", false
+}
+
+func TestApplySettingsPrecedence(t *testing.T) {
+	settings := loadTestSettings(t)
+	flags := newSettingsFlags()
+	assert.NoError(t, flags.Parse([]string{"--ownership", "1000:1000"}))
+	env := map[string]string{"ARCHITECT_PULL_REGISTRY": "env-pull.example.com", "ARCHITECT_OWNERSHIP": "preserve"}
+
+	result, err := ApplySettings(flags, settings, "utv", func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	})
+
+	assert.NoError(t, err)
+	sources := make(map[string]string)
+	for _, setting := range result {
+		sources[setting.Name] = setting.Source
+	}
+	assert.Equal(t, "1000:1000", flags.Lookup("ownership").Value.String())
+	assert.Equal(t, SourceFlag, sources["ownership"])
+	assert.Equal(t, "env-pull.example.com", flags.Lookup("pull-registry").Value.String())
+	assert.Equal(t, "env ARCHITECT_PULL_REGISTRY", sources["pull-registry"])
+	assert.Equal(t, "utv.example.com", flags.Lookup("push-registry").Value.String())
+	assert.Equal(t, "profile utv in "+settings.Path, sources["push-registry"])
+	assert.Equal(t, "java", flags.Lookup("type").Value.String())
+	assert.Equal(t, SourceDefault, sources["type"])
+	assert.NotContains(t, sources, "profile")
+}
+
+func TestApplySettingsFile(t *testing.T) {
+	settings := loadTestSettings(t)
+	flags := newSettingsFlags()
+
+	_, err := ApplySettings(flags, settings, "", noEnv)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "file.example.com", flags.Lookup("push-registry").Value.String())
+	templates, err := flags.GetStringArray("tag-template")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"{{.Version}}-a", "{{.Version}}-b"}, templates)
+}
+
+func TestApplySettingsUnknownProfile(t *testing.T) {
+	settings := loadTestSettings(t)
+
+	_, err := ApplySettings(newSettingsFlags(), settings, "prod", noEnv)
+
+	assert.EqualError(t, err, "Unknown profile prod. Profiles in "+settings.Path+": utv")
+}
+
+func TestLoadSettingsMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.yaml")
+
+	settings, err := LoadSettings(path, false)
+	assert.NoError(t, err)
+	assert.Empty(t, settings.Values)
+
+	_, err = LoadSettings(path, true)
+	assert.Error(t, err)
+}
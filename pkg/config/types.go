@@ -1,6 +1,7 @@
 package config
 
 import (
+	"github.com/skatteetaten/architect/v2/pkg/util"
 	"strings"
 	"time"
 )
@@ -66,13 +67,79 @@ type Config struct {
 	OwnerReferenceUUID string
 	BinaryBuildType    BinaryBuildType
 	NexusIQReportURL   string
+	OwnershipPolicy    util.OwnershipPolicy
+	SourceSpec         SourceSpec
+	SizePolicy         SizePolicy
+	// SkipIdenticalBuilds re-point the tags to an existing image built from the same deliverable and base image
+	SkipIdenticalBuilds bool
+	TelemetrySpec       TelemetrySpec
+	Webhooks            []WebhookEndpoint
+	// StageTimeouts optional timeouts for the build stages. The BuildTimeout still applies to the whole build
+	StageTimeouts map[string]time.Duration
+	// BuildEnv the environment of the build config. Used by the tag templates
+	BuildEnv map[string]string
+	// ResultsDir Tekton results directory. The digest and the url of the pushed image are written there
+	ResultsDir string
+	// Labels image labels from the build file. The labels of the deliverable metadata win
+	Labels map[string]string
 }
 
+// WebhookEndpoint receives build lifecycle events. The events are signed with the secret when it is set
+type WebhookEndpoint struct {
+	URL    string
+	Secret string
+}
+
+// TelemetrySpec where the stage spans and histograms are exported. Nothing is recorded when both are empty
+type TelemetrySpec struct {
+	// OTLPEndpoint OTLP/HTTP collector, e.g. http://localhost:4318
+	OTLPEndpoint string
+	// PrometheusTextfile file for the node-exporter textfile collector
+	PrometheusTextfile string
+}
+
+// Enabled check if stages should be recorded
+func (m TelemetrySpec) Enabled() bool {
+	return m.OTLPEndpoint != "" || m.PrometheusTextfile != ""
+}
+
+// SizePolicy size limits in bytes, checked before the image is pushed. Zero means no limit, and no limit is set
+// unless the build config sets it
+type SizePolicy struct {
+	MaxApplicationLayerSize int64
+	MaxImageSize            int64
+	MaxFileSize             int64
+}
+
+// SourceSpec where the application source is found. Both fields are optional
+type SourceSpec struct {
+	URL      string
+	Revision string
+}
+
+// DefaultMavenRepositoryPath the path of the Maven repository on the Nexus server
+const DefaultMavenRepositoryPath = "/repository/maven-intern"
+
 // NexusAccess nexus url and nexus credentials
 type NexusAccess struct {
 	Username string
 	Password string
 	NexusURL string
+	// RepositoryPath the path of the Maven repository on the server, default DefaultMavenRepositoryPath
+	RepositoryPath string
+	// SnapshotRepositoryPath the path of the Maven repository of snapshots, default RepositoryPath
+	SnapshotRepositoryPath string
+}
+
+// MavenRepositoryPath the path of the Maven repository of release or snapshot versions
+func (n NexusAccess) MavenRepositoryPath(snapshot bool) string {
+	if snapshot && n.SnapshotRepositoryPath != "" {
+		return n.SnapshotRepositoryPath
+	}
+	if n.RepositoryPath != "" {
+		return n.RepositoryPath
+	}
+	return DefaultMavenRepositoryPath
 }
 
 // IsValid check username, password and url is set
@@ -82,13 +149,25 @@ func (n NexusAccess) IsValid() bool {
 
 // String return as string
 func (n NexusAccess) String() string {
-	return "{Username:" + n.Username + " Password:****** NexusURL:" + n.NexusURL + "}"
+	return "{Username:" + n.Username + " Password:****** NexusURL:" + n.NexusURL +
+		" RepositoryPath:" + n.MavenRepositoryPath(false) + " SnapshotRepositoryPath:" + n.MavenRepositoryPath(true) + "}"
 }
 
 // ApplicationSpec config
 type ApplicationSpec struct {
 	MavenGav      MavenGav
 	BaseImageSpec DockerBaseImageSpec
+	// BaseImageMatrix the base images of a matrix build. The deliverable is built on every base image, and
+	// BaseImageSpec is the first
+	BaseImageMatrix []DockerBaseImageSpec
+}
+
+// BaseImages the base images to build on
+func (m ApplicationSpec) BaseImages() []DockerBaseImageSpec {
+	if len(m.BaseImageMatrix) > 0 {
+		return m.BaseImageMatrix
+	}
+	return []DockerBaseImageSpec{m.BaseImageSpec}
 }
 
 // MavenGav GAV parameters
@@ -110,10 +189,32 @@ func (m *MavenGav) Name() string {
 	return strings.Join([]string{m.GroupID, m.ArtifactID, m.ArtifactID}, ":")
 }
 
+// Local base image transports
+const (
+	// OCILayoutTransport base image read from an OCI image layout directory
+	OCILayoutTransport = "oci-layout:"
+	// DockerArchiveTransport base image read from a docker save tarball
+	DockerArchiveTransport = "docker-archive:"
+)
+
 // DockerBaseImageSpec config
 type DockerBaseImageSpec struct {
 	BaseImage   string
 	BaseVersion string
+	// LocalSource e.g. oci-layout:/path or docker-archive:/path.tar. Empty when the base image is in the pull registry
+	LocalSource string
+	// TagMetadata the version metadata of the images of a matrix build on this base image, e.g. wingnut17
+	TagMetadata string
+}
+
+// IsLocal check if the base image is read from a local archive
+func (m DockerBaseImageSpec) IsLocal() bool {
+	return m.LocalSource != ""
+}
+
+// IsLocalBaseImageReference check if the reference uses one of the local base image transports
+func IsLocalBaseImageReference(reference string) bool {
+	return strings.HasPrefix(reference, OCILayoutTransport) || strings.HasPrefix(reference, DockerArchiveTransport)
 }
 
 // DockerSpec config
@@ -127,8 +228,60 @@ type DockerSpec struct {
 	//The tag to push to. This is only used for ImageStreamTags (as for now) and RETAG functionality
 	TagWith   string
 	RetagWith string
+	// RetagDigest the digest of the tested image. Retag fails if RetagWith refers to another image
+	RetagDigest string
+	// RetagDryRun log the tag moves of a retag without pushing
+	RetagDryRun bool
+	// TagTemplates text/template tags pushed in addition to the version tags, e.g. {{.Version}}-{{.GitCommitShort}}
+	TagTemplates []string
+	// VersioningScheme how release versions are tagged, semver if empty
+	VersioningScheme string
+	// OutputTargets registries and repositories the image is pushed to in addition to the output repository
+	OutputTargets []OutputTarget
+}
+
+// OutputTarget a registry and repository that gets a copy of the image. The credentials are looked up by registry
+type OutputTarget struct {
+	Registry      string
+	Repository    string
+	PushExtraTags PushExtraTags
+}
+
+// String registry/repository
+func (m OutputTarget) String() string {
+	return m.Registry + "/" + m.Repository
 }
 
+// ForBaseImage a copy of the configuration that builds on one base image of the matrix. The temporary tag gets the
+// tag metadata of the base image
+func (m *Config) ForBaseImage(baseImage DockerBaseImageSpec) *Config {
+	c := *m
+	c.ApplicationSpec.BaseImageSpec = baseImage
+	c.ApplicationSpec.BaseImageMatrix = nil
+	if baseImage.TagMetadata != "" && c.DockerSpec.TagWith != "" {
+		// In the repository form, where + is _
+		c.DockerSpec.TagWith = strings.ReplaceAll(c.DockerSpec.TagWith, "+", "_") + "_" + baseImage.TagMetadata
+	}
+	return &c
+}
+
+// ForTarget a copy of the configuration that pushes to the target
+func (m *Config) ForTarget(target OutputTarget) *Config {
+	c := *m
+	c.DockerSpec.OutputRegistry = target.Registry
+	c.DockerSpec.OutputRepository = target.Repository
+	c.DockerSpec.PushExtraTags = target.PushExtraTags
+	c.DockerSpec.OutputTargets = nil
+	return &c
+}
+
+// Versioning schemes
+const (
+	VersioningSchemeSemver   = "semver"
+	VersioningSchemeFourPart = "four-part"
+	VersioningSchemeCalver   = "calver"
+)
+
 // BuilderSpec config
 type BuilderSpec struct {
 	Version string
@@ -0,0 +1,495 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"github.com/docker/distribution/reference"
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/pkg/errors"
+	"github.com/skatteetaten/architect/v2/pkg/util"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KnownBuildVariables the env variables of the custom strategy that Architect reads
+var KnownBuildVariables = []string{
+	"APPLICATION_TYPE", "ARTIFACT_ID", "GROUP_ID", "VERSION", "CLASSIFIER",
+	"BASE_IMAGE_REGISTRY", "DOCKER_BASE_IMAGE", "DOCKER_BASE_NAME", "DOCKER_BASE_VERSION", "INTERNAL_PULL_REGISTRY",
+	"PUSH_EXTRA_TAGS", "TAG_WITH", "RETAG_WITH", "RETAG_DIGEST", "RETAG_DRY_RUN", "TAG_TEMPLATES", "TAG_OVERWRITE",
+	"VERSIONING_SCHEME", "BINARY_BUILD_TYPE", "BUILD_TIMEOUT_IN_S", "STAGE_TIMEOUTS_IN_S", "TLS_VERIFY",
+	"SPORINGSTJENESTE", "FILE_OWNERSHIP", "SKIP_IDENTICAL_BUILDS",
+	"MAX_APPLICATION_LAYER_SIZE", "MAX_IMAGE_SIZE", "MAX_FILE_SIZE",
+	"IMAGE_LABEL_NEXUS_IQ_REPORT_URL", "IMAGE_LABEL_SOURCE", "IMAGE_LABEL_REVISION",
+	"OTEL_EXPORTER_OTLP_ENDPOINT", "METRICS_TEXTFILE", "WEBHOOK_URLS", "WEBHOOK_SECRET",
+	"OUTPUT_TARGETS", "DOCKER_BASE_IMAGES",
+}
+
+var extraTagTokens = []string{"latest", "major", "minor", "patch", "none"}
+
+// Problem a problem in the build configuration, with a hint on how to fix it. A warning does not stop a build
+type Problem struct {
+	Field   string
+	Message string
+	Hint    string
+	Warning bool
+}
+
+// String FIELD: message (hint)
+func (m Problem) String() string {
+	text := fmt.Sprintf("%s: %s", m.Field, m.Message)
+	if m.Hint != "" {
+		text = fmt.Sprintf("%s (%s)", text, m.Hint)
+	}
+	if m.Warning {
+		return "warning " + text
+	}
+	return text
+}
+
+// Problems every problem found in a build configuration
+type Problems []Problem
+
+// Error list the problems, one per line
+func (m Problems) Error() string {
+	lines := make([]string, 0, len(m)+1)
+	lines = append(lines, fmt.Sprintf("%d problem(s) in the build configuration:", len(m)))
+	for _, problem := range m {
+		lines = append(lines, "  "+problem.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Errors the problems that are not warnings
+func (m Problems) Errors() Problems {
+	var errs Problems
+	for _, problem := range m {
+		if !problem.Warning {
+			errs = append(errs, problem)
+		}
+	}
+	return errs
+}
+
+// Warnings the problems that do not stop a build
+func (m Problems) Warnings() Problems {
+	var warnings Problems
+	for _, problem := range m {
+		if problem.Warning {
+			warnings = append(warnings, problem)
+		}
+	}
+	return warnings
+}
+
+// RegistryCheck return an error if the registry does not answer. The certificate is only verified with tlsVerify
+type RegistryCheck func(registry string, tlsVerify bool) error
+
+// CheckRegistry check that the registry answers on /v2/ with https, or with http for insecure registries. With
+// tlsVerify a registry with an invalid certificate is unreachable, and is not tried with http
+func CheckRegistry(registry string, tlsVerify bool) error {
+	_, err := probeRegistry(registry, tlsVerify)
+	return err
+}
+
+type registryProbe struct {
+	registry  string
+	tlsVerify bool
+}
+
+type registryProbeResult struct {
+	url string
+	err error
+}
+
+// The validation before the build and the config reader ask for the same registries. Each is only probed once
+var registryProbes = struct {
+	sync.Mutex
+	results map[registryProbe]registryProbeResult
+}{results: make(map[registryProbe]registryProbeResult)}
+
+// probeRegistry the URL of the registry with the protocol it answers on
+func probeRegistry(registry string, tlsVerify bool) (string, error) {
+	base := strings.TrimPrefix(strings.TrimPrefix(registry, "https://"), "http://")
+	key := registryProbe{registry: base, tlsVerify: tlsVerify}
+	registryProbes.Lock()
+	defer registryProbes.Unlock()
+	if result, ok := registryProbes.results[key]; ok {
+		return result.url, result.err
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: !tlsVerify}},
+		Timeout:   10 * time.Second,
+	}
+	result := registryProbeResult{url: "https://" + base}
+	if err := checkURL(client, "https://", base, "/v2/"); err != nil {
+		result.err = err
+		if !tlsVerify || !isCertificateError(err) {
+			result = registryProbeResult{url: "http://" + base, err: checkURL(client, "http://", base, "/v2/")}
+		}
+	}
+	registryProbes.results[key] = result
+	return result.url, result.err
+}
+
+func isCertificateError(err error) bool {
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	return errors.As(err, &unknownAuthority) || errors.As(err, &hostname) || errors.As(err, &invalid)
+}
+
+// ValidateBuildConfig collect every problem in an OpenShift build. The registries are only checked when
+// checkRegistry is set
+func ValidateBuildConfig(buildConfig []byte, checkRegistry RegistryCheck) Problems {
+	build := buildv1.Build{}
+	if err := json.Unmarshal(buildConfig, &build); err != nil {
+		return Problems{{Field: "build", Message: err.Error(), Hint: "the build config must be an OpenShift Build in JSON"}}
+	}
+	customStrategy := build.Spec.Strategy.CustomStrategy
+	if customStrategy == nil {
+		return Problems{{Field: "spec.strategy", Message: "not a custom strategy", Hint: "Architect only supports customStrategy"}}
+	}
+	env := make(map[string]string)
+	for _, e := range customStrategy.Env {
+		env[e.Name] = e.Value
+	}
+
+	v := &validator{env: env}
+	v.required("ARTIFACT_ID", "GROUP_ID", "VERSION")
+	// A matrix build names its base images in DOCKER_BASE_IMAGES
+	if baseImage := env["DOCKER_BASE_IMAGE"]; env["DOCKER_BASE_IMAGES"] == "" && !IsLocalBaseImageReference(baseImage) {
+		if baseImage == "" && env["DOCKER_BASE_NAME"] == "" {
+			v.add("DOCKER_BASE_NAME", "is not set", "set the name of the base image, e.g. aurora/wingnut11")
+		}
+		v.required("DOCKER_BASE_VERSION")
+	}
+	v.unknown()
+
+	v.check("APPLICATION_TYPE", "use java, nodejs or doozer", func(value string) error {
+		_, err := parseApplicationType(value)
+		return err
+	})
+	v.check("BUILD_TIMEOUT_IN_S", "use a positive number of seconds, e.g. 900", positiveInt)
+	v.check("TLS_VERIFY", "use true or false", parseBool)
+	v.check("SKIP_IDENTICAL_BUILDS", "use true or false", parseBool)
+	v.check("RETAG_DRY_RUN", "use true or false", parseBool)
+	v.check("PUSH_EXTRA_TAGS", "use a list of "+strings.Join(extraTagTokens, ", "), extraTags)
+	v.check("VERSIONING_SCHEME", "", func(value string) error {
+		_, err := ParseVersioningScheme(value)
+		return err
+	})
+	v.check("FILE_OWNERSHIP", "", func(value string) error {
+		_, err := util.ParseOwnershipPolicy(value)
+		return err
+	})
+	v.check("STAGE_TIMEOUTS_IN_S", "e.g. download=300,push=600", func(value string) error {
+		_, err := ParseStageTimeouts(value)
+		return err
+	})
+	for _, name := range []string{"MAX_APPLICATION_LAYER_SIZE", "MAX_IMAGE_SIZE", "MAX_FILE_SIZE"} {
+		v.check(name, "e.g. 512MiB or 2GiB", func(value string) error {
+			_, err := util.ParseByteSize(value)
+			return err
+		})
+	}
+	v.check("OUTPUT_TARGETS", "use registry/repository[=extra,tags] separated by ;", func(value string) error {
+		_, err := ParseOutputTargets(value, PushExtraTags{})
+		return err
+	})
+	v.check("DOCKER_BASE_IMAGES", "use name:version[=metadata] separated by ,", func(value string) error {
+		_, err := ParseBaseImageMatrix(value)
+		return err
+	})
+	v.check("DOCKER_BASE_IMAGES", "remove the build metadata from VERSION", func(string) error {
+		return checkMatrixVersion(env["VERSION"])
+	})
+	v.check("BASE_IMAGE_REGISTRY", "use host[:port]", registryHost)
+	v.check("INTERNAL_PULL_REGISTRY", "use host[:port]", registryHost)
+
+	externalRegistry := env["BASE_IMAGE_REGISTRY"]
+	switch kind := build.Spec.Output.To.Kind; kind {
+	case "DockerImage":
+		if named, err := reference.ParseNormalizedNamed(build.Spec.Output.To.Name); err != nil {
+			v.add("spec.output.to.name", err.Error(), "use registry/repository[:tag]")
+		} else if externalRegistry == "" {
+			externalRegistry = reference.Domain(named)
+		}
+	case "ImageStreamTag":
+		// Set by OpenShift on the build pod, not in the custom strategy
+		for _, name := range []string{"OUTPUT_REGISTRY", "OUTPUT_IMAGE"} {
+			if _, ok := os.LookupEnv(name); !ok {
+				v.add(name, "is not set, and the output is an ImageStreamTag",
+					"run the build in OpenShift, or use a DockerImage output")
+			}
+		}
+	default:
+		v.add("spec.output.to.kind", fmt.Sprintf("%q is not supported", kind), "use DockerImage or ImageStreamTag")
+	}
+
+	if externalRegistry == "" {
+		v.add("BASE_IMAGE_REGISTRY", "is not set, and the output is not a DockerImage",
+			"set the registry of the base image")
+	}
+	if _, ok := env["INTERNAL_PULL_REGISTRY"]; !ok {
+		v.add("INTERNAL_PULL_REGISTRY", "is not set", "set the registry the base image is pulled from")
+	}
+	if checkRegistry != nil {
+		tlsVerify := parseTLSVerify(env["TLS_VERIFY"])
+		v.reachable("BASE_IMAGE_REGISTRY", externalRegistry, tlsVerify, checkRegistry)
+		v.reachable("INTERNAL_PULL_REGISTRY", env["INTERNAL_PULL_REGISTRY"], tlsVerify, checkRegistry)
+		targets, _ := ParseOutputTargets(env["OUTPUT_TARGETS"], PushExtraTags{})
+		for _, target := range targets {
+			v.reachable("OUTPUT_TARGETS", target.Registry, tlsVerify, checkRegistry)
+		}
+	}
+	return v.problems
+}
+
+// ValidateArchitectFile collect the problems in an architect.yaml build file. The registries are only checked when
+// checkRegistry is set
+func ValidateArchitectFile(data []byte, checkRegistry RegistryCheck) Problems {
+	v := &validator{}
+	file, err := ParseArchitectFile(data)
+	if err != nil {
+		v.add("architect file", err.Error(), "check the field names and the indentation")
+		return v.problems
+	}
+
+	v.failed("type", "use java, nodejs or doozer", func() error {
+		_, err := parseApplicationType(file.Type)
+		return err
+	}())
+	if file.Gav.Version == "" {
+		v.add("gav.version", "is required", "set the version of the deliverable, e.g. 1.2.3")
+	}
+	v.baseImages(file)
+	if file.Output.Registry == "" {
+		v.add("output.registry", "is required", "set the registry the image is pushed to")
+	}
+	if file.Output.Repository == "" {
+		v.add("output.repository", "is required", "set the repository of the image, e.g. aurora/minarch")
+	}
+	for i, target := range file.Output.Targets {
+		if target.Registry == "" || target.Repository == "" {
+			v.add(fmt.Sprintf("output.targets[%d]", i), "registry and repository are required",
+				"set the registry and the repository of the target")
+		}
+	}
+	v.failed("output.versioningScheme", "", func() error {
+		_, err := ParseVersioningScheme(file.Output.VersioningScheme)
+		return err
+	}())
+	v.failed("ownership", "use preserve, arbitrary-uid or uid:gid", func() error {
+		_, err := util.ParseOwnershipPolicy(file.Ownership)
+		return err
+	}())
+	if file.BuildTimeout < 0 {
+		v.add("buildTimeout", fmt.Sprintf("%d is not a positive number", file.BuildTimeout),
+			"use a positive number of seconds, e.g. 900")
+	}
+	if checkRegistry != nil {
+		tlsVerify := file.TLSVerify == nil || *file.TLSVerify
+		v.reachable("output.registry", file.Output.Registry, tlsVerify, checkRegistry)
+		if file.Output.PullRegistry != file.Output.Registry {
+			v.reachable("output.pullRegistry", file.Output.PullRegistry, tlsVerify, checkRegistry)
+		}
+		if file.BaseImage.Registry != file.Output.PullRegistry {
+			v.reachable("baseImage.registry", file.BaseImage.Registry, tlsVerify, checkRegistry)
+		}
+	}
+	return v.problems
+}
+
+// baseImages check baseImages, or baseImage when there is no matrix
+func (v *validator) baseImages(file *ArchitectFile) {
+	if len(file.BaseImages) == 0 {
+		switch {
+		case file.BaseImage.Local != "":
+			if !IsLocalBaseImageReference(file.BaseImage.Local) {
+				v.add("baseImage.local", fmt.Sprintf("%q is not a local base image", file.BaseImage.Local),
+					fmt.Sprintf("use %s/path or %s/path.tar", OCILayoutTransport, DockerArchiveTransport))
+			}
+		case file.BaseImage.Name == "" || file.BaseImage.Version == "":
+			v.add("baseImage", "name and version, local or baseImages are required",
+				"set the name and the version of the base image, e.g. aurora/wingnut11 and 1")
+		}
+		return
+	}
+
+	var entries []string
+	for i, baseImage := range file.BaseImages {
+		if baseImage.Name == "" || baseImage.Version == "" {
+			v.add(fmt.Sprintf("baseImages[%d]", i), "name and version are required",
+				"set the name and the version of the base image")
+			continue
+		}
+		entry := baseImage.Name + ":" + baseImage.Version
+		if baseImage.TagMetadata != "" {
+			entry += "=" + baseImage.TagMetadata
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == len(file.BaseImages) {
+		_, err := ParseBaseImageMatrix(strings.Join(entries, ","))
+		v.failed("baseImages", "give every base image a different tagMetadata of [0-9A-Za-z]", err)
+	}
+	v.failed("baseImages", "remove the build metadata from gav.version", checkMatrixVersion(file.Gav.Version))
+}
+
+type validator struct {
+	env      map[string]string
+	problems Problems
+}
+
+func (v *validator) add(field string, message string, hint string) {
+	v.problems = append(v.problems, Problem{Field: field, Message: message, Hint: hint})
+}
+
+func (v *validator) required(names ...string) {
+	for _, name := range names {
+		if _, ok := v.env[name]; !ok {
+			v.add(name, "is not set", "add it to the env of the custom strategy")
+		}
+	}
+}
+
+// unknown warn about variables Architect does not read, unless a tag template refers to them. Old build configs
+// carry variables of earlier builders, so they do not stop the build
+func (v *validator) unknown() {
+	known := make(map[string]bool, len(KnownBuildVariables))
+	for _, name := range KnownBuildVariables {
+		known[name] = true
+	}
+	var names []string
+	for name := range v.env {
+		if !known[name] && !strings.Contains(v.env["TAG_TEMPLATES"], name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		hint := "remove it, or check the spelling"
+		if suggestion := closest(name, KnownBuildVariables); suggestion != "" {
+			hint = fmt.Sprintf("did you mean %s?", suggestion)
+		}
+		v.problems = append(v.problems, Problem{Field: name, Message: "is not a build variable", Hint: hint, Warning: true})
+	}
+}
+
+func (v *validator) check(name string, hint string, parse func(string) error) {
+	value, ok := v.env[name]
+	if !ok {
+		return
+	}
+	if err := parse(value); err != nil {
+		v.add(name, err.Error(), hint)
+	}
+}
+
+func (v *validator) failed(field string, hint string, err error) {
+	if err != nil {
+		v.add(field, err.Error(), hint)
+	}
+}
+
+func (v *validator) reachable(name string, registry string, tlsVerify bool, checkRegistry RegistryCheck) {
+	if registry == "" {
+		return
+	}
+	if err := checkRegistry(registry, tlsVerify); err != nil {
+		v.add(name, fmt.Sprintf("registry %s is unreachable: %v", registry, err),
+			"check the host name, the port and the network access from the build")
+	}
+}
+
+func positiveInt(value string) error {
+	i, err := strconv.Atoi(value)
+	if err != nil || i <= 0 {
+		return errors.Errorf("%q is not a positive number", value)
+	}
+	return nil
+}
+
+func parseBool(value string) error {
+	if _, err := strconv.ParseBool(value); err != nil {
+		return errors.Errorf("%q is not a boolean", value)
+	}
+	return nil
+}
+
+func extraTags(value string) error {
+	var unknown []string
+	tokens := strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' })
+	for _, token := range tokens {
+		valid := false
+		for _, extraTag := range extraTagTokens {
+			valid = valid || token == extraTag
+		}
+		if !valid {
+			unknown = append(unknown, token)
+		}
+	}
+	if len(unknown) > 0 {
+		return errors.Errorf("unknown extra tags %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+func registryHost(value string) error {
+	host := strings.TrimPrefix(strings.TrimPrefix(value, "https://"), "http://")
+	parsed, err := url.Parse("https://" + host)
+	if err != nil || parsed.Host == "" || parsed.Host != host {
+		return errors.Errorf("%q is not a registry host", value)
+	}
+	return nil
+}
+
+// closest the candidate within two edits of the name, empty if there is none
+func closest(name string, candidates []string) string {
+	best, bestDistance := "", 3
+	for _, candidate := range candidates {
+		if d := editDistance(name, candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+func editDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
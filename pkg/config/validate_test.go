@@ -0,0 +1,211 @@
+package config_test
+
+import (
+	"github.com/pkg/errors"
+	"github.com/skatteetaten/architect/v2/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func buildWithEnv(outputKind string, env map[string]string) []byte {
+	var vars []string
+	for name, value := range env {
+		vars = append(vars, `{"name": "`+name+`", "value": "`+value+`"}`)
+	}
+	return []byte(`{"kind": "Build", "spec": {
+		"strategy": {"customStrategy": {"env": [` + strings.Join(vars, ",") + `]}},
+		"output": {"to": {"kind": "` + outputKind + `", "name": "registry.example.com:5000/aurora/app"}}}}`)
+}
+
+func fields(problems config.Problems) []string {
+	var names []string
+	for _, problem := range problems {
+		names = append(names, problem.Field)
+	}
+	return names
+}
+
+func TestValidateBuildConfigReportsEveryProblem(t *testing.T) {
+	problems := config.ValidateBuildConfig(buildWithEnv("ImageStream", map[string]string{
+		"GROUP_ID":           "no.skatteetaten.aurora",
+		"DOCKER_BASE_NAME":   "aurora/wingnut11",
+		"APPLICATION_TYPE":   "python",
+		"BUILD_TIMEOUT_IN_S": "ten",
+		"PUSH_EXTRA_TAGS":    "latest,mayor",
+		"TLS_VERIFY":         "nope",
+		"TAG_WTIH":           "temp",
+	}), nil)
+
+	assert.ElementsMatch(t, []string{
+		"ARTIFACT_ID", "VERSION", "DOCKER_BASE_VERSION", "TAG_WTIH", "APPLICATION_TYPE", "BUILD_TIMEOUT_IN_S",
+		"TLS_VERIFY", "PUSH_EXTRA_TAGS", "spec.output.to.kind", "BASE_IMAGE_REGISTRY", "INTERNAL_PULL_REGISTRY",
+	}, fields(problems))
+	assert.Contains(t, problems.Error(), "warning TAG_WTIH: is not a build variable (did you mean TAG_WITH?)")
+	assert.Equal(t, []string{"TAG_WTIH"}, fields(problems.Warnings()))
+	assert.Contains(t, problems.Error(), "PUSH_EXTRA_TAGS: unknown extra tags mayor")
+}
+
+func TestValidateBuildConfigChecksRegistries(t *testing.T) {
+	buildConfig := buildWithEnv("DockerImage", map[string]string{
+		"ARTIFACT_ID":            "app",
+		"GROUP_ID":               "no.skatteetaten.aurora",
+		"VERSION":                "1.0.0",
+		"DOCKER_BASE_NAME":       "aurora/wingnut11",
+		"DOCKER_BASE_VERSION":    "1",
+		"INTERNAL_PULL_REGISTRY": "registry-pull.example.com",
+	})
+	var checked []string
+	problems := config.ValidateBuildConfig(buildConfig, func(registry string, tlsVerify bool) error {
+		assert.True(t, tlsVerify)
+		checked = append(checked, registry)
+		if registry == "registry-pull.example.com" {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+
+	assert.Equal(t, []string{"registry.example.com:5000", "registry-pull.example.com"}, checked)
+	assert.Equal(t, []string{"INTERNAL_PULL_REGISTRY"}, fields(problems))
+}
+
+func TestValidateBuildConfigHonoursTLSVerify(t *testing.T) {
+	buildConfig := buildWithEnv("DockerImage", map[string]string{
+		"ARTIFACT_ID":            "app",
+		"GROUP_ID":               "no.skatteetaten.aurora",
+		"VERSION":                "1.0.0",
+		"DOCKER_BASE_NAME":       "aurora/wingnut11",
+		"DOCKER_BASE_VERSION":    "1",
+		"INTERNAL_PULL_REGISTRY": "registry-pull.example.com",
+		"TLS_VERIFY":             "false",
+	})
+	problems := config.ValidateBuildConfig(buildConfig, func(registry string, tlsVerify bool) error {
+		assert.False(t, tlsVerify)
+		return nil
+	})
+	assert.Empty(t, problems.Errors())
+}
+
+func TestCheckRegistryVerifiesCertificate(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	registry := strings.TrimPrefix(server.URL, "https://")
+
+	assert.Error(t, config.CheckRegistry(registry, true))
+	assert.NoError(t, config.CheckRegistry(registry, false))
+}
+
+func TestValidateBuildConfigAcceptsTestdata(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/build.json")
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"INTERNAL_PULL_REGISTRY"}, fields(config.ValidateBuildConfig(data, nil)))
+}
+
+func TestValidateBuildConfigAcceptsOldBuildConfigs(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/bug-sitj-650.json")
+	assert.NoError(t, err)
+	t.Setenv("OUTPUT_REGISTRY", "docker-registry.default.svc:5000")
+	t.Setenv("OUTPUT_IMAGE", "aurora/app:latest")
+
+	problems := config.ValidateBuildConfig(data, nil)
+	assert.Equal(t, []string{"INTERNAL_PULL_REGISTRY"}, fields(problems.Errors()))
+	assert.Equal(t, []string{"BUILD_STRATEGY"}, fields(problems.Warnings()))
+}
+
+func TestValidateArchitectFile(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/architect.yaml")
+	assert.NoError(t, err)
+	assert.Empty(t, config.ValidateArchitectFile(data, nil))
+
+	assert.Equal(t, []string{"architect file"}, fields(config.ValidateArchitectFile([]byte("tpye: java"), nil)))
+}
+
+func TestValidateArchitectFileReportsEveryProblem(t *testing.T) {
+	problems := config.ValidateArchitectFile([]byte(`
+type: python
+gav:
+  artifactId: minarch
+baseImages:
+  - name: aurora/wingnut11
+  - name: aurora/wingnut17
+    version: "2"
+output:
+  registry: registry.example.com
+  versioningScheme: dates
+  targets:
+    - registry: dr-registry.example.com
+ownership: root
+buildTimeout: -1
+`), nil)
+
+	assert.Equal(t, []string{
+		"type", "gav.version", "baseImages[0]", "output.repository", "output.targets[0]",
+		"output.versioningScheme", "ownership", "buildTimeout",
+	}, fields(problems))
+	for _, problem := range problems {
+		if problem.Field != "output.versioningScheme" {
+			assert.NotEmpty(t, problem.Hint, problem.Field)
+		}
+	}
+}
+
+func TestRegistriesAreCheckedOnceBeforeTheBuild(t *testing.T) {
+	requests := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+	registry := strings.TrimPrefix(server.URL, "https://")
+	buildConfig := buildWithEnv("DockerImage", map[string]string{
+		"ARTIFACT_ID":            "app",
+		"GROUP_ID":               "no.skatteetaten.aurora",
+		"VERSION":                "1.0.0",
+		"DOCKER_BASE_NAME":       "aurora/wingnut11",
+		"DOCKER_BASE_VERSION":    "1",
+		"BASE_IMAGE_REGISTRY":    "registry.example.com",
+		"INTERNAL_PULL_REGISTRY": registry,
+		"TLS_VERIFY":             "false",
+	})
+	path := filepath.Join(t.TempDir(), "build.json")
+	assert.NoError(t, os.WriteFile(path, buildConfig, 0644))
+
+	problems := config.ValidateBuildConfig(buildConfig, func(name string, tlsVerify bool) error {
+		if name == registry {
+			return config.CheckRegistry(name, tlsVerify)
+		}
+		return nil
+	})
+	assert.Empty(t, problems)
+	c, err := config.NewFileConfigReader(path).ReadConfig()
+
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL, c.DockerSpec.InternalPullRegistry)
+	assert.Equal(t, 1, requests)
+}
+
+func TestValidateBuildConfigChecksImageStreamTagOutput(t *testing.T) {
+	buildConfig := buildWithEnv("ImageStreamTag", map[string]string{
+		"ARTIFACT_ID":            "app",
+		"GROUP_ID":               "no.skatteetaten.aurora",
+		"VERSION":                "1.0.0",
+		"DOCKER_BASE_NAME":       "aurora/wingnut11",
+		"DOCKER_BASE_VERSION":    "1",
+		"BASE_IMAGE_REGISTRY":    "registry.example.com",
+		"INTERNAL_PULL_REGISTRY": "registry-pull.example.com",
+	})
+	t.Setenv("OUTPUT_REGISTRY", "docker-registry.default.svc:5000")
+	os.Unsetenv("OUTPUT_REGISTRY")
+	t.Setenv("OUTPUT_IMAGE", "aurora/app:latest")
+	os.Unsetenv("OUTPUT_IMAGE")
+
+	assert.Equal(t, []string{"OUTPUT_REGISTRY", "OUTPUT_IMAGE"}, fields(config.ValidateBuildConfig(buildConfig, nil)))
+
+	t.Setenv("OUTPUT_REGISTRY", "docker-registry.default.svc:5000")
+	t.Setenv("OUTPUT_IMAGE", "aurora/app:latest")
+	assert.Empty(t, config.ValidateBuildConfig(buildConfig, nil))
+}
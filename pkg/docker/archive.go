@@ -0,0 +1,459 @@
+package docker
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/skatteetaten/architect/v2/pkg/config"
+	"github.com/skatteetaten/architect/v2/pkg/config/runtime"
+	"github.com/skatteetaten/architect/v2/pkg/util"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	mediaTypeDockerLayerGzip    = "application/vnd.docker.image.rootfs.diff.tar.gzip"
+	mediaTypeDockerLayerTar     = "application/vnd.docker.image.rootfs.diff.tar"
+	mediaTypeOCIManifest        = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeOCIIndex           = "application/vnd.oci.image.index.v1+json"
+	mediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeOCILayerGzip       = "application/vnd.oci.image.layer.v1.tar+gzip"
+	mediaTypeOCILayerTar        = "application/vnd.oci.image.layer.v1.tar"
+
+	ociRefNameAnnotation        = "org.opencontainers.image.ref.name"
+	containerdImageNameAnnotion = "io.containerd.image.name"
+)
+
+// ArchiveRegistry is a read only Registry serving a single base image from an OCI image layout
+// directory or a docker save tarball. The manifest is normalized to a Docker schema 2 manifest with
+// gzipped layers, so that the image can be pushed as if it came from the pull registry.
+type ArchiveRegistry struct {
+	reference  string
+	repository string
+	tag        string
+	manifest   []byte
+	config     []byte
+	blobs      map[string]string
+	workDir    string
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int               `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Platform    *struct {
+		Architecture string `json:"architecture"`
+		OS           string `json:"os"`
+	} `json:"platform,omitempty"`
+}
+
+type ociIndex struct {
+	MediaType string          `json:"mediaType"`
+	Manifests []ociDescriptor `json:"manifests"`
+}
+
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Config    ociDescriptor   `json:"config"`
+	Layers    []ociDescriptor `json:"layers"`
+}
+
+type dockerArchiveManifest struct {
+	Config   string   `json:"Config"`
+	RepoTags []string `json:"RepoTags"`
+	Layers   []string `json:"Layers"`
+}
+
+// NewArchiveRegistry read the base image from an oci-layout: or docker-archive: reference. Close removes the
+// extracted and gzipped layers
+func NewArchiveRegistry(reference string) (*ArchiveRegistry, error) {
+	workDir, err := os.MkdirTemp("", "baseimage")
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create base image work directory")
+	}
+	archive := &ArchiveRegistry{
+		reference: reference,
+		blobs:     make(map[string]string),
+		workDir:   workDir,
+	}
+
+	switch {
+	case strings.HasPrefix(reference, config.OCILayoutTransport):
+		err = archive.readOCILayout(strings.TrimPrefix(reference, config.OCILayoutTransport), workDir)
+	case strings.HasPrefix(reference, config.DockerArchiveTransport):
+		err = archive.readDockerArchive(strings.TrimPrefix(reference, config.DockerArchiveTransport), workDir)
+	default:
+		err = errors.Errorf("Unknown base image transport. Use %s or %s", config.OCILayoutTransport, config.DockerArchiveTransport)
+	}
+	if err != nil {
+		archive.Close()
+		return nil, errors.Wrapf(err, "Unable to read base image %s", reference)
+	}
+	logrus.Infof("Using local base image %s:%s from %s", archive.repository, archive.tag, reference)
+	return archive, nil
+}
+
+// Close remove the work directory of the archive. Failures are logged
+func (a *ArchiveRegistry) Close() {
+	if err := os.RemoveAll(a.workDir); err != nil {
+		logrus.Warnf("Unable to remove base image work directory %s: %v", a.workDir, err)
+	}
+}
+
+// ImageName the repository and tag of the archived image
+func (a *ArchiveRegistry) ImageName() (string, string) {
+	return a.repository, a.tag
+}
+
+func (a *ArchiveRegistry) readOCILayout(layoutDir string, workDir string) error {
+	data, err := os.ReadFile(filepath.Join(layoutDir, "index.json"))
+	if err != nil {
+		return errors.Wrap(err, "Not an OCI image layout")
+	}
+	var index ociIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return errors.Wrap(err, "Unable to parse index.json")
+	}
+
+	descriptor, err := a.findOCIManifest(layoutDir, index)
+	if err != nil {
+		return err
+	}
+	a.setImageName(descriptor.Annotations[containerdImageNameAnnotion], descriptor.Annotations[ociRefNameAnnotation], layoutDir)
+
+	data, err = os.ReadFile(ociBlobPath(layoutDir, descriptor.Digest))
+	if err != nil {
+		return errors.Wrapf(err, "Unable to read manifest %s", descriptor.Digest)
+	}
+	var manifest ociManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return errors.Wrap(err, "Unable to parse manifest")
+	}
+
+	layers := make([]archiveLayer, 0, len(manifest.Layers))
+	for _, layer := range manifest.Layers {
+		switch layer.MediaType {
+		case mediaTypeOCILayerGzip, mediaTypeDockerLayerGzip, mediaTypeOCILayerTar, mediaTypeDockerLayerTar:
+			layers = append(layers, archiveLayer{path: ociBlobPath(layoutDir, layer.Digest)})
+		default:
+			return errors.Errorf("Layer %s has unsupported media type %s", layer.Digest, layer.MediaType)
+		}
+	}
+	return a.normalize(ociBlobPath(layoutDir, manifest.Config.Digest), layers, workDir)
+}
+
+// Pick the tagged manifest, and the linux/amd64 manifest from an image index
+func (a *ArchiveRegistry) findOCIManifest(layoutDir string, index ociIndex) (ociDescriptor, error) {
+	if len(index.Manifests) == 0 {
+		return ociDescriptor{}, errors.New("The image layout contains no manifests")
+	}
+	if len(index.Manifests) > 1 {
+		logrus.Warnf("The image layout contains %d images. Using the first", len(index.Manifests))
+	}
+	descriptor := index.Manifests[0]
+	if descriptor.MediaType != mediaTypeOCIIndex && descriptor.MediaType != mediaTypeDockerManifestList {
+		return descriptor, nil
+	}
+
+	data, err := os.ReadFile(ociBlobPath(layoutDir, descriptor.Digest))
+	if err != nil {
+		return ociDescriptor{}, errors.Wrapf(err, "Unable to read image index %s", descriptor.Digest)
+	}
+	var nested ociIndex
+	if err := json.Unmarshal(data, &nested); err != nil {
+		return ociDescriptor{}, errors.Wrap(err, "Unable to parse image index")
+	}
+	for _, candidate := range nested.Manifests {
+		if candidate.Platform != nil && candidate.Platform.OS == "linux" && candidate.Platform.Architecture == "amd64" {
+			candidate.Annotations = descriptor.Annotations
+			return candidate, nil
+		}
+	}
+	return ociDescriptor{}, errors.New("The image index has no linux/amd64 image")
+}
+
+func ociBlobPath(layoutDir string, digest string) string {
+	return filepath.Join(layoutDir, "blobs", strings.Replace(digest, ":", string(filepath.Separator), 1))
+}
+
+func (a *ArchiveRegistry) readDockerArchive(tarball string, workDir string) error {
+	extractDir := filepath.Join(workDir, "archive")
+	if err := extractTar(tarball, extractDir); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(filepath.Join(extractDir, "manifest.json"))
+	if err != nil {
+		return errors.Wrap(err, "Not a docker archive")
+	}
+	var manifests []dockerArchiveManifest
+	if err := json.Unmarshal(data, &manifests); err != nil {
+		return errors.Wrap(err, "Unable to parse manifest.json")
+	}
+	if len(manifests) == 0 {
+		return errors.New("The docker archive contains no images")
+	}
+	if len(manifests) > 1 {
+		logrus.Warnf("The docker archive contains %d images. Using the first", len(manifests))
+	}
+	manifest := manifests[0]
+
+	var repoTag string
+	if len(manifest.RepoTags) > 0 {
+		repoTag = manifest.RepoTags[0]
+	}
+	a.setImageName(repoTag, "", tarball)
+
+	layers := make([]archiveLayer, 0, len(manifest.Layers))
+	for _, layer := range manifest.Layers {
+		layers = append(layers, archiveLayer{path: filepath.Join(extractDir, layer)})
+	}
+	return a.normalize(filepath.Join(extractDir, manifest.Config), layers, workDir)
+}
+
+// Use repository:tag when known. Otherwise the file name and latest
+func (a *ArchiveRegistry) setImageName(name string, tag string, source string) {
+	a.repository = strings.TrimSuffix(filepath.Base(source), filepath.Ext(source))
+	a.tag = "latest"
+	if name != "" {
+		lastSlash := strings.LastIndex(name, "/")
+		if colon := strings.LastIndex(name, ":"); colon > lastSlash {
+			a.repository, a.tag = name[:colon], name[colon+1:]
+		} else {
+			a.repository = name
+		}
+		// Strip registry host, it is not part of the repository
+		if parts := strings.SplitN(a.repository, "/", 2); len(parts) == 2 && strings.ContainsAny(parts[0], ".:") {
+			a.repository = parts[1]
+		}
+	}
+	if tag != "" {
+		a.tag = tag
+	}
+}
+
+type archiveLayer struct {
+	path string
+}
+
+// Create a Docker schema 2 manifest with gzipped layers
+func (a *ArchiveRegistry) normalize(configPath string, layers []archiveLayer, workDir string) error {
+	configData, err := os.ReadFile(configPath)
+	if err != nil {
+		return errors.Wrap(err, "Unable to read image config")
+	}
+	a.config = configData
+	configDigest := util.CalculateDigest(configData)
+	a.blobs[configDigest] = configPath
+
+	manifest := ManifestV2{
+		SchemaVersion: 2,
+		MediaType:     httpHeaderManifestSchemaV2,
+	}
+	manifest.Config.MediaType = httpHeaderContainerImageV1
+	manifest.Config.Size = len(configData)
+	manifest.Config.Digest = configDigest
+
+	for i, layer := range layers {
+		blobPath, err := gzipLayer(layer.path, filepath.Join(workDir, fmt.Sprintf("layer-%d.tar.gz", i)))
+		if err != nil {
+			return errors.Wrapf(err, "Unable to read layer %s", layer.path)
+		}
+		digest, err := util.CalculateDigestFromFile(blobPath)
+		if err != nil {
+			return err
+		}
+		stat, err := os.Stat(blobPath)
+		if err != nil {
+			return err
+		}
+		a.blobs[digest] = blobPath
+		manifest.Layers = append(manifest.Layers, Layer{
+			MediaType: mediaTypeDockerLayerGzip,
+			Size:      int(stat.Size()),
+			Digest:    digest,
+		})
+	}
+
+	a.manifest, err = json.Marshal(manifest)
+	return err
+}
+
+// Return the layer path if it is gzipped. Otherwise write a gzipped copy
+func gzipLayer(layerPath string, gzipPath string) (string, error) {
+	source, err := os.Open(layerPath)
+	if err != nil {
+		return "", err
+	}
+	defer source.Close()
+
+	reader := bufio.NewReader(source)
+	magic, err := reader.Peek(2)
+	if err != nil {
+		return "", err
+	}
+	if magic[0] == 0x1f && magic[1] == 0x8b {
+		return layerPath, nil
+	}
+
+	target, err := os.Create(gzipPath)
+	if err != nil {
+		return "", err
+	}
+	defer target.Close()
+	gw := gzip.NewWriter(target)
+	if _, err := io.Copy(gw, reader); err != nil {
+		return "", err
+	}
+	if err := gw.Close(); err != nil {
+		return "", err
+	}
+	return gzipPath, nil
+}
+
+func extractTar(tarball string, destination string) error {
+	file, err := os.Open(tarball)
+	if err != nil {
+		return errors.Wrap(err, "Unable to open docker archive")
+	}
+	defer file.Close()
+
+	tr := tar.NewReader(file)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "Unable to read docker archive")
+		}
+		name := path.Clean(header.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return errors.Errorf("Illegal path %s in docker archive", header.Name)
+		}
+		target := filepath.Join(destination, filepath.FromSlash(name))
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := util.MkdirAllWithPermissions(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := util.MkdirAllWithPermissions(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			out, err := os.Create(target)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(out, tr)
+			out.Close()
+			if err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			// docker save links identical layers to each other
+			if err := util.MkdirAllWithPermissions(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			linkTarget := path.Clean(path.Join(path.Dir(name), header.Linkname))
+			if path.IsAbs(header.Linkname) || linkTarget == ".." || strings.HasPrefix(linkTarget, "../") {
+				return errors.Errorf("Illegal link %s in docker archive", header.Name)
+			}
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// GetImageInfo get information about the archived image
+func (a *ArchiveRegistry) GetImageInfo(_ context.Context, _ string, _ string) (*runtime.ImageInfo, error) {
+	var v1Image V1Image
+	if err := json.Unmarshal(a.config, &v1Image); err != nil {
+		return nil, errors.Wrapf(err, "Failed to unmarshal image config from %s", a.reference)
+	}
+	return newImageInfo(a.repository, v1Image, util.CalculateDigest(a.manifest))
+}
+
+// GetTags return the tag of the archived image. Other repositories have no tags
+func (a *ArchiveRegistry) GetTags(_ context.Context, repository string) (*TagsAPIResponse, error) {
+	if repository != a.repository {
+		return &TagsAPIResponse{Name: repository, Tags: []string{}}, nil
+	}
+	return &TagsAPIResponse{Name: repository, Tags: []string{a.tag}}, nil
+}
+
+// GetImageConfig get image config
+func (a *ArchiveRegistry) GetImageConfig(_ context.Context, _ string, _ string) (map[string]interface{}, error) {
+	var result map[string]interface{}
+	if err := json.Unmarshal(a.config, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetManifest returns the normalized image manifest
+func (a *ArchiveRegistry) GetManifest(_ context.Context, _ string, _ string) (*ManifestV2, error) {
+	var manifest ManifestV2
+	if err := json.Unmarshal(a.manifest, &manifest); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal of manifest failed")
+	}
+	return &manifest, nil
+}
+
+// GetRawManifest returns the normalized image manifest bytes
+func (a *ArchiveRegistry) GetRawManifest(_ context.Context, _ string, _ string) ([]byte, error) {
+	return a.manifest, nil
+}
+
+// GetContainerConfig returns the image's container configuration
+func (a *ArchiveRegistry) GetContainerConfig(_ context.Context, _ string, _ string) (*ContainerConfig, error) {
+	var containerConfig ContainerConfig
+	if err := json.Unmarshal(a.config, &containerConfig); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal of container config failed")
+	}
+	return &containerConfig, nil
+}
+
+// LayerExists checks if the blob is in the archive
+func (a *ArchiveRegistry) LayerExists(_ context.Context, _ string, layerDigest string) (bool, error) {
+	_, ok := a.blobs[layerDigest]
+	return ok, nil
+}
+
+// PullLayer return the path to the blob in the archive
+func (a *ArchiveRegistry) PullLayer(_ context.Context, _ string, layerDigest string) (string, error) {
+	blobPath, ok := a.blobs[layerDigest]
+	if !ok {
+		return "", errors.Errorf("Blob %s is not in %s", layerDigest, a.reference)
+	}
+	return blobPath, nil
+}
+
+// PushLayer is not supported
+func (a *ArchiveRegistry) PushLayer(_ context.Context, _ io.Reader, _ string, _ string) error {
+	return errors.Errorf("Can not push to base image archive %s", a.reference)
+}
+
+// DeleteManifest is not supported
+func (a *ArchiveRegistry) DeleteManifest(_ context.Context, _ string, _ string) error {
+	return errors.Errorf("Can not delete from base image archive %s", a.reference)
+}
+
+// MountLayer is not supported
+func (a *ArchiveRegistry) MountLayer(_ context.Context, _ string, _ string, _ string) error {
+	return errors.Errorf("Can not mount to base image archive %s", a.reference)
+}
+
+// PushManifest is not supported
+func (a *ArchiveRegistry) PushManifest(_ context.Context, _ []byte, _ string, _ string) error {
+	return errors.Errorf("Can not push to base image archive %s", a.reference)
+}
@@ -0,0 +1,143 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/skatteetaten/architect/v2/pkg/util"
+	"github.com/stretchr/testify/assert"
+)
+
+const archiveImageConfig = `{"architecture":"amd64","os":"linux","config":{"Env":["BASE_IMAGE_VERSION=1.2.3","PATH=/usr/bin"]},"rootfs":{"type":"layers","diff_ids":[]}}`
+
+func TestArchiveRegistryFromDockerArchive(t *testing.T) {
+	dir := t.TempDir()
+	layer := tarBytes(t, map[string][]byte{"u01/file.txt": []byte("base")})
+	tarball := filepath.Join(dir, "base.tar")
+	writeTarFile(t, tarball, map[string][]byte{
+		"manifest.json": []byte(`[{"Config":"config.json","RepoTags":["registry.example.com:5000/aurora/wingnut11:1"],"Layers":["abc/layer.tar"]}]`),
+		"config.json":   []byte(archiveImageConfig),
+		"abc/layer.tar": layer,
+	})
+
+	archive, err := NewArchiveRegistry("docker-archive:" + tarball)
+	assert.NoError(t, err)
+	repository, tag := archive.ImageName()
+	assert.Equal(t, "aurora/wingnut11", repository)
+	assert.Equal(t, "1", tag)
+
+	assertArchiveImage(t, archive, repository)
+	archive.Close()
+	assert.NoDirExists(t, archive.workDir)
+}
+
+func TestArchiveRegistryFromOCILayout(t *testing.T) {
+	dir := t.TempDir()
+	layer := tarBytes(t, map[string][]byte{"u01/file.txt": []byte("base")})
+	layerDigest := writeBlob(t, dir, layer)
+	configDigest := writeBlob(t, dir, []byte(archiveImageConfig))
+	manifest, _ := json.Marshal(ociManifest{
+		MediaType: mediaTypeOCIManifest,
+		Config:    ociDescriptor{MediaType: "application/vnd.oci.image.config.v1+json", Digest: configDigest},
+		Layers:    []ociDescriptor{{MediaType: mediaTypeOCILayerTar, Digest: layerDigest, Size: len(layer)}},
+	})
+	manifestDigest := writeBlob(t, dir, manifest)
+	index, _ := json.Marshal(ociIndex{Manifests: []ociDescriptor{{
+		MediaType:   mediaTypeOCIManifest,
+		Digest:      manifestDigest,
+		Annotations: map[string]string{ociRefNameAnnotation: "1.2.3"},
+	}}})
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "index.json"), index, 0644))
+
+	archive, err := NewArchiveRegistry("oci-layout:" + dir)
+	assert.NoError(t, err)
+	repository, tag := archive.ImageName()
+	assert.Equal(t, filepath.Base(dir), repository)
+	assert.Equal(t, "1.2.3", tag)
+
+	assertArchiveImage(t, archive, repository)
+}
+
+func TestArchiveRegistryRejectsLinkOutsideArchive(t *testing.T) {
+	var buffer bytes.Buffer
+	tw := tar.NewWriter(&buffer)
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Name: "parent", Linkname: "..", Typeflag: tar.TypeSymlink}))
+	assert.NoError(t, tw.Close())
+	tarball := filepath.Join(t.TempDir(), "base.tar")
+	assert.NoError(t, os.WriteFile(tarball, buffer.Bytes(), 0644))
+
+	_, err := NewArchiveRegistry("docker-archive:" + tarball)
+	assert.ErrorContains(t, err, "Illegal link parent in docker archive")
+}
+
+func TestArchiveRegistryRejectsUnknownReference(t *testing.T) {
+	_, err := NewArchiveRegistry("docker-archive:/does/not/exist.tar")
+	assert.Error(t, err)
+	_, err = NewArchiveRegistry("aurora/wingnut11:1")
+	assert.Error(t, err)
+}
+
+func assertArchiveImage(t *testing.T, archive *ArchiveRegistry, repository string) {
+	ctx := context.Background()
+	imageInfo, err := archive.GetImageInfo(ctx, "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.2.3", imageInfo.CompleteBaseImageVersion)
+
+	manifest, err := archive.GetManifest(ctx, "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, httpHeaderContainerImageV1, manifest.Config.MediaType)
+	assert.Len(t, manifest.Layers, 1)
+	assert.Equal(t, mediaTypeDockerLayerGzip, manifest.Layers[0].MediaType)
+
+	// Layers are served gzipped, with the digest given in the manifest
+	layerPath, err := archive.PullLayer(ctx, "", manifest.Layers[0].Digest)
+	assert.NoError(t, err)
+	digest, err := util.CalculateDigestFromFile(layerPath)
+	assert.NoError(t, err)
+	assert.Equal(t, manifest.Layers[0].Digest, digest)
+	files, err := util.ListLayerFiles([]string{layerPath})
+	assert.NoError(t, err)
+	assert.Contains(t, files, "/u01/file.txt")
+
+	exists, err := archive.LayerExists(ctx, "", manifest.Config.Digest)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	tags, err := archive.GetTags(ctx, "aurora/other")
+	assert.NoError(t, err)
+	assert.Empty(t, tags.Tags)
+	tags, err = archive.GetTags(ctx, repository)
+	assert.NoError(t, err)
+	assert.Len(t, tags.Tags, 1)
+
+	assert.Error(t, archive.PushManifest(ctx, []byte("{}"), "aurora/other", "1"))
+}
+
+func tarBytes(t *testing.T, files map[string][]byte) []byte {
+	var buffer bytes.Buffer
+	tw := tar.NewWriter(&buffer)
+	for name, content := range files {
+		assert.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write(content)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tw.Close())
+	return buffer.Bytes()
+}
+
+func writeTarFile(t *testing.T, name string, files map[string][]byte) {
+	assert.NoError(t, os.WriteFile(name, tarBytes(t, files), 0644))
+}
+
+func writeBlob(t *testing.T, layoutDir string, data []byte) string {
+	digest := util.CalculateDigest(data)
+	blobPath := ociBlobPath(layoutDir, digest)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(blobPath), 0755))
+	assert.NoError(t, os.WriteFile(blobPath, data, 0644))
+	return digest
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"github.com/pkg/errors"
 	"os"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -108,12 +110,55 @@ func (c *ContainerConfig) Save(dstFolder string, filename string) error {
 	return nil
 }
 
+// addEnv replace variables already in the env, and append new variables sorted by name.
+// The config digest then only changes when the env does
 func (c *ContainerConfig) addEnv(env map[string]string) {
-	var envList []string
-	for k, v := range env {
-		envList = append(envList, fmt.Sprintf("%s=%s", k, v))
+	added := make(map[string]bool)
+	for i, entry := range c.Config.Env {
+		key := envKey(entry)
+		if value, exists := env[key]; exists {
+			c.Config.Env[i] = fmt.Sprintf("%s=%s", key, value)
+			added[key] = true
+		}
 	}
-	c.Config.Env = append(c.Config.Env, envList...)
+
+	var keys []string
+	for k := range env {
+		if !added[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		c.Config.Env = append(c.Config.Env, fmt.Sprintf("%s=%s", k, env[k]))
+	}
+}
+
+// removeEnv remove variables, and duplicates of the same variable where the last definition wins
+func (c *ContainerConfig) removeEnv(unset []string) {
+	removed := make(map[string]bool)
+	for _, key := range unset {
+		removed[key] = true
+	}
+
+	last := make(map[string]int)
+	for i, entry := range c.Config.Env {
+		last[envKey(entry)] = i
+	}
+
+	envList := make([]string, 0, len(c.Config.Env))
+	for i, entry := range c.Config.Env {
+		key := envKey(entry)
+		if removed[key] || last[key] != i {
+			continue
+		}
+		envList = append(envList, entry)
+	}
+	c.Config.Env = envList
+}
+
+func envKey(entry string) string {
+	return strings.SplitN(entry, "=", 2)[0]
 }
 
 func (c *ContainerConfig) addLabels(labels map[string]string) {
@@ -149,6 +194,7 @@ func (c *ContainerConfig) addHistoryEntry() {
 // Create container configuration
 func (c *ContainerConfig) Create(buildConfig BuildConfig) ([]byte, error) {
 	//Set env, labels, and cmd
+	c.removeEnv(buildConfig.UnsetEnv)
 	c.addEnv(buildConfig.Env)
 	c.addLabels(buildConfig.Labels)
 
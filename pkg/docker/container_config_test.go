@@ -32,3 +32,18 @@ func TestCreatedAndHistory(t *testing.T) {
 
 	assert.Equal(t, "architect", wip.History[10].CreatedBy)
 }
+
+func TestCreateReplacesAndSortsEnv(t *testing.T) {
+	config := ContainerConfig{}
+	config.Config.Labels = map[string]string{}
+	config.Config.Env = []string{"PATH=/usr/bin", "TZ=UTC", "HTTP_PROXY=proxy:3128", "JAVA_OPTS=-Xmx1g", "TZ=GMT"}
+
+	_, err := config.Create(BuildConfig{
+		Env:      map[string]string{"TZ": "Europe/Oslo", "APP_VERSION": "1.0.0", "AURORA_VERSION": "1.0.0-b1"},
+		UnsetEnv: []string{"HTTP_PROXY"},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"PATH=/usr/bin", "JAVA_OPTS=-Xmx1g", "TZ=Europe/Oslo",
+		"APP_VERSION=1.0.0", "AURORA_VERSION=1.0.0-b1"}, config.Config.Env)
+}
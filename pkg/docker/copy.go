@@ -0,0 +1,72 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/skatteetaten/architect/v2/pkg/util"
+	"io"
+	"os"
+)
+
+// CopyBlob make the blob available in the destination repository. A blob that already exists is left alone. With
+// mount the registry is asked to mount the blob from the source repository before it is streamed
+func CopyBlob(ctx context.Context, source Registry, sourceRepository string, destination Registry,
+	destinationRepository string, digest string, mount bool) error {
+	exists, err := destination.LayerExists(ctx, destinationRepository, digest)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to check blob %s", digest)
+	}
+	if exists {
+		logrus.Debugf("Blob %s exists in %s", digest, destinationRepository)
+		return nil
+	}
+
+	if mount {
+		err := destination.MountLayer(ctx, sourceRepository, destinationRepository, digest)
+		if err == nil {
+			return nil
+		}
+		logrus.Debugf("Unable to mount blob %s. Copy it: %v", digest, err)
+	}
+
+	blob, err := openBlob(ctx, source, sourceRepository, digest)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to pull blob %s", digest)
+	}
+	defer blob.Close()
+
+	if err := destination.PushLayer(ctx, blob, destinationRepository, digest); err != nil {
+		return errors.Wrapf(err, "Failed to push blob %s", digest)
+	}
+	return nil
+}
+
+func openBlob(ctx context.Context, source Registry, repository string, digest string) (io.ReadCloser, error) {
+	if opener, ok := source.(LayerOpener); ok {
+		return opener.OpenLayer(ctx, repository, digest)
+	}
+	path, err := source.PullLayer(ctx, repository, digest)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// GetVerifiedManifest the manifest bytes with the given digest, and the parsed manifest. Pushing the bytes unchanged
+// gives the copy the same digest
+func GetVerifiedManifest(ctx context.Context, registry Registry, repository string, digest string) ([]byte, *ManifestV2, error) {
+	data, err := registry.GetRawManifest(ctx, repository, digest)
+	if err != nil {
+		return nil, nil, err
+	}
+	if actual := util.CalculateDigest(data); actual != digest {
+		return nil, nil, errors.Errorf("Manifest of %s has digest %s, expected %s", repository, actual, digest)
+	}
+	var manifest ManifestV2
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, nil, errors.Wrap(err, "Unmarshal of manifest failed")
+	}
+	return data, &manifest, nil
+}
@@ -3,6 +3,7 @@ package docker
 import (
 	"github.com/sirupsen/logrus"
 	"github.com/skatteetaten/architect/v2/pkg/config/runtime"
+	"github.com/skatteetaten/architect/v2/pkg/util"
 	"os"
 	"os/user"
 	"path/filepath"
@@ -24,8 +25,15 @@ type BuildConfig struct {
 	OutputRegistry   string
 	Env              map[string]string
 	Labels           map[string]string
+	Maintainer       string
 	Cmd              []string
 	Entrypoint       []string
+	FileOwnership    []util.PathOwnership
+	// Paths in the base image to remove, and base image directories to replace with the layer content
+	RemovePaths        []string
+	ReplaceDirectories []string
+	// Variables from the base image that are removed from the image env
+	UnsetEnv []string
 }
 
 // GetDockerConfigPath path to the docker configuration file
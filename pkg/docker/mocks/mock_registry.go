@@ -44,6 +44,20 @@ func (m *MockRegistry) EXPECT() *MockRegistryMockRecorder {
 	return m.recorder
 }
 
+// DeleteManifest mocks base method.
+func (m *MockRegistry) DeleteManifest(ctx context.Context, repository, digest string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteManifest", ctx, repository, digest)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteManifest indicates an expected call of DeleteManifest.
+func (mr *MockRegistryMockRecorder) DeleteManifest(ctx, repository, digest interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteManifest", reflect.TypeOf((*MockRegistry)(nil).DeleteManifest), ctx, repository, digest)
+}
+
 // GetContainerConfig mocks base method.
 func (m *MockRegistry) GetContainerConfig(ctx context.Context, repository, digest string) (*docker.ContainerConfig, error) {
 	m.ctrl.T.Helper()
@@ -104,6 +118,21 @@ func (mr *MockRegistryMockRecorder) GetManifest(ctx, repository, digest interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetManifest", reflect.TypeOf((*MockRegistry)(nil).GetManifest), ctx, repository, digest)
 }
 
+// GetRawManifest mocks base method.
+func (m *MockRegistry) GetRawManifest(ctx context.Context, repository, reference string) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRawManifest", ctx, repository, reference)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRawManifest indicates an expected call of GetRawManifest.
+func (mr *MockRegistryMockRecorder) GetRawManifest(ctx, repository, reference interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRawManifest", reflect.TypeOf((*MockRegistry)(nil).GetRawManifest), ctx, repository, reference)
+}
+
 // GetTags mocks base method.
 func (m *MockRegistry) GetTags(ctx context.Context, repository string) (*docker.TagsAPIResponse, error) {
 	m.ctrl.T.Helper()
@@ -24,13 +24,21 @@ type Registry interface {
 	GetTags(ctx context.Context, repository string) (*TagsAPIResponse, error)
 	GetImageConfig(ctx context.Context, repository string, digest string) (map[string]interface{}, error)
 	GetManifest(ctx context.Context, repository string, digest string) (*ManifestV2, error)
+	GetRawManifest(ctx context.Context, repository string, reference string) ([]byte, error)
 	GetContainerConfig(ctx context.Context, repository string, digest string) (*ContainerConfig, error)
 	LayerExists(ctx context.Context, repository string, layerDigest string) (bool, error)
 	PushLayer(ctx context.Context, layer io.Reader, dstRepository string, layerDigest string) error
+	MountLayer(ctx context.Context, srcRepository string, dstRepository string, layerDigest string) error
 	PushManifest(ctx context.Context, manifest []byte, repository string, tag string) error
+	DeleteManifest(ctx context.Context, repository string, digest string) error
 	PullLayer(ctx context.Context, repository string, layerDigest string) (string, error)
 }
 
+// LayerOpener is implemented by registries that can stream a blob without a temporary file
+type LayerOpener interface {
+	OpenLayer(ctx context.Context, repository string, layerDigest string) (io.ReadCloser, error)
+}
+
 // Manifest schema representation
 type Manifest struct {
 	SchemaVersion int    `json:"schemaVersion"`
@@ -145,6 +153,15 @@ func (registry *RegistryClient) GetManifest(ctx context.Context, repository stri
 	return &manifest, nil
 }
 
+// GetRawManifest returns the manifest bytes as stored in the registry. Pushing them unchanged keeps the digest
+func (registry *RegistryClient) GetRawManifest(ctx context.Context, repository string, reference string) ([]byte, error) {
+	data, err := registry.getRegistryManifest(ctx, repository, reference)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to fetch image manifest for image %s:%s", repository, reference)
+	}
+	return data, nil
+}
+
 // GetContainerConfig returns the image's container configuration
 func (registry *RegistryClient) GetContainerConfig(ctx context.Context, repository string, digest string) (*ContainerConfig, error) {
 	data, err := registry.getRegistryBlob(ctx, repository, digest)
@@ -243,6 +260,14 @@ func (registry *RegistryClient) GetImageInfo(ctx context.Context, repository str
 		return nil, errors.Errorf("Error getting image manifest for %s from docker registry %s", repository, registry.connectionInfo.URL())
 	}
 
+	return newImageInfo(repository, v1Image, manifestDigest)
+}
+
+func newImageInfo(repository string, v1Image V1Image, manifestDigest string) (*runtime.ImageInfo, error) {
+	if v1Image.Config == nil {
+		return nil, errors.Errorf("Image %s has no container config", repository)
+	}
+
 	envMap := make(map[string]string)
 	for _, entry := range v1Image.Config.Env {
 		key, value, err := envKeyValue(entry)
@@ -323,6 +348,62 @@ func (registry *RegistryClient) PullLayer(ctx context.Context, repository string
 
 }
 
+// OpenLayer stream image blob from registry. The caller must close the blob
+func (registry *RegistryClient) OpenLayer(ctx context.Context, repository string, layerDigest string) (io.ReadCloser, error) {
+	path := fmt.Sprintf("/v2/%s/blobs/%s", repository, layerDigest)
+
+	req, err := registry.newRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not create the blob download request")
+	}
+
+	resp, err := registry.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed download")
+	}
+
+	if resp.StatusCode != 200 {
+		resp.Body.Close()
+		return nil, errors.Errorf("OpenLayer: Unexpected http code %d for blob %s", resp.StatusCode, layerDigest)
+	}
+	return resp.Body, nil
+}
+
+// MountLayer mount a blob from another repository in the same registry
+func (registry *RegistryClient) MountLayer(ctx context.Context, srcRepository string, dstRepository string, layerDigest string) error {
+	//POST /v2/<repository>/blobs/uploads/?mount=<digest>&from=<repository>
+	path := fmt.Sprintf("/v2/%s/blobs/uploads/?mount=%s&from=%s", dstRepository, url.QueryEscape(layerDigest),
+		url.QueryEscape(srcRepository))
+
+	req, err := registry.newRequest(ctx, "POST", path, bytes.NewBufferString(""))
+	if err != nil {
+		return errors.Wrap(err, "MountLayer: Request creation failed")
+	}
+
+	resp, err := registry.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "MountLayer: Request failed")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 201 {
+		logrus.Infof("Mounted layer %s:%s from %s", dstRepository, layerDigest, srcRepository)
+		return nil
+	}
+	if resp.StatusCode == 202 {
+		// The registry started an upload instead of mounting the blob. Cancel it
+		if location := resp.Header.Get("Location"); location != "" {
+			if cancel, err := registry.newLocationRequest(ctx, "DELETE", location, nil); err == nil {
+				if cancelResp, err := registry.client.Do(cancel); err == nil {
+					cancelResp.Body.Close()
+				}
+			}
+		}
+		return errors.Errorf("MountLayer: Registry did not mount %s from %s", layerDigest, srcRepository)
+	}
+	return errors.Errorf("MountLayer: Unexpected http code %d. From server: %s", resp.StatusCode, resp.Status)
+}
+
 // PushLayer push image blob
 func (registry *RegistryClient) PushLayer(ctx context.Context, layer io.Reader, repository string, layerDigest string) error {
 	//v2/repository/blobs/uploads/
@@ -345,7 +426,7 @@ func (registry *RegistryClient) PushLayer(ctx context.Context, layer io.Reader,
 	}
 
 	location := resp.Header.Get("Location")
-	req, err = registry.newRequest(ctx, "PATCH", location, layer)
+	req, err = registry.newLocationRequest(ctx, "PATCH", location, layer)
 	if err != nil {
 		return errors.Wrap(err, "Upload request creation failed")
 	}
@@ -368,7 +449,7 @@ func (registry *RegistryClient) PushLayer(ctx context.Context, layer io.Reader,
 	}
 
 	location = resp.Header.Get("Location")
-	req, err = registry.newRequest(ctx, "PUT", location, nil)
+	req, err = registry.newLocationRequest(ctx, "PUT", location, nil)
 	if err != nil {
 		return errors.Wrap(err, "Commit request creation failed")
 	}
@@ -405,7 +486,7 @@ func (registry *RegistryClient) PushManifest(ctx context.Context, manifest []byt
 		return errors.Wrap(err, "PushManifest: request creation failed")
 	}
 
-	req.Header.Set("Content-Type", "application/vnd.docker.distribution.manifest.v2+json")
+	req.Header.Set("Content-Type", manifestMediaType(manifest))
 
 	resp, err := registry.client.Do(req)
 	if err != nil {
@@ -425,6 +506,44 @@ func (registry *RegistryClient) PushManifest(ctx context.Context, manifest []byt
 	return nil
 }
 
+// manifestMediaType the media type declared in the manifest, docker v2 if it has none
+func manifestMediaType(manifest []byte) string {
+	var header struct {
+		MediaType string `json:"mediaType"`
+	}
+	if err := json.Unmarshal(manifest, &header); err != nil || header.MediaType == "" {
+		return httpHeaderManifestSchemaV2
+	}
+	return header.MediaType
+}
+
+// DeleteManifest delete a manifest, and all the tags that reference it
+func (registry *RegistryClient) DeleteManifest(ctx context.Context, repository string, digest string) error {
+	//DELETE /v2/<repository>/manifests/<digest>
+	path := fmt.Sprintf("/v2/%s/manifests/%s", repository, digest)
+	req, err := registry.newRequest(ctx, "DELETE", path, nil)
+	if err != nil {
+		return errors.Wrap(err, "DeleteManifest: request creation failed")
+	}
+
+	resp, err := registry.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "DeleteManifest: Request failed")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 202 {
+		respData, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return errors.Wrap(err, "DeleteManifest: ")
+		}
+		return errors.Errorf("DeleteManifest: Unexpected http code %d. From server: %s ", resp.StatusCode, string(respData))
+	}
+
+	logrus.Infof("Deleted manifest %s@%s", repository, digest)
+	return nil
+}
+
 // GetTags return image tags for a given repository
 func (registry *RegistryClient) GetTags(ctx context.Context, repository string) (*TagsAPIResponse, error) {
 	path := fmt.Sprintf("/v2/%s/tags/list", repository)
@@ -473,6 +592,23 @@ func envKeyValue(target string) (string, string, error) {
 	return "", "", errors.Errorf("Invalid env declaration: %s", target)
 }
 
+// newLocationRequest a request to the Location header of an upload. The registry may return a path or an absolute
+// URL, and a path is resolved against the registry URL
+func (registry *RegistryClient) newLocationRequest(ctx context.Context, method string, location string,
+	body io.Reader) (*http.Request, error) {
+	reference, err := url.Parse(location)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid location %s", location)
+	}
+	u := registry.connectionInfo.URL().ResolveReference(reference)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, errors.Wrap(err, "Request creation failed")
+	}
+	return req, nil
+}
+
 func (registry *RegistryClient) newRequest(ctx context.Context, method string, path string, body io.Reader) (*http.Request, error) {
 
 	reference, err := url.ParseRequestURI(path)
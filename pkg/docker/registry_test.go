@@ -265,6 +265,35 @@ func TestReadingOfEnvStrings(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestMountLayerCancelsUploadAtAbsoluteLocation(t *testing.T) {
+	mux := http.NewServeMux()
+	var ts *httptest.Server
+	cancelled := false
+
+	mux.HandleFunc("/v2/test/architect/blobs/uploads/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/v2/test/architect/blobs/uploads/?mount=sha256%3A666&from=test%2Fsource", r.RequestURI)
+		w.Header().Set("Location", ts.URL+"/v2/test/architect/blobs/uploads/1234?state=abc")
+		w.WriteHeader(202)
+	})
+	mux.HandleFunc("/v2/test/architect/blobs/uploads/1234", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "DELETE", r.Method)
+		assert.Equal(t, "/v2/test/architect/blobs/uploads/1234?state=abc", r.RequestURI)
+		cancelled = true
+		w.WriteHeader(204)
+	})
+
+	ts = httptest.NewUnstartedServer(mux)
+	ts.StartTLS()
+	defer ts.Close()
+
+	target := createTestRegistryClient(ts)
+
+	err := target.MountLayer(context.Background(), "test/source", "test/architect", "sha256:666")
+	assert.EqualError(t, err, "MountLayer: Registry did not mount sha256:666 from test/source")
+	assert.True(t, cancelled)
+}
+
 func createTestRegistryClient(server *httptest.Server) Registry {
 	u, _ := url.Parse(server.URL)
 	var port string
@@ -3,6 +3,7 @@ package config
 import (
 	"encoding/json"
 	"github.com/pkg/errors"
+	"github.com/skatteetaten/architect/v2/pkg/util"
 	"io"
 )
 
@@ -16,8 +17,12 @@ type DeliverableMetadata struct {
 
 // MetadataDocker maintainer and labels. These values are appended to the resulting image.
 type MetadataDocker struct {
-	Maintainer string            `json:"maintainer"`
-	Labels     map[string]string `json:"labels"`
+	Maintainer         string               `json:"maintainer"`
+	Labels             map[string]string    `json:"labels"`
+	FileOwnership      []util.PathOwnership `json:"fileOwnership"`
+	Remove             []string             `json:"remove"`
+	ReplaceDirectories []string             `json:"replaceDirectories"`
+	UnsetEnv           []string             `json:"unsetEnv"`
 }
 
 // MetadataDoozer build specific information for dozer builds.
@@ -1,6 +1,7 @@
 package prepare
 
 import (
+	"context"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/skatteetaten/architect/v2/pkg/config"
@@ -18,11 +19,16 @@ import (
 )
 
 type buildConfiguration struct {
-	BuildContext string
-	Env          map[string]string
-	Labels       map[string]string
-	Cmd          []string
-	EntryPoint   []string
+	BuildContext       string
+	Env                map[string]string
+	Labels             map[string]string
+	Maintainer         string
+	Cmd                []string
+	EntryPoint         []string
+	FileOwnership      []util.PathOwnership
+	RemovePaths        []string
+	ReplaceDirectories []string
+	UnsetEnv           []string
 }
 
 const (
@@ -31,30 +37,35 @@ const (
 
 // Prepper prepare build context
 func Prepper() process.Prepper {
-	return func(cfg *config.Config, auroraVersion *runtime.AuroraVersion, deliverable nexus.Deliverable,
+	return func(ctx context.Context, cfg *config.Config, auroraVersion *runtime.AuroraVersion, deliverable nexus.Deliverable,
 		baseImage runtime.BaseImage) (*docker.BuildConfig, error) {
 
-		buildContext, err := prepareLayers(cfg.DockerSpec, auroraVersion, deliverable, baseImage)
+		buildContext, err := prepareLayers(ctx, cfg.DockerSpec, auroraVersion, deliverable, baseImage)
 
 		if err != nil {
 			return nil, errors.Wrap(err, "Error prepare artifact")
 		}
 
 		return &docker.BuildConfig{
-			AuroraVersion:    auroraVersion,
-			BuildFolder:      buildContext.BuildContext,
-			DockerRepository: cfg.DockerSpec.OutputRepository,
-			Image:            baseImage.DockerImage,
-			Env:              buildContext.Env,
-			Labels:           buildContext.Labels,
-			Cmd:              buildContext.Cmd,
-			Entrypoint:       buildContext.EntryPoint,
+			AuroraVersion:      auroraVersion,
+			BuildFolder:        buildContext.BuildContext,
+			DockerRepository:   cfg.DockerSpec.OutputRepository,
+			Image:              baseImage.DockerImage,
+			Env:                buildContext.Env,
+			Labels:             buildContext.Labels,
+			Maintainer:         buildContext.Maintainer,
+			Cmd:                buildContext.Cmd,
+			Entrypoint:         buildContext.EntryPoint,
+			FileOwnership:      buildContext.FileOwnership,
+			RemovePaths:        buildContext.RemovePaths,
+			ReplaceDirectories: buildContext.ReplaceDirectories,
+			UnsetEnv:           buildContext.UnsetEnv,
 		}, nil
 
 	}
 }
 
-func prepareLayers(dockerSpec config.DockerSpec, auroraVersions *runtime.AuroraVersion, deliverable nexus.Deliverable, baseImage runtime.BaseImage) (*buildConfiguration, error) {
+func prepareLayers(ctx context.Context, dockerSpec config.DockerSpec, auroraVersions *runtime.AuroraVersion, deliverable nexus.Deliverable, baseImage runtime.BaseImage) (*buildConfiguration, error) {
 	//Create build context
 	buildContext, err := ioutil.TempDir("", "deliverable")
 	filewriter := util.NewFileWriter(buildContext)
@@ -73,7 +84,7 @@ func prepareLayers(dockerSpec config.DockerSpec, auroraVersions *runtime.AuroraV
 
 	// Unzip deliverable
 	applicationFolder := filepath.Join(buildContext, util.ApplicationBuildFolder)
-	err = util.ExtractAndRenameDeliverable(buildContext, deliverable.Path)
+	err = util.ExtractAndRenameDeliverable(ctx, buildContext, deliverable.Path)
 
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to extract application archive")
@@ -150,11 +161,16 @@ func prepareLayers(dockerSpec config.DockerSpec, auroraVersions *runtime.AuroraV
 	}
 
 	return &buildConfiguration{
-		BuildContext: buildContext,
-		Env:          imageMetadata.Env,
-		Labels:       imageMetadata.Labels,
-		Cmd:          cmd,
-		EntryPoint:   entrypoint,
+		BuildContext:       buildContext,
+		Env:                imageMetadata.Env,
+		Labels:             imageMetadata.Labels,
+		Maintainer:         imageMetadata.Maintainer,
+		Cmd:                cmd,
+		EntryPoint:         entrypoint,
+		FileOwnership:      deliverableMetadata.Docker.FileOwnership,
+		RemovePaths:        deliverableMetadata.Docker.Remove,
+		ReplaceDirectories: deliverableMetadata.Docker.ReplaceDirectories,
+		UnsetEnv:           deliverableMetadata.Docker.UnsetEnv,
 	}, nil
 }
 
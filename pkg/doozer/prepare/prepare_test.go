@@ -1,6 +1,7 @@
 package prepare
 
 import (
+	"context"
 	"github.com/skatteetaten/architect/v2/pkg/config"
 	"github.com/skatteetaten/architect/v2/pkg/config/runtime"
 	"github.com/skatteetaten/architect/v2/pkg/nexus"
@@ -11,7 +12,7 @@ import (
 )
 
 func TestArchitectPrepareLayers(t *testing.T) {
-	buildconfiguration, err := prepareLayers(config.DockerSpec{
+	buildconfiguration, err := prepareLayers(context.Background(), config.DockerSpec{
 		OutputRegistry:         "",
 		OutputRepository:       "",
 		InternalPullRegistry:   "",
@@ -49,7 +50,7 @@ func TestArchitectPrepareLayers(t *testing.T) {
 }
 
 func TestPrepareLayers(t *testing.T) {
-	buildconfiguration, err := prepareLayers(config.DockerSpec{
+	buildconfiguration, err := prepareLayers(context.Background(), config.DockerSpec{
 		OutputRegistry:         "",
 		OutputRepository:       "",
 		InternalPullRegistry:   "",
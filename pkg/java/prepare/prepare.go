@@ -1,6 +1,7 @@
 package prepare
 
 import (
+	"context"
 	"github.com/pkg/errors"
 	"github.com/skatteetaten/architect/v2/pkg/config"
 	"github.com/skatteetaten/architect/v2/pkg/config/runtime"
@@ -15,34 +16,44 @@ import (
 )
 
 type buildConfiguration struct {
-	BuildContext string
-	Env          map[string]string
-	Labels       map[string]string
-	Cmd          []string
+	BuildContext       string
+	Env                map[string]string
+	Labels             map[string]string
+	Maintainer         string
+	Cmd                []string
+	FileOwnership      []util.PathOwnership
+	RemovePaths        []string
+	ReplaceDirectories []string
+	UnsetEnv           []string
 }
 
 // Prepper prepare java image layers
 func Prepper() process.Prepper {
-	return func(cfg *config.Config, auroraVersion *runtime.AuroraVersion, deliverable nexus.Deliverable,
+	return func(ctx context.Context, cfg *config.Config, auroraVersion *runtime.AuroraVersion, deliverable nexus.Deliverable,
 		baseImage runtime.BaseImage) (*docker.BuildConfig, error) {
-		buildConfiguration, err := prepareLayers(cfg.DockerSpec, auroraVersion, deliverable)
+		buildConfiguration, err := prepareLayers(ctx, cfg.DockerSpec, auroraVersion, deliverable)
 		if err != nil {
 			return nil, errors.Wrap(err, "Error while preparing layers")
 		}
 		return &docker.BuildConfig{
-			AuroraVersion:    auroraVersion,
-			DockerRepository: cfg.DockerSpec.OutputRepository,
-			BuildFolder:      buildConfiguration.BuildContext,
-			Image:            baseImage.DockerImage,
-			Env:              buildConfiguration.Env,
-			Labels:           buildConfiguration.Labels,
-			Cmd:              buildConfiguration.Cmd,
+			AuroraVersion:      auroraVersion,
+			DockerRepository:   cfg.DockerSpec.OutputRepository,
+			BuildFolder:        buildConfiguration.BuildContext,
+			Image:              baseImage.DockerImage,
+			Env:                buildConfiguration.Env,
+			Labels:             buildConfiguration.Labels,
+			Maintainer:         buildConfiguration.Maintainer,
+			Cmd:                buildConfiguration.Cmd,
+			FileOwnership:      buildConfiguration.FileOwnership,
+			RemovePaths:        buildConfiguration.RemovePaths,
+			ReplaceDirectories: buildConfiguration.ReplaceDirectories,
+			UnsetEnv:           buildConfiguration.UnsetEnv,
 		}, nil
 	}
 }
 
 // TODO: Vurder om vi kan trekke ut prepare layer, slik at den kan gjenbrukes på tvers av byggene våre. Metoden er veldig lik doozer sin
-func prepareLayers(dockerSpec config.DockerSpec, auroraVersions *runtime.AuroraVersion, deliverable nexus.Deliverable) (*buildConfiguration, error) {
+func prepareLayers(ctx context.Context, dockerSpec config.DockerSpec, auroraVersions *runtime.AuroraVersion, deliverable nexus.Deliverable) (*buildConfiguration, error) {
 	buildPath, err := os.MkdirTemp("", "deliverable")
 
 	if err != nil {
@@ -61,7 +72,7 @@ func prepareLayers(dockerSpec config.DockerSpec, auroraVersions *runtime.AuroraV
 	applicationRoot := filepath.Join(buildPath, util.DockerfileApplicationFolder)
 	renamedApplicationFolder := filepath.Join(buildPath, "/layer/u01/application")
 
-	if err := util.ExtractDeliverable(deliverable.Path, applicationRoot); err != nil {
+	if err := util.ExtractDeliverable(ctx, deliverable.Path, applicationRoot); err != nil {
 		return nil, errors.Wrapf(err, "Failed to extract application archive")
 	}
 
@@ -90,10 +101,15 @@ func prepareLayers(dockerSpec config.DockerSpec, auroraVersions *runtime.AuroraV
 	}
 
 	return &buildConfiguration{
-		BuildContext: buildPath,
-		Env:          createEnv(*auroraVersions, dockerSpec.PushExtraTags, docker.GetUtcTimestamp()),
-		Labels:       createLabels(*meta),
-		Cmd:          nil,
+		BuildContext:       buildPath,
+		Env:                createEnv(*auroraVersions, dockerSpec.PushExtraTags, docker.GetUtcTimestamp()),
+		Labels:             createLabels(*meta),
+		Maintainer:         meta.Docker.Maintainer,
+		Cmd:                nil,
+		FileOwnership:      meta.Docker.FileOwnership,
+		RemovePaths:        meta.Docker.Remove,
+		ReplaceDirectories: meta.Docker.ReplaceDirectories,
+		UnsetEnv:           meta.Docker.UnsetEnv,
 	}, nil
 }
 
@@ -1,6 +1,7 @@
 package prepare
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/sirupsen/logrus"
 	"github.com/skatteetaten/architect/v2/pkg/config"
@@ -14,7 +15,7 @@ import (
 
 func TestPrepareLayers(t *testing.T) {
 
-	buildConfiguration, err := prepareLayers(config.DockerSpec{
+	buildConfiguration, err := prepareLayers(context.Background(), config.DockerSpec{
 		OutputRegistry:         "https://localhost:5000",
 		OutputRepository:       "aurora/minarch",
 		InternalPullRegistry:   "https://localhost:5000",
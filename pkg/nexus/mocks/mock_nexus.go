@@ -5,6 +5,7 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -36,16 +37,16 @@ func (m *MockDownloader) EXPECT() *MockDownloaderMockRecorder {
 }
 
 // DownloadArtifact mocks base method.
-func (m *MockDownloader) DownloadArtifact(c *config.MavenGav) (nexus.Deliverable, error) {
+func (m *MockDownloader) DownloadArtifact(ctx context.Context, c *config.MavenGav) (nexus.Deliverable, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DownloadArtifact", c)
+	ret := m.ctrl.Call(m, "DownloadArtifact", ctx, c)
 	ret0, _ := ret[0].(nexus.Deliverable)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // DownloadArtifact indicates an expected call of DownloadArtifact.
-func (mr *MockDownloaderMockRecorder) DownloadArtifact(c interface{}) *gomock.Call {
+func (mr *MockDownloaderMockRecorder) DownloadArtifact(ctx, c interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DownloadArtifact", reflect.TypeOf((*MockDownloader)(nil).DownloadArtifact), c)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DownloadArtifact", reflect.TypeOf((*MockDownloader)(nil).DownloadArtifact), ctx, c)
 }
@@ -1,6 +1,7 @@
 package nexus
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 	"github.com/pkg/errors"
@@ -18,14 +19,16 @@ import (
 
 // Downloader interface
 type Downloader interface {
-	DownloadArtifact(c *config.MavenGav) (Deliverable, error)
+	DownloadArtifact(ctx context.Context, c *config.MavenGav) (Deliverable, error)
 }
 
 // MavenDownloader configuration
 type MavenDownloader struct {
-	baseURL  string
-	username string
-	password string
+	baseURL                string
+	username               string
+	password               string
+	repositoryPath         string
+	snapshotRepositoryPath string
 }
 
 // BinaryDownloader configuration
@@ -70,7 +73,7 @@ func NewBinaryDownloader(path string) Downloader {
 }
 
 // DownloadArtifact prepare the binary artifact
-func (n *BinaryDownloader) DownloadArtifact(_ *config.MavenGav) (Deliverable, error) {
+func (n *BinaryDownloader) DownloadArtifact(_ context.Context, _ *config.MavenGav) (Deliverable, error) {
 	deliverable := Deliverable{
 		Path: n.Path,
 	}
@@ -83,17 +86,20 @@ func (n *BinaryDownloader) DownloadArtifact(_ *config.MavenGav) (Deliverable, er
 	return deliverable, nil
 }
 
-// NewMavenDownloader MavenDownloader of type Downloader
-func NewMavenDownloader(baseURL string, username string, password string) Downloader {
+// NewMavenDownloader MavenDownloader of type Downloader. Downloads from the release and snapshot repositories of
+// nexusAccess, which may be any repository with the Maven 2 layout
+func NewMavenDownloader(nexusAccess config.NexusAccess) Downloader {
 	return &MavenDownloader{
-		baseURL:  baseURL,
-		username: username,
-		password: password,
+		baseURL:                nexusAccess.NexusURL,
+		username:               nexusAccess.Username,
+		password:               nexusAccess.Password,
+		repositoryPath:         nexusAccess.MavenRepositoryPath(false),
+		snapshotRepositoryPath: nexusAccess.MavenRepositoryPath(true),
 	}
 }
 
 // DownloadArtifact downloads a maven artifact
-func (n *MavenDownloader) DownloadArtifact(c *config.MavenGav) (Deliverable, error) {
+func (n *MavenDownloader) DownloadArtifact(ctx context.Context, c *config.MavenGav) (Deliverable, error) {
 	deliverable := Deliverable{}
 
 	httpClient := &http.Client{
@@ -110,14 +116,14 @@ func (n *MavenDownloader) DownloadArtifact(c *config.MavenGav) (Deliverable, err
 			return deliverable, errors.Wrapf(err, "Unable to parse nexus url %s", n.baseURL)
 		}
 		//Set path
-		u.Path = createMavenManifestPath(c)
+		u.Path = path.Join("/", u.Path, createMavenManifestPath(n.snapshotRepositoryPath, c))
 		logrus.Infof("Downloading artifact from %s", u.String())
 
-		req, err := http.NewRequest("GET", u.String(), nil)
-		req.Header.Set("Accept", "application/xml")
+		req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
 		if err != nil {
 			return deliverable, errors.Wrapf(err, "Failed to create request for Nexus url %s", u.String())
 		}
+		req.Header.Set("Accept", "application/xml")
 		if n.username != "" && n.password != "" {
 			req.SetBasicAuth(n.username, n.password)
 		}
@@ -128,22 +134,36 @@ func (n *MavenDownloader) DownloadArtifact(c *config.MavenGav) (Deliverable, err
 
 		defer resp.Body.Close()
 
-		err = xml.NewDecoder(resp.Body).Decode(&mavenManifest)
-		if err != nil {
-			return deliverable, errors.Wrapf(err, "Requested resource %s. XML decode failed", u.String())
+		switch resp.StatusCode {
+		case http.StatusOK:
+			err = xml.NewDecoder(resp.Body).Decode(&mavenManifest)
+			if err != nil {
+				return deliverable, errors.Wrapf(err, "Requested resource %s. XML decode failed", u.String())
+			}
+		case http.StatusNotFound:
+			// Repositories with non-unique snapshots have no metadata, and the file has the version as its name
+			logrus.Infof("No snapshot metadata at %s. Downloading the non-unique snapshot", u.String())
+		default:
+			return deliverable, errors.Errorf("Could not download snapshot metadata. Status code %s , Location %s",
+				resp.Status, u.String())
 		}
 	}
 
+	repositoryPath := n.repositoryPath
+	if c.IsSnapshot() {
+		repositoryPath = n.snapshotRepositoryPath
+	}
+
 	// Create download url
 	u, err := url.Parse(n.baseURL)
 	if err != nil {
 		return deliverable, errors.Wrapf(err, "Unable to parse nexus url %s", n.baseURL)
 	}
 	// Set path
-	u.Path = createDownloadPath(mavenManifest, c)
+	u.Path = path.Join("/", u.Path, createDownloadPath(repositoryPath, mavenManifest, c))
 	logrus.Infof("Downloading artifact from %s", u.String())
 
-	req, err := http.NewRequest("GET", u.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
 	if err != nil {
 		return deliverable, errors.Wrapf(err, "Failed to create request for Nexus url %s", u.String())
 	}
@@ -190,15 +210,15 @@ func (n *MavenDownloader) DownloadArtifact(c *config.MavenGav) (Deliverable, err
 
 }
 
-func createMavenManifestPath(c *config.MavenGav) string {
+func createMavenManifestPath(repositoryPath string, c *config.MavenGav) string {
 	groupID := strings.ReplaceAll(c.GroupID, ".", "/")
-	return fmt.Sprintf("/repository/maven-intern/%s/%s/%s/maven-metadata.xml", groupID, c.ArtifactID, c.Version)
+	return path.Join("/", repositoryPath, groupID, c.ArtifactID, c.Version, "maven-metadata.xml")
 }
 
-func createDownloadPath(manifest MavenManifest, c *config.MavenGav) string {
+func createDownloadPath(repositoryPath string, manifest MavenManifest, c *config.MavenGav) string {
 	groupID := strings.ReplaceAll(c.GroupID, ".", "/")
 	artifact := createFileName(c, manifest)
-	return fmt.Sprintf("/repository/maven-intern/%s/%s/%s/%s", groupID, c.ArtifactID, c.Version, artifact)
+	return path.Join("/", repositoryPath, groupID, c.ArtifactID, c.Version, artifact)
 }
 
 func createFileName(gav *config.MavenGav, manifest MavenManifest) string {
@@ -3,6 +3,7 @@ package nexus
 import (
 	"archive/zip"
 	"bytes"
+	"context"
 	"github.com/skatteetaten/architect/v2/pkg/config"
 	"github.com/stretchr/testify/assert"
 	"log"
@@ -19,7 +20,7 @@ func TestNewLocalDownloader(t *testing.T) {
 		GroupID:    "ske",
 		Version:    "develop-SNAPSHOT",
 	}
-	l, err := d.DownloadArtifact(&m)
+	l, err := d.DownloadArtifact(context.Background(), &m)
 
 	expected := "test"
 	if l.Path != expected {
@@ -124,7 +125,7 @@ func TestMavenDownloaderOnSnapshot(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	mavenDownloader := NewMavenDownloader(srv.URL, "username", "password")
+	mavenDownloader := NewMavenDownloader(config.NexusAccess{NexusURL: srv.URL, Username: "username", Password: "password"})
 
 	maven := config.MavenGav{
 		ArtifactID: "architect",
@@ -134,7 +135,7 @@ func TestMavenDownloaderOnSnapshot(t *testing.T) {
 		Type:       "zip",
 	}
 
-	_, err := mavenDownloader.DownloadArtifact(&maven)
+	_, err := mavenDownloader.DownloadArtifact(context.Background(), &maven)
 	assert.NoError(t, err)
 }
 
@@ -152,7 +153,7 @@ func TestMavenDownloaderOnRelease(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	mavenDownloader := NewMavenDownloader(srv.URL, "username", "password")
+	mavenDownloader := NewMavenDownloader(config.NexusAccess{NexusURL: srv.URL, Username: "username", Password: "password"})
 
 	maven := config.MavenGav{
 		ArtifactID: "architect",
@@ -162,10 +163,65 @@ func TestMavenDownloaderOnRelease(t *testing.T) {
 		Type:       "zip",
 	}
 
-	_, err := mavenDownloader.DownloadArtifact(&maven)
+	_, err := mavenDownloader.DownloadArtifact(context.Background(), &maven)
 	assert.NoError(t, err)
 }
 
+func TestMavenDownloaderOnRepositoryPaths(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.RequestURI {
+		case "/artifactory/libs-release-local/no/skatteetaten/aurora/architect/1.0.0/architect-1.0.0.zip":
+		case "/artifactory/libs-snapshot-local/no/skatteetaten/aurora/architect/1.0-SNAPSHOT/maven-metadata.xml":
+			// A repository with non-unique snapshots
+			w.WriteHeader(http.StatusNotFound)
+			return
+		case "/artifactory/libs-snapshot-local/no/skatteetaten/aurora/architect/1.0-SNAPSHOT/architect-1.0-SNAPSHOT.zip":
+		default:
+			t.Errorf("Unexpected call %s", r.RequestURI)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		data, err := createZipFile()
+		assert.NoError(t, err)
+		w.Write(data.Bytes())
+	}))
+	defer srv.Close()
+
+	mavenDownloader := NewMavenDownloader(config.NexusAccess{
+		NexusURL:               srv.URL + "/artifactory",
+		RepositoryPath:         "libs-release-local",
+		SnapshotRepositoryPath: "libs-snapshot-local/",
+	})
+
+	for _, version := range []string{"1.0.0", "1.0-SNAPSHOT"} {
+		deliverable, err := mavenDownloader.DownloadArtifact(context.Background(), &config.MavenGav{
+			ArtifactID: "architect",
+			GroupID:    "no.skatteetaten.aurora",
+			Version:    version,
+			Type:       "zip",
+		})
+		assert.NoError(t, err)
+		assert.FileExists(t, deliverable.Path)
+	}
+}
+
+func TestMavenDownloaderCancelled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Unexpected call %s", r.RequestURI)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := NewMavenDownloader(config.NexusAccess{NexusURL: srv.URL}).DownloadArtifact(ctx, &config.MavenGav{
+		ArtifactID: "architect",
+		GroupID:    "no.skatteetaten.aurora",
+		Version:    "1.0.0",
+		Type:       "zip",
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
 func TestCreateFileName(t *testing.T) {
 	// case 1 - release
 	gav := config.MavenGav{
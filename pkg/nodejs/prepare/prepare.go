@@ -1,6 +1,7 @@
 package prepare
 
 import (
+	"context"
 	process "github.com/skatteetaten/architect/v2/pkg/process/build"
 	"os"
 	"strings"
@@ -15,41 +16,51 @@ import (
 )
 
 type buildConfiguration struct {
-	BuildContext string
-	Env          map[string]string
-	Labels       map[string]string
-	Cmd          []string
+	BuildContext       string
+	Env                map[string]string
+	Labels             map[string]string
+	Maintainer         string
+	Cmd                []string
+	FileOwnership      []util.PathOwnership
+	RemovePaths        []string
+	ReplaceDirectories []string
+	UnsetEnv           []string
 }
 
 // Prepper prepare the image build context
 func Prepper() process.Prepper {
-	return func(cfg *config.Config, auroraVersion *runtime.AuroraVersion, deliverable nexus.Deliverable,
+	return func(ctx context.Context, cfg *config.Config, auroraVersion *runtime.AuroraVersion, deliverable nexus.Deliverable,
 		baseImage runtime.BaseImage) (*docker.BuildConfig, error) {
 
-		buildConfiguration, err := prepareLayers(cfg.DockerSpec, auroraVersion, deliverable, baseImage)
+		buildConfiguration, err := prepareLayers(ctx, cfg.DockerSpec, auroraVersion, deliverable, baseImage)
 		if err != nil {
 			return nil, errors.Wrap(err, "Error while preparing layers")
 		}
 
 		return &docker.BuildConfig{
-			AuroraVersion:    auroraVersion,
-			DockerRepository: cfg.DockerSpec.OutputRepository,
-			BuildFolder:      buildConfiguration.BuildContext,
-			Image:            baseImage.DockerImage,
-			Env:              buildConfiguration.Env,
-			Labels:           buildConfiguration.Labels,
-			Cmd:              buildConfiguration.Cmd,
+			AuroraVersion:      auroraVersion,
+			DockerRepository:   cfg.DockerSpec.OutputRepository,
+			BuildFolder:        buildConfiguration.BuildContext,
+			Image:              baseImage.DockerImage,
+			Env:                buildConfiguration.Env,
+			Labels:             buildConfiguration.Labels,
+			Maintainer:         buildConfiguration.Maintainer,
+			Cmd:                buildConfiguration.Cmd,
+			FileOwnership:      buildConfiguration.FileOwnership,
+			RemovePaths:        buildConfiguration.RemovePaths,
+			ReplaceDirectories: buildConfiguration.ReplaceDirectories,
+			UnsetEnv:           buildConfiguration.UnsetEnv,
 		}, nil
 	}
 }
 
-func prepareLayers(dockerSpec config.DockerSpec, auroraVersion *runtime.AuroraVersion, deliverable nexus.Deliverable, baseImage runtime.BaseImage) (*buildConfiguration, error) {
+func prepareLayers(ctx context.Context, dockerSpec config.DockerSpec, auroraVersion *runtime.AuroraVersion, deliverable nexus.Deliverable, baseImage runtime.BaseImage) (*buildConfiguration, error) {
 	openshiftJSON, err := findOpenshiftJSONInTarball(deliverable.Path)
 	if err != nil {
 		return nil, err
 	}
 
-	buildPath, err := extractTarball(deliverable.Path)
+	buildPath, err := extractTarball(ctx, deliverable.Path)
 	if err != nil {
 		return nil, err
 	}
@@ -132,10 +143,15 @@ func prepareLayers(dockerSpec config.DockerSpec, auroraVersion *runtime.AuroraVe
 	}
 
 	return &buildConfiguration{
-		BuildContext: buildPath,
-		Env:          dockerData.Env,
-		Labels:       dockerData.Labels,
-		Cmd:          []string{"/u01/bin/run_nginx"},
+		BuildContext:       buildPath,
+		Env:                dockerData.Env,
+		Labels:             dockerData.Labels,
+		Maintainer:         openshiftJSON.DockerMetadata.Maintainer,
+		Cmd:                []string{"/u01/bin/run_nginx"},
+		FileOwnership:      openshiftJSON.DockerMetadata.FileOwnership,
+		RemovePaths:        openshiftJSON.DockerMetadata.Remove,
+		ReplaceDirectories: openshiftJSON.DockerMetadata.ReplaceDirectories,
+		UnsetEnv:           openshiftJSON.DockerMetadata.UnsetEnv,
 	}, nil
 
 }
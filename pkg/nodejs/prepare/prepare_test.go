@@ -1,6 +1,7 @@
 package prepare
 
 import (
+	"context"
 	"github.com/skatteetaten/architect/v2/pkg/config"
 	"github.com/skatteetaten/architect/v2/pkg/config/runtime"
 	"github.com/skatteetaten/architect/v2/pkg/nexus"
@@ -11,7 +12,7 @@ import (
 
 func TestPrepareLayers(t *testing.T) {
 
-	buildConfiguration, err := prepareLayers(config.DockerSpec{
+	buildConfiguration, err := prepareLayers(context.Background(), config.DockerSpec{
 		OutputRegistry:         "",
 		OutputRepository:       "",
 		InternalPullRegistry:   "",
@@ -3,6 +3,7 @@ package prepare
 import (
 	"archive/tar"
 	"compress/gzip"
+	"context"
 	"encoding/json"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -14,7 +15,7 @@ import (
 )
 
 // TODO: Kan vi bruke den fra utils ?
-func extractTarball(pathToTarball string) (string, error) {
+func extractTarball(ctx context.Context, pathToTarball string) (string, error) {
 	tmpdir, err := ioutil.TempDir("", "nodejs-architect")
 	tarball, err := os.Open(pathToTarball)
 	if err != nil {
@@ -31,6 +32,9 @@ func extractTarball(pathToTarball string) (string, error) {
 	tarReader := tar.NewReader(gzipStream)
 
 	for true {
+		if err := ctx.Err(); err != nil {
+			return "", errors.Wrap(err, "Extraction of tarball cancelled")
+		}
 		header, err := tarReader.Next()
 
 		if err == io.EOF {
@@ -1,6 +1,9 @@
 package prepare
 
-import "github.com/skatteetaten/architect/v2/pkg/config/runtime"
+import (
+	"github.com/skatteetaten/architect/v2/pkg/config/runtime"
+	"github.com/skatteetaten/architect/v2/pkg/util"
+)
 
 // We copy this over the script in wrench if we don't have a nodejs app
 const blockingRunNodeJS string = `#!/bin/sh
@@ -47,8 +50,12 @@ type openshiftJSON struct {
 }
 
 type dockerMetadata struct {
-	Maintainer string            `json:"maintainer"`
-	Labels     map[string]string `json:"labels"`
+	Maintainer         string               `json:"maintainer"`
+	Labels             map[string]string    `json:"labels"`
+	FileOwnership      []util.PathOwnership `json:"fileOwnership"`
+	Remove             []string             `json:"remove"`
+	ReplaceDirectories []string             `json:"replaceDirectories"`
+	UnsetEnv           []string             `json:"unsetEnv"`
 }
 
 type PreparedImage struct {
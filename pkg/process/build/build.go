@@ -2,6 +2,7 @@ package process
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/skatteetaten/architect/v2/pkg/config"
@@ -10,77 +11,250 @@ import (
 	"github.com/skatteetaten/architect/v2/pkg/nexus"
 	"github.com/skatteetaten/architect/v2/pkg/process/tagger"
 	"github.com/skatteetaten/architect/v2/pkg/sporingslogger"
+	"github.com/skatteetaten/architect/v2/pkg/telemetry"
+	"github.com/skatteetaten/architect/v2/pkg/util"
+	"github.com/skatteetaten/architect/v2/pkg/webhook"
 	"strings"
+	"time"
 )
 
 // Builder interface
 type Builder interface {
-	Build(buildConfig docker.BuildConfig, baseimageLayers *LayerProvider) (*LayerProvider, error)
+	Build(ctx context.Context, buildConfig docker.BuildConfig, baseimageLayers *LayerProvider) (*LayerProvider, error)
 	Pull(ctx context.Context, buildConfig docker.BuildConfig) (*LayerProvider, error)
 	Push(ctx context.Context, buildResult *LayerProvider, tag []string) error
 }
 
-// Build a container image
+// Build a container image, or one image per base image of a matrix build. The deliverable is downloaded once, and
+// prepared, built and pushed on one base image at a time
 func Build(ctx context.Context, pullRegistry docker.Registry, pushRegistry docker.Registry, cfg *config.Config,
-	downloader nexus.Downloader, prepper Prepper, layerBuilder Builder, sporingsLoggerClient sporingslogger.Sporingslogger) error {
+	downloader nexus.Downloader, prepper Prepper, layerBuilder Builder, sporingsLoggerClient sporingslogger.Sporingslogger,
+	notifier webhook.Notifier, targets []Target) error {
+	application := cfg.ApplicationSpec
+	downloadCtx, cancel := stageContext(ctx, cfg, ErrorCategoryDownload)
+	downloadCtx, span := telemetry.StartSpan(downloadCtx, "download")
+	deliverable, err := downloader.DownloadArtifact(downloadCtx, &application.MavenGav)
+	span.Finish(err)
+	cancel()
+	if err != nil {
+		return stageFailed(ErrorCategoryDownload, errors.Wrapf(err, "Could not download deliverable %-v", cfg.ApplicationSpec))
+	}
+
+	if len(application.BaseImageMatrix) > 0 {
+		prepper = prepareOnce(prepper)
+	}
+	for _, baseImageSpec := range application.BaseImages() {
+		baseCfg := cfg
+		if len(application.BaseImageMatrix) > 0 {
+			logrus.Infof("Building on base image %s:%s", baseImageSpec.BaseImage, baseImageSpec.BaseVersion)
+			baseCfg = cfg.ForBaseImage(baseImageSpec)
+		}
+		err := buildOnBaseImage(ctx, pullRegistry, pushRegistry, baseCfg, deliverable, prepper, layerBuilder,
+			sporingsLoggerClient, notifier, targets)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prepareOnce prepare the deliverable on the first call. Later calls reuse the prepared layers, and only replace the
+// values that depend on the version and the base image
+func prepareOnce(prepper Prepper) Prepper {
+	var prepared *docker.BuildConfig
+	return func(ctx context.Context, cfg *config.Config, auroraVersion *runtime.AuroraVersion,
+		deliverable nexus.Deliverable, baseImage runtime.BaseImage) (*docker.BuildConfig, error) {
+		if prepared == nil {
+			buildConfig, err := prepper(ctx, cfg, auroraVersion, deliverable, baseImage)
+			if err != nil {
+				return nil, err
+			}
+			prepared = buildConfig
+			return buildConfig, nil
+		}
+		return preparedForBaseImage(*prepared, auroraVersion, baseImage), nil
+	}
+}
+
+// preparedForBaseImage copy the prepared build config with the version and the build time of this image. The labels
+// of the base image are set when the image is built
+func preparedForBaseImage(buildConfig docker.BuildConfig, auroraVersion *runtime.AuroraVersion,
+	baseImage runtime.BaseImage) *docker.BuildConfig {
+	env := make(map[string]string, len(buildConfig.Env))
+	for k, v := range buildConfig.Env {
+		env[k] = v
+	}
+	env[docker.EnvAppVersion] = string(auroraVersion.GetAppVersion())
+	env[docker.EnvAuroraVersion] = auroraVersion.GetCompleteVersion()
+	env[docker.ImageBuildTime] = docker.GetUtcTimestamp()
+	if auroraVersion.Snapshot {
+		env[docker.EnvSnapshotVersion] = auroraVersion.GetGivenVersion()
+	}
+	labels := make(map[string]string, len(buildConfig.Labels))
+	for k, v := range buildConfig.Labels {
+		labels[k] = v
+	}
+
+	buildConfig.AuroraVersion = auroraVersion
+	buildConfig.Image = baseImage.DockerImage
+	buildConfig.Env = env
+	buildConfig.Labels = labels
+	return &buildConfig
+}
+
+// buildOnBaseImage prepare, build and push the image on the base image of the configuration
+func buildOnBaseImage(ctx context.Context, pullRegistry docker.Registry, pushRegistry docker.Registry,
+	cfg *config.Config, deliverable nexus.Deliverable, prepper Prepper, layerBuilder Builder,
+	sporingsLoggerClient sporingslogger.Sporingslogger, notifier webhook.Notifier, targets []Target) error {
 	application := cfg.ApplicationSpec
 	snapshot := application.MavenGav.IsSnapshot()
 	buildImage := &runtime.ArchitectImage{
 		Tag: cfg.BuilderSpec.Version,
 	}
-	deliverable, err := downloader.DownloadArtifact(&application.MavenGav)
-	if err != nil {
-		return errors.Wrapf(err, "Could not download deliverable %-v", cfg.ApplicationSpec)
-	}
 
-	baseImage, err := getBaseImage(ctx, pullRegistry, err, cfg)
+	baseImageCtx, cancel := stageContext(ctx, cfg, ErrorCategoryBaseImage)
+	baseImageCtx, span := telemetry.StartSpan(baseImageCtx, "base_image")
+	baseImage, err := getBaseImage(baseImageCtx, pullRegistry, nil, cfg)
+	span.Finish(err)
+	cancel()
 	if err != nil {
-		return errors.Wrap(err, "Error getBaseImage")
+		return stageFailed(ErrorCategoryBaseImage, errors.Wrap(err, "Error getBaseImage"))
 	}
 
 	appVersion := nexus.GetSnapshotTimestampVersion(application.MavenGav, deliverable)
 	auroraVersion := runtime.NewAuroraVersionFromBuilderAndBase(appVersion, snapshot,
 		application.MavenGav.Version, buildImage, baseImage.DockerImage, deliverable.SHA1)
+	if tagMetadata := application.BaseImageSpec.TagMetadata; tagMetadata != "" {
+		auroraVersion, err = auroraVersion.WithMetadata(tagMetadata)
+		if err != nil {
+			return stageFailed(ErrorCategoryTags, err)
+		}
+	}
 
 	logrus.Infof("appversion %s  auroraVersion:%s ", appVersion, auroraVersion.GetCompleteVersion())
 	logrus.Infof(" MavenGav.Version:%s", application.MavenGav.Version)
 
-	dockerBuildConfig, err := prepper(cfg, auroraVersion, deliverable, baseImage)
+	tagVariables := tagger.NewTemplateVariables(cfg, time.Now())
+	// Before the identical image is retagged, so neither path moves protected tags
+	tagsConfig := docker.BuildConfig{AuroraVersion: auroraVersion, DockerRepository: cfg.DockerSpec.OutputRepository}
+	err = checkAllTagsForOverwrite(ctx, tagsConfig, pushRegistry, cfg, tagVariables)
 	if err != nil {
-		return errors.Wrap(err, "Error preparing image")
+		return stageFailed(ErrorCategoryTags, err)
+	}
+	if !cfg.NoPush {
+		if err := CheckTargetsForOverwrite(ctx, cfg, targets, auroraVersion, tagVariables); err != nil {
+			return stageFailed(ErrorCategoryTags, err)
+		}
+	}
+
+	fingerprint := buildFingerprint(deliverable.SHA1, baseImage, cfg)
+	if cfg.SkipIdenticalBuilds && !cfg.NoPush && fingerprint != "" {
+		retagged, shortTags, tagDecisions, manifestData, err := retagIdenticalImage(ctx, pushRegistry, cfg,
+			auroraVersion, fingerprint, tagVariables)
+		if err != nil {
+			return stageFailed(ErrorCategoryRetag, errors.Wrap(err, "Unable to retag identical image"))
+		}
+		if retagged != nil {
+			notifier.Notify(ctx, webhook.Event{
+				Type:         webhook.Retagged,
+				Version:      auroraVersion.GetCompleteVersion(),
+				Tags:         retagged,
+				Digest:       util.CalculateDigest(manifestData),
+				TagDecisions: tagDecisions,
+			})
+			targetsErr := PushToTargets(ctx, cfg, pushRegistry, targets, manifestData, auroraVersion, tagVariables,
+				notifier)
+			// The retagged image has no build folder
+			logToSporingslogger(ctx, sporingsLoggerClient, cfg, &docker.BuildConfig{
+				DockerRepository: cfg.DockerSpec.OutputRepository,
+			}, auroraVersion.Snapshot, pushRegistry, shortTags, baseImage)
+			if targetsErr != nil {
+				return stageFailed(ErrorCategoryPush, targetsErr)
+			}
+			return nil
+		}
 	}
 
-	err = checkAllTagsForOverwrite(ctx, *dockerBuildConfig, pushRegistry, cfg)
+	prepareCtx, cancel := stageContext(ctx, cfg, ErrorCategoryPrepare)
+	prepareCtx, span = telemetry.StartSpan(prepareCtx, "prepare")
+	dockerBuildConfig, err := prepper(prepareCtx, cfg, auroraVersion, deliverable, baseImage)
+	span.Finish(err)
+	cancel()
 	if err != nil {
-		return err
+		return stageFailed(ErrorCategoryPrepare, errors.Wrap(err, "Error preparing image"))
 	}
+	notifier.Notify(ctx, webhook.Event{
+		Type:    webhook.Prepared,
+		Version: auroraVersion.GetCompleteVersion(),
+	})
 
-	tags, shortTags, err := extractTags(*dockerBuildConfig, pushRegistry, cfg)
+	tags, shortTags, tagDecisions, err := extractTags(ctx, *dockerBuildConfig, pushRegistry, cfg, tagVariables)
 	if err != nil {
-		return errors.Wrapf(err, "Unable to extract tags")
+		return stageFailed(ErrorCategoryTags, errors.Wrapf(err, "Unable to extract tags"))
 	}
 
-	buildResult, err := buildDockerImage(ctx, *dockerBuildConfig, cfg, layerBuilder)
+	buildCtx, cancel := stageContext(ctx, cfg, ErrorCategoryBuild)
+	buildResult, err := buildDockerImage(buildCtx, *dockerBuildConfig, cfg, baseImage, fingerprint, layerBuilder)
+	cancel()
 	if err != nil {
-		return errors.Wrap(err, "There was an error with the build operation.")
+		return stageFailed(ErrorCategoryBuild, errors.Wrap(err, "There was an error with the build operation."))
 	}
 
-	err = pushImage(ctx, cfg, buildResult, layerBuilder, tags)
+	pushCtx, cancel := stageContext(ctx, cfg, ErrorCategoryPush)
+	err = pushImage(pushCtx, cfg, buildResult, layerBuilder, tags)
+	cancel()
 	if err != nil {
-		return errors.Wrapf(err, "Image push failed")
+		return stageFailed(ErrorCategoryPush, errors.Wrapf(err, "Image push failed"))
+	}
+	if !cfg.NoPush {
+		notifier.Notify(ctx, webhook.Event{
+			Type:         webhook.Pushed,
+			Version:      auroraVersion.GetCompleteVersion(),
+			Tags:         tags,
+			Digest:       manifestDigest(buildResult),
+			TagDecisions: tagDecisions,
+		})
+	}
+	// A failed target does not stop the other targets or the sporingslogger. The build fails at the end
+	var targetsErr error
+	if !cfg.NoPush && len(targets) > 0 {
+		targetsErr = pushToTargets(ctx, cfg, pushRegistry, targets, buildResult.Manifest, auroraVersion, tagVariables,
+			notifier)
 	}
 
-	err = sendImageInfoToSporingsLogger(sporingsLoggerClient, ctx, cfg,
-		dockerBuildConfig, application.MavenGav.Version, auroraVersion.Snapshot,
+	logToSporingslogger(ctx, sporingsLoggerClient, cfg, dockerBuildConfig, auroraVersion.Snapshot, pushRegistry,
+		shortTags, baseImage)
+
+	if targetsErr != nil {
+		return stageFailed(ErrorCategoryPush, targetsErr)
+	}
+	return nil
+}
+
+// logToSporingslogger send the image info to the sporingslogger. A failure is logged, and does not fail the build
+func logToSporingslogger(ctx context.Context, sporingsLoggerClient sporingslogger.Sporingslogger, cfg *config.Config,
+	dockerBuildConfig *docker.BuildConfig, snapshot bool, pushRegistry docker.Registry, shortTags []string,
+	baseImage runtime.BaseImage) {
+	sporingsloggerCtx, cancel := stageContext(ctx, cfg, "sporingslogger")
+	defer cancel()
+	sporingsloggerCtx, span := telemetry.StartSpan(sporingsloggerCtx, "sporingslogger")
+	err := sendImageInfoToSporingsLogger(sporingsLoggerClient, sporingsloggerCtx, cfg,
+		dockerBuildConfig, cfg.ApplicationSpec.MavenGav.Version, snapshot,
 		pushRegistry, shortTags,
 		baseImage)
+	span.Finish(err)
 	if err != nil {
 		logrus.Warnf("Unable to send sporingslogger to Sporinglogger  %s:%s  error: %v",
 			dockerBuildConfig.DockerRepository, shortTags[0], err)
-		return nil
 	}
+}
 
-	return nil
+// stageContext apply the timeout of the stage, if one is configured
+func stageContext(ctx context.Context, cfg *config.Config, stage string) (context.Context, context.CancelFunc) {
+	if timeout, ok := cfg.StageTimeouts[stage]; ok {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
 }
 
 func getBaseImage(ctx context.Context, pullRegistry docker.Registry, err error, cfg *config.Config) (runtime.BaseImage, error) {
@@ -106,10 +280,12 @@ func getBaseImage(ctx context.Context, pullRegistry docker.Registry, err error,
 	return baseImage, nil
 }
 
-func buildDockerImage(ctx context.Context, buildConfig docker.BuildConfig, cfg *config.Config, layerBuilder Builder) (*LayerProvider, error) {
+func buildDockerImage(ctx context.Context, buildConfig docker.BuildConfig, cfg *config.Config, baseImage runtime.BaseImage,
+	fingerprint string, layerBuilder Builder) (*LayerProvider, error) {
 
-	if cfg.NexusIQReportURL != "" {
-		buildConfig.Labels["no.skatteetaten.aurora.nexus-iq-report-url"] = cfg.NexusIQReportURL
+	buildConfig.Labels = imageLabels(buildConfig, cfg, baseImage)
+	if fingerprint != "" {
+		buildConfig.Labels[auroraLabelBuildFingerprint] = fingerprint
 	}
 
 	baseImageLayers, err := layerBuilder.Pull(ctx, buildConfig)
@@ -117,8 +293,63 @@ func buildDockerImage(ctx context.Context, buildConfig docker.BuildConfig, cfg *
 		return nil, errors.Wrap(err, "There was an error with the pull operation.")
 	}
 
-	return layerBuilder.Build(buildConfig, baseImageLayers)
+	return layerBuilder.Build(ctx, buildConfig, baseImageLayers)
+}
+
+// retagIdenticalImage push the tags of this build to an existing image with the same build fingerprint. The manifest
+// bytes are pushed unchanged, so the tags get the digest of the existing image.
+// Returns the pushed tags, the short tags, the tag decisions and the manifest bytes of the image, or nil when there
+// is no such image and a new image must be built
+func retagIdenticalImage(ctx context.Context, pushRegistry docker.Registry, cfg *config.Config,
+	auroraVersion *runtime.AuroraVersion, fingerprint string,
+	tagVariables tagger.TemplateVariables) ([]string, []string, []string, []byte, error) {
+
+	buildConfig := docker.BuildConfig{
+		AuroraVersion:    auroraVersion,
+		DockerRepository: cfg.DockerSpec.OutputRepository,
+	}
+	tags, shortTags, tagDecisions, err := extractTags(ctx, buildConfig, pushRegistry, cfg, tagVariables)
+	if err != nil {
+		return nil, nil, nil, nil, errors.Wrapf(err, "Unable to extract tags")
+	}
+
+	manifestData, _, existingTag, err := findIdenticalImage(ctx, pushRegistry, cfg.DockerSpec.OutputRepository,
+		shortTags, fingerprint)
+	if err != nil {
+		logrus.Warnf("Unable to look for an identical image. Building a new image: %v", err)
+		return nil, nil, nil, nil, nil
+	}
+	if manifestData == nil {
+		return nil, nil, nil, nil, nil
+	}
+
+	logrus.Infof("Tag %s is built from the same deliverable and base image. Retagging instead of building", existingTag)
+	for _, tag := range tags {
+		shortTag, err := util.FindOutputTagOrHash(tag)
+		if err != nil {
+			return nil, nil, nil, nil, errors.Wrap(err, "Tag failed")
+		}
+		logrus.Infof("Push tag: %s", tag)
+		err = pushRegistry.PushManifest(ctx, manifestData, cfg.DockerSpec.OutputRepository, shortTag)
+		if err != nil {
+			return nil, nil, nil, nil, errors.Wrapf(err, "Failed to push tag %s", tag)
+		}
+	}
+	return tags, shortTags, tagDecisions, manifestData, nil
+}
+
+// manifestDigest the digest of the pushed manifest, empty if it can not be calculated
+func manifestDigest(buildResult *LayerProvider) string {
+	if buildResult == nil || buildResult.Manifest == nil {
+		return ""
+	}
+	data, err := json.Marshal(buildResult.Manifest)
+	if err != nil {
+		return ""
+	}
+	return util.CalculateDigest(data)
 }
+
 func pushImage(ctx context.Context, cfg *config.Config, buildResult *LayerProvider, layerBuilder Builder, tags []string) error {
 	if cfg.NoPush {
 		logrus.Info("NoPush configured, not pushing image")
@@ -141,9 +372,14 @@ func sendImageInfoToSporingsLogger(sporingsLoggerClient sporingslogger.Sporingsl
 		return nil
 	}
 
-	dependencies, err := sporingsLoggerClient.ScanImage(dockerBuildConfig.BuildFolder)
-	if err != nil {
-		return errors.Wrapf(err, "ScanImage failed")
+	// The dependencies of a retagged identical image were sent when the image was built
+	var dependencies []sporingslogger.Dependency
+	if dockerBuildConfig.BuildFolder != "" {
+		var err error
+		dependencies, err = sporingsLoggerClient.ScanImage(ctx, dockerBuildConfig.BuildFolder)
+		if err != nil {
+			return errors.Wrapf(err, "ScanImage failed")
+		}
 	}
 
 	imageInfo, err := dockerRegistry.GetImageInfo(ctx, dockerBuildConfig.DockerRepository, shortTags[0])
@@ -152,7 +388,7 @@ func sendImageInfoToSporingsLogger(sporingsLoggerClient sporingslogger.Sporingsl
 	}
 	logrus.Infof("Sending image info to sporingslogger %s ", imageInfo.Digest)
 
-	return sporingsLoggerClient.SendImageMetadata(sporingslogger.DeployableImage{
+	return sporingsLoggerClient.SendImageMetadata(ctx, sporingslogger.DeployableImage{
 		Type:             "deployableImage",
 		Name:             dockerBuildConfig.DockerRepository,
 		AppVersion:       version,
@@ -167,7 +403,8 @@ func sendImageInfoToSporingsLogger(sporingsLoggerClient sporingslogger.Sporingsl
 	})
 }
 
-func checkAllTagsForOverwrite(ctx context.Context, buildConfig docker.BuildConfig, pushRegistry docker.Registry, cfg *config.Config) error {
+func checkAllTagsForOverwrite(ctx context.Context, buildConfig docker.BuildConfig, pushRegistry docker.Registry, cfg *config.Config,
+	tagVariables tagger.TemplateVariables) error {
 	tagsAPIResponse, err := pushRegistry.GetTags(ctx, cfg.DockerSpec.OutputRepository)
 	if err != nil {
 		return err
@@ -178,8 +415,39 @@ func checkAllTagsForOverwrite(ctx context.Context, buildConfig docker.BuildConfi
 	semanticVersion := buildConfig.AuroraVersion.GetGivenVersion()
 	completeVersion := buildConfig.AuroraVersion.GetCompleteVersion()
 	err = CheckTagsForOverwrite(isSnapshot, tagsAPIResponse.Tags, tagWith, semanticVersion, completeVersion)
+	if err != nil {
+		return err
+	}
 
-	return err
+	if !isSnapshot {
+		scheme, err := tagger.NewVersionScheme(cfg.DockerSpec.VersioningScheme)
+		if err != nil {
+			return err
+		}
+		err = scheme.CheckOverwrite(string(buildConfig.AuroraVersion.GetAppVersion()), tagsAPIResponse.Tags)
+		if err != nil {
+			return err
+		}
+	}
+
+	return CheckTemplateTagsForOverwrite(isSnapshot, tagsAPIResponse.Tags, cfg.DockerSpec.TagTemplates,
+		buildConfig.AuroraVersion, tagVariables)
+}
+
+// CheckTemplateTagsForOverwrite check that the rendered template tags do not overwrite existing tags. Template tags
+// are protected like TagWith
+func CheckTemplateTagsForOverwrite(isSnapshot bool, tags []string, templates []string,
+	auroraVersion *runtime.AuroraVersion, tagVariables tagger.TemplateVariables) error {
+	templateTags, err := tagger.RenderTagTemplates(templates, auroraVersion, tagVariables)
+	if err != nil {
+		return err
+	}
+	for _, templateTag := range templateTags {
+		if err := CheckTagsForOverwrite(isSnapshot, tags, templateTag, "", ""); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // CheckTagsForOverwrite /
@@ -209,14 +477,37 @@ func CheckTagsForOverwrite(isSnapshot bool, tags []string, tagWith string, seman
 	return nil
 }
 
-func extractTags(buildConfig docker.BuildConfig, pushRegistry docker.Registry, cfg *config.Config) ([]string, []string, error) {
+// extractTags the tags to push, and the decisions for the candidate tags. The decisions are made once, and the tags
+// are derived from them
+func extractTags(ctx context.Context, buildConfig docker.BuildConfig, pushRegistry docker.Registry, cfg *config.Config,
+	tagVariables tagger.TemplateVariables) ([]string, []string, []string, error) {
 
 	var tagResolver tagger.TagResolver
+	var report []string
 	if cfg.DockerSpec.TagWith == "" {
-		tagResolver = &tagger.NormalTagResolver{
+		scheme, err := tagger.NewVersionScheme(cfg.DockerSpec.VersioningScheme)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		normalTagResolver := &tagger.NormalTagResolver{
 			RegistryClient: pushRegistry,
 			Registry:       cfg.DockerSpec.OutputRegistry,
 			Repository:     buildConfig.DockerRepository,
+			Scheme:         scheme,
+		}
+		decisions, err := normalTagResolver.Explain(ctx, buildConfig.AuroraVersion, cfg.DockerSpec.PushExtraTags)
+		if err != nil {
+			return nil, nil, nil, errors.Wrapf(err, "Image tag failed")
+		}
+		logrus.Info("Tag decisions:")
+		for _, decision := range decisions {
+			logrus.Infof("  %s", decision)
+			report = append(report, decision.String())
+		}
+		tagResolver = &tagger.DecidedTagResolver{
+			Registry:   cfg.DockerSpec.OutputRegistry,
+			Repository: buildConfig.DockerRepository,
+			Decisions:  decisions,
 		}
 	} else {
 		tagResolver = &tagger.SingleTagResolver{
@@ -225,11 +516,23 @@ func extractTags(buildConfig docker.BuildConfig, pushRegistry docker.Registry, c
 			Repository: buildConfig.DockerRepository,
 		}
 	}
+	if len(cfg.DockerSpec.TagTemplates) > 0 {
+		tagResolver = &tagger.TemplateTagResolver{
+			Registry:   cfg.DockerSpec.OutputRegistry,
+			Repository: buildConfig.DockerRepository,
+			Templates:  cfg.DockerSpec.TagTemplates,
+			Variables:  tagVariables,
+			Resolver:   tagResolver,
+		}
+	}
 
-	tags, err := tagResolver.ResolveTags(buildConfig.AuroraVersion, cfg.DockerSpec.PushExtraTags)
-	shortTags, err := tagResolver.ResolveShortTag(buildConfig.AuroraVersion, cfg.DockerSpec.PushExtraTags)
+	tags, err := tagResolver.ResolveTags(ctx, buildConfig.AuroraVersion, cfg.DockerSpec.PushExtraTags)
+	if err != nil {
+		return nil, nil, nil, errors.Wrapf(err, "Image tag failed")
+	}
+	shortTags, err := tagResolver.ResolveShortTag(ctx, buildConfig.AuroraVersion, cfg.DockerSpec.PushExtraTags)
 	if err != nil {
-		return nil, nil, errors.Wrapf(err, "Image tag failed")
+		return nil, nil, nil, errors.Wrapf(err, "Image tag failed")
 	}
-	return tags, shortTags, nil
+	return tags, shortTags, report, nil
 }
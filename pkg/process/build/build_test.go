@@ -14,6 +14,8 @@ import (
 	build_mock "github.com/skatteetaten/architect/v2/pkg/process/build/mocks"
 	"github.com/skatteetaten/architect/v2/pkg/sporingslogger"
 	sporingslogger_mock "github.com/skatteetaten/architect/v2/pkg/sporingslogger/mocks"
+	"github.com/skatteetaten/architect/v2/pkg/webhook"
+	"github.com/stretchr/testify/assert"
 	"io/ioutil"
 	"testing"
 )
@@ -90,52 +92,52 @@ func TestBuild(t *testing.T) {
 		ctx := context.Background()
 
 		appSpec := config.ApplicationSpec{
-			config.MavenGav{
-				"ArtifactId",
-				"GroupId",
-				"Version111",
-				"Classifier",
-				"typeVersion",
+			MavenGav: config.MavenGav{
+				ArtifactID: "ArtifactId",
+				GroupID:    "GroupId",
+				Version:    "Version111",
+				Classifier: "Classifier",
+				Type:       "typeVersion",
 			},
-			config.DockerBaseImageSpec{
-				"BaseImageName",
-				"BaseVersion",
+			BaseImageSpec: config.DockerBaseImageSpec{
+				BaseImage:   "BaseImageName",
+				BaseVersion: "BaseVersion",
 			},
 		}
 
 		dockerSpec := config.DockerSpec{
-			"OutputRegistry",
-			"OutputRepository",
-			"InternalPullRegistry",
-			config.PushExtraTags{
-				false,
-				true,
-				false,
-				false,
+			OutputRegistry:       "OutputRegistry",
+			OutputRepository:     "OutputRepository",
+			InternalPullRegistry: "InternalPullRegistry",
+			PushExtraTags: config.PushExtraTags{
+				Latest: false,
+				Major:  true,
+				Minor:  false,
+				Patch:  false,
 			},
 			//This is the external docker registry where we check versions.
-			"ExternalDockerRegistry",
+			ExternalDockerRegistry: "ExternalDockerRegistry",
 			//The tag to push to. This is only used for ImageStreamTags (as for now) and RETAG functionality
-			"TagWith",
-			"RetagWith",
+			TagWith:   "TagWith",
+			RetagWith: "RetagWith",
 		}
 
 		testConfig := config.Config{
-			"ApplicationType",
-			appSpec,
-			dockerSpec,
-			config.BuilderSpec{
-				"BuildImageVersion123",
+			ApplicationType: "ApplicationType",
+			ApplicationSpec: appSpec,
+			DockerSpec:      dockerSpec,
+			BuilderSpec: config.BuilderSpec{
+				Version: "BuildImageVersion123",
 			},
-			true,
-			true,
-			true,
-			10,
-			false,
-			"Sporingstjeneste",
-			"OwnerReferenceUUID",
-			"BinaryBuildType",
-			"NexusIQReportURL",
+			BinaryBuild:        true,
+			LocalBuild:         true,
+			TLSVerify:          true,
+			BuildTimeout:       10,
+			NoPush:             false,
+			Sporingstjeneste:   "Sporingstjeneste",
+			OwnerReferenceUUID: "OwnerReferenceUUID",
+			BinaryBuildType:    "BinaryBuildType",
+			NexusIQReportURL:   "NexusIQReportURL",
 		}
 
 		mockCtrl := gomock.NewController(t)
@@ -166,36 +168,35 @@ func TestBuild(t *testing.T) {
 			Tags: []string{"tag1", "tag2"},
 		}, nil)
 
-		nexusDownloader.EXPECT().DownloadArtifact(gomock.Any()).Return(nexus.Deliverable{
+		nexusDownloader.EXPECT().DownloadArtifact(gomock.Any(), gomock.Any()).Return(nexus.Deliverable{
 			Path: "PATH",
 			SHA1: "SHA1",
 		}, nil)
 
 		mockPrepper := func(
+			ctx context.Context,
 			cfg *config.Config,
 			auroraVersion *runtime.AuroraVersion,
 			deliverable nexus.Deliverable,
 			baseImage runtime.BaseImage) (*docker.BuildConfig, error) {
 			return &docker.BuildConfig{
-				runtime.NewAuroraVersion("1.2.3", false, "giverVersioin", "completeVersion"), //AuroraVersion    *runtime.AuroraVersion
-				"ServiceNameTest",
-				"BuildFolder",
-				runtime.DockerImage{
-					"TAG-test",
-					"repo-test",
-					"registry-test",
+				AuroraVersion:    runtime.NewAuroraVersion("1.2.3", false, "giverVersioin", "completeVersion"),
+				DockerRepository: "ServiceNameTest",
+				BuildFolder:      "BuildFolder",
+				Image: runtime.DockerImage{
+					Tag:        "TAG-test",
+					Repository: "repo-test",
+					Registry:   "registry-test",
 				},
-				"OutputRegistry",
-				map[string]string{},
-				map[string]string{},
-				nil,
-				nil,
+				OutputRegistry: "OutputRegistry",
+				Env:            map[string]string{},
+				Labels:         map[string]string{},
 			}, nil
 		}
 
 		layerBuilder.EXPECT().Pull(gomock.Any(), gomock.Any()).Return(nil, nil)
 		layerBuilder.EXPECT().Push(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
-		layerBuilder.EXPECT().Build(gomock.Any(), gomock.Any()).Return(nil, nil)
+		layerBuilder.EXPECT().Build(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil)
 
 		jsonFile, err := ioutil.ReadFile("testdata/dependencies.json")
 		if err != nil {
@@ -207,9 +208,9 @@ func TestBuild(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Unmarshal error %v ", err)
 		}
-		mockSporingslogger.EXPECT().ScanImage(gomock.Any()).Return(dependencies, nil)
+		mockSporingslogger.EXPECT().ScanImage(gomock.Any(), gomock.Any()).Return(dependencies, nil)
 
-		mockSporingslogger.EXPECT().SendImageMetadata(gomock.Eq(
+		mockSporingslogger.EXPECT().SendImageMetadata(gomock.Any(), gomock.Eq(
 			sporingslogger.DeployableImage{
 				Type:             "deployableImage",
 				Digest:           "ImageDigest",
@@ -224,7 +225,7 @@ func TestBuild(t *testing.T) {
 				Dependencies:     dependencies,
 			}))
 
-		err = process.Build(ctx, registryClient, registryClient, &testConfig, nexusDownloader, mockPrepper, layerBuilder, mockSporingslogger)
+		err = process.Build(ctx, registryClient, registryClient, &testConfig, nexusDownloader, mockPrepper, layerBuilder, mockSporingslogger, webhook.NewClient(&testConfig), nil)
 
 		if err != nil {
 			t.Fatal("Overwrite should be allowed for tagWith-snapshot")
@@ -232,3 +233,174 @@ func TestBuild(t *testing.T) {
 	})
 
 }
+
+func TestBuildChecksOverwriteBeforeRetaggingIdenticalImage(t *testing.T) {
+	testConfig := config.Config{
+		ApplicationSpec: config.ApplicationSpec{
+			MavenGav:      config.MavenGav{ArtifactID: "minarch", GroupID: "no.skatteetaten.aurora", Version: "1.0.0"},
+			BaseImageSpec: config.DockerBaseImageSpec{BaseImage: "aurora/wingnut11", BaseVersion: "1"},
+		},
+		DockerSpec: config.DockerSpec{
+			OutputRegistry:   "registry.example.com",
+			OutputRepository: "aurora/minarch",
+			TagWith:          "temp-1",
+		},
+		SkipIdenticalBuilds: true,
+	}
+
+	mockCtrl := gomock.NewController(t)
+	registryClient := docker_mock.NewMockRegistry(mockCtrl)
+	nexusDownloader := nexus_mock.NewMockDownloader(mockCtrl)
+	layerBuilder := build_mock.NewMockBuilder(mockCtrl)
+	mockSporingslogger := sporingslogger_mock.NewMockSporingslogger(mockCtrl)
+
+	nexusDownloader.EXPECT().DownloadArtifact(gomock.Any(), gomock.Any()).Return(nexus.Deliverable{
+		Path: "PATH",
+		SHA1: "SHA1",
+	}, nil)
+	registryClient.EXPECT().GetImageInfo(gomock.Any(), "aurora/wingnut11", "1").Return(&runtime.ImageInfo{
+		CompleteBaseImageVersion: "1.0.0",
+		Digest:                   "BaseImageDigest",
+	}, nil)
+	registryClient.EXPECT().GetTags(gomock.Any(), "aurora/minarch").Return(&docker.TagsAPIResponse{
+		Tags: []string{"temp-1"},
+	}, nil)
+	mockPrepper := func(context.Context, *config.Config, *runtime.AuroraVersion, nexus.Deliverable,
+		runtime.BaseImage) (*docker.BuildConfig, error) {
+		t.Fatal("A build that overwrites a protected tag must not be prepared")
+		return nil, nil
+	}
+
+	err := process.Build(context.Background(), registryClient, registryClient, &testConfig, nexusDownloader,
+		mockPrepper, layerBuilder, mockSporingslogger, webhook.NewClient(&testConfig), nil)
+
+	assert.EqualError(t, err, "Given value for TagWith=temp-1 have already been build, overwrite not allowed")
+	assert.Equal(t, process.ErrorCategoryTags, process.ErrorCategory(err))
+}
+
+type recordingNotifier struct {
+	events []webhook.Event
+}
+
+func (r *recordingNotifier) Notify(_ context.Context, event webhook.Event) {
+	r.events = append(r.events, event)
+}
+
+func TestBuildReportsTagDecisions(t *testing.T) {
+	testConfig := config.Config{
+		ApplicationSpec: config.ApplicationSpec{
+			MavenGav:      config.MavenGav{ArtifactID: "minarch", GroupID: "no.skatteetaten.aurora", Version: "1.2.3"},
+			BaseImageSpec: config.DockerBaseImageSpec{BaseImage: "aurora/wingnut11", BaseVersion: "1"},
+		},
+		DockerSpec: config.DockerSpec{
+			OutputRegistry:   "registry.example.com",
+			OutputRepository: "aurora/minarch",
+			PushExtraTags:    config.PushExtraTags{Latest: true},
+		},
+	}
+
+	mockCtrl := gomock.NewController(t)
+	registryClient := docker_mock.NewMockRegistry(mockCtrl)
+	nexusDownloader := nexus_mock.NewMockDownloader(mockCtrl)
+	layerBuilder := build_mock.NewMockBuilder(mockCtrl)
+	mockSporingslogger := sporingslogger_mock.NewMockSporingslogger(mockCtrl)
+
+	nexusDownloader.EXPECT().DownloadArtifact(gomock.Any(), gomock.Any()).Return(nexus.Deliverable{Path: "PATH"}, nil)
+	registryClient.EXPECT().GetImageInfo(gomock.Any(), gomock.Any(), gomock.Any()).Return(&runtime.ImageInfo{
+		CompleteBaseImageVersion: "1.0.0",
+		Digest:                   "Digest",
+	}, nil).AnyTimes()
+	// Once for the overwrite check, and once for the tag decisions
+	registryClient.EXPECT().GetTags(gomock.Any(), "aurora/minarch").Return(&docker.TagsAPIResponse{
+		Tags: []string{"latest", "1.3.0"},
+	}, nil).Times(2)
+	mockPrepper := func(_ context.Context, _ *config.Config, auroraVersion *runtime.AuroraVersion, _ nexus.Deliverable,
+		_ runtime.BaseImage) (*docker.BuildConfig, error) {
+		return &docker.BuildConfig{AuroraVersion: auroraVersion, DockerRepository: "aurora/minarch"}, nil
+	}
+	layerBuilder.EXPECT().Pull(gomock.Any(), gomock.Any()).Return(nil, nil)
+	layerBuilder.EXPECT().Build(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil)
+	layerBuilder.EXPECT().Push(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	mockSporingslogger.EXPECT().ScanImage(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	mockSporingslogger.EXPECT().SendImageMetadata(gomock.Any(), gomock.Any()).AnyTimes()
+	notifier := &recordingNotifier{}
+
+	err := process.Build(context.Background(), registryClient, registryClient, &testConfig, nexusDownloader,
+		mockPrepper, layerBuilder, mockSporingslogger, notifier, nil)
+
+	assert.NoError(t, err)
+	pushed := notifier.events[len(notifier.events)-1]
+	assert.Equal(t, webhook.Pushed, pushed.Type)
+	assert.Equal(t, []string{"registry.example.com/aurora/minarch:1.2.3-b-wingnut11-1.0.0"}, pushed.Tags)
+	assert.Contains(t, pushed.TagDecisions, "excluded latest: 1.3.0 exists in repository and is greater than 1.2.3")
+}
+
+func TestBuildPreparesTheDeliverableOnceForABaseImageMatrix(t *testing.T) {
+	wingnut11 := config.DockerBaseImageSpec{BaseImage: "aurora/wingnut11", BaseVersion: "1", TagMetadata: "wingnut11"}
+	wingnut17 := config.DockerBaseImageSpec{BaseImage: "aurora/wingnut17", BaseVersion: "2", TagMetadata: "wingnut17"}
+	testConfig := config.Config{
+		ApplicationSpec: config.ApplicationSpec{
+			MavenGav:        config.MavenGav{ArtifactID: "minarch", GroupID: "no.skatteetaten.aurora", Version: "1.2.3"},
+			BaseImageSpec:   wingnut11,
+			BaseImageMatrix: []config.DockerBaseImageSpec{wingnut11, wingnut17},
+		},
+		DockerSpec: config.DockerSpec{
+			OutputRegistry:   "registry.example.com",
+			OutputRepository: "aurora/minarch",
+		},
+	}
+
+	mockCtrl := gomock.NewController(t)
+	registryClient := docker_mock.NewMockRegistry(mockCtrl)
+	nexusDownloader := nexus_mock.NewMockDownloader(mockCtrl)
+	layerBuilder := build_mock.NewMockBuilder(mockCtrl)
+	mockSporingslogger := sporingslogger_mock.NewMockSporingslogger(mockCtrl)
+
+	nexusDownloader.EXPECT().DownloadArtifact(gomock.Any(), gomock.Any()).Return(nexus.Deliverable{Path: "PATH"}, nil)
+	registryClient.EXPECT().GetImageInfo(gomock.Any(), gomock.Any(), gomock.Any()).Return(&runtime.ImageInfo{
+		CompleteBaseImageVersion: "1.0.0",
+		Digest:                   "Digest",
+	}, nil).AnyTimes()
+	registryClient.EXPECT().GetTags(gomock.Any(), "aurora/minarch").Return(&docker.TagsAPIResponse{}, nil).AnyTimes()
+	prepared := 0
+	mockPrepper := func(_ context.Context, _ *config.Config, auroraVersion *runtime.AuroraVersion, _ nexus.Deliverable,
+		baseImage runtime.BaseImage) (*docker.BuildConfig, error) {
+		prepared++
+		return &docker.BuildConfig{
+			AuroraVersion:    auroraVersion,
+			BuildFolder:      "BUILD",
+			DockerRepository: "aurora/minarch",
+			Image:            baseImage.DockerImage,
+			Env: map[string]string{
+				docker.EnvAuroraVersion: auroraVersion.GetCompleteVersion(),
+				docker.EnvAppVersion:    string(auroraVersion.GetAppVersion()),
+				"LANG":                  "en_US.UTF-8",
+			},
+		}, nil
+	}
+	var built []docker.BuildConfig
+	layerBuilder.EXPECT().Pull(gomock.Any(), gomock.Any()).Return(nil, nil).Times(2)
+	layerBuilder.EXPECT().Build(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, buildConfig docker.BuildConfig, _ *process.LayerProvider) (*process.LayerProvider, error) {
+			built = append(built, buildConfig)
+			return nil, nil
+		}).Times(2)
+	layerBuilder.EXPECT().Push(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	mockSporingslogger.EXPECT().ScanImage(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	mockSporingslogger.EXPECT().SendImageMetadata(gomock.Any(), gomock.Any()).AnyTimes()
+
+	err := process.Build(context.Background(), registryClient, registryClient, &testConfig, nexusDownloader,
+		mockPrepper, layerBuilder, mockSporingslogger, &recordingNotifier{}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, prepared)
+	assert.Len(t, built, 2)
+	assert.Equal(t, "aurora/wingnut17", built[1].Image.Repository)
+	assert.Equal(t, "BUILD", built[1].BuildFolder)
+	assert.Equal(t, "1.2.3+wingnut11", built[0].Env[docker.EnvAppVersion])
+	assert.Equal(t, "1.2.3+wingnut17", built[1].Env[docker.EnvAppVersion])
+	assert.Equal(t, built[1].AuroraVersion.GetCompleteVersion(), built[1].Env[docker.EnvAuroraVersion])
+	assert.NotEqual(t, built[0].Env[docker.EnvAuroraVersion], built[1].Env[docker.EnvAuroraVersion])
+	assert.NotEmpty(t, built[1].Env[docker.ImageBuildTime])
+	assert.Equal(t, "en_US.UTF-8", built[1].Env["LANG"])
+}
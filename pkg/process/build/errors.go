@@ -0,0 +1,78 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+)
+
+// Error categories of a failed build
+const (
+	ErrorCategoryDownload    = "download"
+	ErrorCategoryBaseImage   = "base_image"
+	ErrorCategoryPrepare     = "prepare"
+	ErrorCategoryTags        = "tags"
+	ErrorCategoryBuild       = "build"
+	ErrorCategoryPush        = "push"
+	ErrorCategoryRetag       = "retag"
+	ErrorCategoryTimeout     = "timeout"
+	ErrorCategoryInterrupted = "interrupted"
+	ErrorCategoryUnknown     = "unknown"
+)
+
+type stageError struct {
+	category string
+	err      error
+}
+
+// stageFailed mark the error with the category of the stage that failed
+func stageFailed(category string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &stageError{category: category, err: err}
+}
+
+func (e *stageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *stageError) Unwrap() error {
+	return e.err
+}
+
+func (e *stageError) Cause() error {
+	return e.err
+}
+
+// Format keep the stack trace of the wrapped error for %+v
+func (e *stageError) Format(s fmt.State, verb rune) {
+	if formatter, ok := e.err.(fmt.Formatter); ok {
+		formatter.Format(s, verb)
+		return
+	}
+	fmt.Fprint(s, e.err.Error())
+}
+
+// ErrorCategory the category of a build error. A timeout or an interrupt wins over the stage that failed
+func ErrorCategory(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorCategoryTimeout
+	}
+	if errors.Is(err, context.Canceled) {
+		return ErrorCategoryInterrupted
+	}
+	if stage := FailedStage(err); stage != "" {
+		return stage
+	}
+	return ErrorCategoryUnknown
+}
+
+// FailedStage the stage a build error comes from, empty if it is not known
+func FailedStage(err error) string {
+	var stage *stageError
+	if errors.As(err, &stage) {
+		return stage.category
+	}
+	return ""
+}
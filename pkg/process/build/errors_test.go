@@ -0,0 +1,26 @@
+package process
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestErrorCategory(t *testing.T) {
+	pushFailed := stageFailed(ErrorCategoryPush, errors.New("unauthorized"))
+	assert.Equal(t, ErrorCategoryPush, ErrorCategory(errors.Wrap(pushFailed, "Build failed")))
+	assert.Equal(t, "unauthorized", pushFailed.Error())
+
+	timedOut := stageFailed(ErrorCategoryDownload, errors.Wrap(context.DeadlineExceeded, "Could not download"))
+	assert.Equal(t, ErrorCategoryTimeout, ErrorCategory(timedOut))
+	assert.Equal(t, ErrorCategoryDownload, FailedStage(timedOut))
+
+	interrupted := stageFailed(ErrorCategoryPush, errors.Wrap(context.Canceled, "Push cancelled"))
+	assert.Equal(t, ErrorCategoryInterrupted, ErrorCategory(interrupted))
+	assert.Equal(t, ErrorCategoryPush, FailedStage(interrupted))
+
+	assert.Equal(t, ErrorCategoryUnknown, ErrorCategory(errors.New("boom")))
+	assert.Empty(t, FailedStage(errors.New("boom")))
+	assert.Nil(t, stageFailed(ErrorCategoryBuild, nil))
+}
@@ -0,0 +1,96 @@
+package process
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/skatteetaten/architect/v2/pkg/config"
+	"github.com/skatteetaten/architect/v2/pkg/config/runtime"
+	"github.com/skatteetaten/architect/v2/pkg/docker"
+	"github.com/skatteetaten/architect/v2/pkg/util"
+)
+
+const auroraLabelBuildFingerprint = "no.skatteetaten.aurora.build-fingerprint"
+
+// The configuration that changes the image content
+type fingerprintInput struct {
+	DeliverableSHA1  string
+	BaseImageDigest  string
+	BuilderVersion   string
+	ApplicationType  config.ApplicationType
+	PushExtraTags    string
+	OwnershipPolicy  util.OwnershipPolicy
+	SourceSpec       config.SourceSpec
+	NexusIQReportURL string
+	VersioningScheme string
+	SizePolicy       config.SizePolicy
+	TagMetadata      string
+	Labels           map[string]string `json:",omitempty"`
+}
+
+// buildFingerprint identify the image built from a deliverable on a base image.
+// Empty if the deliverable checksum or the base image digest is unknown
+func buildFingerprint(deliverableSHA1 string, baseImage runtime.BaseImage, cfg *config.Config) string {
+	if deliverableSHA1 == "" || baseImage.ImageInfo == nil || baseImage.ImageInfo.Digest == "" {
+		return ""
+	}
+	data, err := json.Marshal(fingerprintInput{
+		DeliverableSHA1:  deliverableSHA1,
+		BaseImageDigest:  baseImage.ImageInfo.Digest,
+		BuilderVersion:   cfg.BuilderSpec.Version,
+		ApplicationType:  cfg.ApplicationType,
+		PushExtraTags:    cfg.DockerSpec.PushExtraTags.ToStringValue(),
+		OwnershipPolicy:  cfg.OwnershipPolicy,
+		SourceSpec:       cfg.SourceSpec,
+		NexusIQReportURL: cfg.NexusIQReportURL,
+		VersioningScheme: cfg.DockerSpec.VersioningScheme,
+		SizePolicy:       cfg.SizePolicy,
+		TagMetadata:      cfg.ApplicationSpec.BaseImageSpec.TagMetadata,
+		Labels:           cfg.Labels,
+	})
+	if err != nil {
+		logrus.Warnf("Unable to create build fingerprint: %v", err)
+		return ""
+	}
+	return util.CalculateDigest(data)
+}
+
+// findIdenticalImage return the manifest bytes, the manifest and the tag of the first existing tag with the same
+// build fingerprint, or nil
+func findIdenticalImage(ctx context.Context, registry docker.Registry, repository string, candidateTags []string,
+	fingerprint string) ([]byte, *docker.ManifestV2, string, error) {
+
+	tagsInRepo, err := registry.GetTags(ctx, repository)
+	if err != nil {
+		return nil, nil, "", errors.Wrapf(err, "Unable to list tags in %s", repository)
+	}
+	existing := make(map[string]bool)
+	for _, tag := range tagsInRepo.Tags {
+		existing[tag] = true
+	}
+
+	for _, candidate := range candidateTags {
+		tag := docker.ConvertTagToRepositoryTag(candidate)
+		if !existing[tag] {
+			continue
+		}
+		manifestData, err := registry.GetRawManifest(ctx, repository, tag)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		manifest := &docker.ManifestV2{}
+		if err := json.Unmarshal(manifestData, manifest); err != nil {
+			return nil, nil, "", errors.Wrap(err, "Unmarshal of manifest failed")
+		}
+		containerConfig, err := registry.GetContainerConfig(ctx, repository, manifest.Config.Digest)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		if containerConfig.Config.Labels[auroraLabelBuildFingerprint] == fingerprint {
+			return manifestData, manifest, tag, nil
+		}
+		logrus.Debugf("Tag %s has a different build fingerprint", tag)
+	}
+	return nil, nil, "", nil
+}
@@ -0,0 +1,126 @@
+package process
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	"github.com/skatteetaten/architect/v2/pkg/config"
+	"github.com/skatteetaten/architect/v2/pkg/config/runtime"
+	"github.com/skatteetaten/architect/v2/pkg/docker"
+	docker_mock "github.com/skatteetaten/architect/v2/pkg/docker/mocks"
+	"github.com/skatteetaten/architect/v2/pkg/nexus"
+	nexus_mock "github.com/skatteetaten/architect/v2/pkg/nexus/mocks"
+	"github.com/skatteetaten/architect/v2/pkg/sporingslogger"
+	sporingslogger_mock "github.com/skatteetaten/architect/v2/pkg/sporingslogger/mocks"
+	"github.com/skatteetaten/architect/v2/pkg/util"
+	"github.com/skatteetaten/architect/v2/pkg/webhook"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBuildFingerprint(t *testing.T) {
+	cfg := &config.Config{BuilderSpec: config.BuilderSpec{Version: "1.2.0"}}
+	baseImage := runtime.BaseImage{ImageInfo: &runtime.ImageInfo{Digest: "sha256:base"}}
+
+	fingerprint := buildFingerprint("sha1", baseImage, cfg)
+	assert.NotEmpty(t, fingerprint)
+	assert.Equal(t, fingerprint, buildFingerprint("sha1", baseImage, cfg))
+
+	newBase := runtime.BaseImage{ImageInfo: &runtime.ImageInfo{Digest: "sha256:newbase"}}
+	assert.NotEqual(t, fingerprint, buildFingerprint("sha1", newBase, cfg))
+	newBuilder := &config.Config{BuilderSpec: config.BuilderSpec{Version: "1.3.0"}}
+	assert.NotEqual(t, fingerprint, buildFingerprint("sha1", baseImage, newBuilder))
+	newScheme := &config.Config{BuilderSpec: cfg.BuilderSpec,
+		DockerSpec: config.DockerSpec{VersioningScheme: config.VersioningSchemeCalver}}
+	assert.NotEqual(t, fingerprint, buildFingerprint("sha1", baseImage, newScheme))
+	newSizePolicy := &config.Config{BuilderSpec: cfg.BuilderSpec, SizePolicy: config.SizePolicy{MaxImageSize: 1024}}
+	assert.NotEqual(t, fingerprint, buildFingerprint("sha1", baseImage, newSizePolicy))
+	newMetadata := &config.Config{BuilderSpec: cfg.BuilderSpec, ApplicationSpec: config.ApplicationSpec{
+		BaseImageSpec: config.DockerBaseImageSpec{TagMetadata: "wingnut17"}}}
+	assert.NotEqual(t, fingerprint, buildFingerprint("sha1", baseImage, newMetadata))
+
+	assert.Empty(t, buildFingerprint("", baseImage, cfg))
+	assert.Empty(t, buildFingerprint("sha1", runtime.BaseImage{}, cfg))
+}
+
+func TestFindIdenticalImage(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	registry := docker_mock.NewMockRegistry(ctrl)
+
+	registry.EXPECT().GetTags(ctx, "aurora/minarch").
+		Return(&docker.TagsAPIResponse{Tags: []string{"1.0.0-SNAPSHOT", "1.0.0-SNAPSHOT-20210101.120000-1"}}, nil)
+	registry.EXPECT().GetRawManifest(ctx, "aurora/minarch", "1.0.0-SNAPSHOT-20210101.120000-1").
+		Return([]byte(`{"config":{"digest":"sha256:old"}}`), nil)
+	registry.EXPECT().GetContainerConfig(ctx, "aurora/minarch", "sha256:old").
+		Return(&docker.ContainerConfig{Config: docker.DockerContainerConfig{
+			Labels: map[string]string{auroraLabelBuildFingerprint: "sha256:other"}}}, nil)
+	// Unusual formatting, so a manifest that is marshalled again gets another digest
+	sameManifest := []byte(`{ "config": { "digest": "sha256:same" } }`)
+	registry.EXPECT().GetRawManifest(ctx, "aurora/minarch", "1.0.0-SNAPSHOT").Return(sameManifest, nil)
+	registry.EXPECT().GetContainerConfig(ctx, "aurora/minarch", "sha256:same").
+		Return(&docker.ContainerConfig{Config: docker.DockerContainerConfig{
+			Labels: map[string]string{auroraLabelBuildFingerprint: "sha256:fingerprint"}}}, nil)
+
+	manifestData, manifest, tag, err := findIdenticalImage(ctx, registry, "aurora/minarch",
+		[]string{"1.0.0-SNAPSHOT-20210101.120000-2", "1.0.0-SNAPSHOT-20210101.120000-1", "1.0.0-SNAPSHOT"}, "sha256:fingerprint")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.0.0-SNAPSHOT", tag)
+	assert.Equal(t, "sha256:same", manifest.Config.Digest)
+	assert.Equal(t, sameManifest, manifestData)
+}
+
+func TestBuildRetagsIdenticalImage(t *testing.T) {
+	cfg := &config.Config{
+		ApplicationSpec: config.ApplicationSpec{
+			MavenGav:      config.MavenGav{ArtifactID: "minarch", GroupID: "no.skatteetaten.aurora", Version: "1.0.0-SNAPSHOT"},
+			BaseImageSpec: config.DockerBaseImageSpec{BaseImage: "aurora/wingnut11", BaseVersion: "1"},
+		},
+		DockerSpec: config.DockerSpec{
+			OutputRegistry:   "registry.example.com",
+			OutputRepository: "aurora/minarch",
+			TagWith:          "1.0.0-SNAPSHOT",
+		},
+		BuilderSpec:         config.BuilderSpec{Version: "1.2.0"},
+		SkipIdenticalBuilds: true,
+	}
+	baseImageInfo := &runtime.ImageInfo{CompleteBaseImageVersion: "1.0.0", Digest: "sha256:base"}
+	fingerprint := buildFingerprint("SHA1", runtime.BaseImage{ImageInfo: baseImageInfo}, cfg)
+	manifestData := []byte(`{ "config": { "digest": "sha256:same" } }`)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	registry := docker_mock.NewMockRegistry(ctrl)
+	downloader := nexus_mock.NewMockDownloader(ctrl)
+	mockSporingslogger := sporingslogger_mock.NewMockSporingslogger(ctrl)
+
+	downloader.EXPECT().DownloadArtifact(gomock.Any(), gomock.Any()).Return(nexus.Deliverable{SHA1: "SHA1"}, nil)
+	registry.EXPECT().GetImageInfo(gomock.Any(), "aurora/wingnut11", "1").Return(baseImageInfo, nil)
+	registry.EXPECT().GetTags(gomock.Any(), "aurora/minarch").
+		Return(&docker.TagsAPIResponse{Tags: []string{"1.0.0-SNAPSHOT"}}, nil).Times(2)
+	registry.EXPECT().GetRawManifest(gomock.Any(), "aurora/minarch", "1.0.0-SNAPSHOT").Return(manifestData, nil)
+	registry.EXPECT().GetContainerConfig(gomock.Any(), "aurora/minarch", "sha256:same").
+		Return(&docker.ContainerConfig{Config: docker.DockerContainerConfig{
+			Labels: map[string]string{auroraLabelBuildFingerprint: fingerprint}}}, nil)
+	registry.EXPECT().PushManifest(gomock.Any(), manifestData, "aurora/minarch", "1.0.0-SNAPSHOT").Return(nil)
+	registry.EXPECT().GetImageInfo(gomock.Any(), "aurora/minarch", "1.0.0-SNAPSHOT").
+		Return(&runtime.ImageInfo{Digest: util.CalculateDigest(manifestData)}, nil)
+	var sent sporingslogger.DeployableImage
+	mockSporingslogger.EXPECT().SendImageMetadata(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, data interface{}) error {
+			sent = data.(sporingslogger.DeployableImage)
+			return nil
+		})
+	prepper := func(context.Context, *config.Config, *runtime.AuroraVersion, nexus.Deliverable,
+		runtime.BaseImage) (*docker.BuildConfig, error) {
+		t.Fatal("An identical image must not be prepared")
+		return nil, nil
+	}
+
+	err := Build(context.Background(), registry, registry, cfg, downloader, prepper, nil, mockSporingslogger,
+		webhook.NewClient(cfg), nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, util.CalculateDigest(manifestData), sent.Digest)
+	assert.Equal(t, "sha256:base", sent.BaseImageDigest)
+}
@@ -0,0 +1,81 @@
+package process
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/skatteetaten/architect/v2/pkg/config"
+	"github.com/skatteetaten/architect/v2/pkg/config/runtime"
+	"github.com/skatteetaten/architect/v2/pkg/docker"
+)
+
+// Labels from https://github.com/opencontainers/image-spec/blob/main/annotations.md
+const (
+	ociLabelVersion    = "org.opencontainers.image.version"
+	ociLabelCreated    = "org.opencontainers.image.created"
+	ociLabelRevision   = "org.opencontainers.image.revision"
+	ociLabelSource     = "org.opencontainers.image.source"
+	ociLabelBaseName   = "org.opencontainers.image.base.name"
+	ociLabelBaseDigest = "org.opencontainers.image.base.digest"
+	ociLabelVendor     = "org.opencontainers.image.vendor"
+	ociLabelAuthors    = "org.opencontainers.image.authors"
+
+	auroraLabelGroupID          = "no.skatteetaten.aurora.group-id"
+	auroraLabelArtifactID       = "no.skatteetaten.aurora.artifact-id"
+	auroraLabelMavenVersion     = "no.skatteetaten.aurora.maven-version"
+	auroraLabelBuilderVersion   = "no.skatteetaten.aurora.builder-version"
+	auroraLabelNexusIQReportURL = "no.skatteetaten.aurora.nexus-iq-report-url"
+)
+
+// imageLabels merge the labels generated by Architect, the labels of the build file and the labels from the
+// deliverable metadata. Labels inherited from the base image are overwritten by all, and the deliverable metadata
+// always wins.
+func imageLabels(buildConfig docker.BuildConfig, cfg *config.Config, baseImage runtime.BaseImage) map[string]string {
+	labels := standardLabels(buildConfig, cfg, baseImage)
+	for k, v := range cfg.Labels {
+		labels[k] = v
+	}
+	for k, v := range buildConfig.Labels {
+		if generated, exists := labels[k]; exists && generated != v {
+			logrus.Infof("Label %s=%s from the deliverable metadata replaces %s", k, v, generated)
+		}
+		labels[k] = v
+	}
+	return labels
+}
+
+func standardLabels(buildConfig docker.BuildConfig, cfg *config.Config, baseImage runtime.BaseImage) map[string]string {
+	labels := make(map[string]string)
+	setLabel := func(key string, value string) {
+		if value != "" {
+			labels[key] = value
+		}
+	}
+
+	if buildConfig.AuroraVersion != nil {
+		setLabel(ociLabelVersion, string(buildConfig.AuroraVersion.GetAppVersion()))
+	}
+	created := buildConfig.Env[docker.ImageBuildTime]
+	if created == "" {
+		created = docker.GetUtcTimestamp()
+	}
+	setLabel(ociLabelCreated, created)
+	setLabel(ociLabelSource, cfg.SourceSpec.URL)
+	setLabel(ociLabelRevision, cfg.SourceSpec.Revision)
+
+	if baseImage.Repository != "" {
+		setLabel(ociLabelBaseName, baseImage.GetCompleteDockerTagName())
+	}
+	if baseImage.ImageInfo != nil {
+		setLabel(ociLabelBaseDigest, baseImage.ImageInfo.Digest)
+	}
+
+	setLabel(ociLabelVendor, buildConfig.Maintainer)
+	setLabel(ociLabelAuthors, buildConfig.Maintainer)
+
+	gav := cfg.ApplicationSpec.MavenGav
+	setLabel(auroraLabelGroupID, gav.GroupID)
+	setLabel(auroraLabelArtifactID, gav.ArtifactID)
+	setLabel(auroraLabelMavenVersion, gav.Version)
+	setLabel(auroraLabelBuilderVersion, cfg.BuilderSpec.Version)
+	setLabel(auroraLabelNexusIQReportURL, cfg.NexusIQReportURL)
+	return labels
+}
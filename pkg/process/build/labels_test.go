@@ -0,0 +1,69 @@
+package process
+
+import (
+	"github.com/skatteetaten/architect/v2/pkg/config"
+	"github.com/skatteetaten/architect/v2/pkg/config/runtime"
+	"github.com/skatteetaten/architect/v2/pkg/docker"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestImageLabels(t *testing.T) {
+	cfg := &config.Config{
+		ApplicationSpec: config.ApplicationSpec{
+			MavenGav: config.MavenGav{GroupID: "no.skatteetaten.aurora", ArtifactID: "minarch", Version: "2.3.0"},
+		},
+		BuilderSpec:      config.BuilderSpec{Version: "1.2.0"},
+		NexusIQReportURL: "https://nexus-iq/report/1",
+		SourceSpec:       config.SourceSpec{URL: "https://git/minarch.git", Revision: "abc123"},
+	}
+	buildConfig := docker.BuildConfig{
+		AuroraVersion: runtime.NewAuroraVersion("2.3.0", false, "2.3.0", "2.3.0-b1.2.0-wingnut11-1.0.0"),
+		Env:           map[string]string{docker.ImageBuildTime: "2021-01-01T00:00:00Z"},
+		Maintainer:    "Aurora <aurora@skatteetaten.no>",
+		Labels: map[string]string{
+			"org.opencontainers.image.vendor": "Skatteetaten",
+			"team":                            "aurora",
+		},
+	}
+	baseImage := runtime.BaseImage{
+		DockerImage: runtime.DockerImage{Registry: "registry:5000", Repository: "aurora/wingnut11", Tag: "1.0.0"},
+		ImageInfo:   &runtime.ImageInfo{Digest: "sha256:1234"},
+	}
+
+	labels := imageLabels(buildConfig, cfg, baseImage)
+
+	assert.Equal(t, "2.3.0", labels[ociLabelVersion])
+	assert.Equal(t, "2021-01-01T00:00:00Z", labels[ociLabelCreated])
+	assert.Equal(t, "https://git/minarch.git", labels[ociLabelSource])
+	assert.Equal(t, "abc123", labels[ociLabelRevision])
+	assert.Equal(t, "registry:5000/aurora/wingnut11:1.0.0", labels[ociLabelBaseName])
+	assert.Equal(t, "sha256:1234", labels[ociLabelBaseDigest])
+	assert.Equal(t, "Aurora <aurora@skatteetaten.no>", labels[ociLabelAuthors])
+	assert.Equal(t, "minarch", labels[auroraLabelArtifactID])
+	assert.Equal(t, "1.2.0", labels[auroraLabelBuilderVersion])
+	assert.Equal(t, "https://nexus-iq/report/1", labels[auroraLabelNexusIQReportURL])
+
+	// The deliverable metadata is authoritative
+	assert.Equal(t, "Skatteetaten", labels[ociLabelVendor])
+	assert.Equal(t, "aurora", labels["team"])
+}
+
+func TestImageLabelsFromBuildFile(t *testing.T) {
+	cfg := &config.Config{Labels: map[string]string{"team": "aurora", ociLabelVendor: "Aurora"}}
+	buildConfig := docker.BuildConfig{Labels: map[string]string{"team": "minarch"}}
+
+	labels := imageLabels(buildConfig, cfg, runtime.BaseImage{})
+
+	assert.Equal(t, "Aurora", labels[ociLabelVendor])
+	assert.Equal(t, "minarch", labels["team"])
+}
+
+func TestImageLabelsSkipsUnknownValues(t *testing.T) {
+	labels := imageLabels(docker.BuildConfig{}, &config.Config{}, runtime.BaseImage{})
+
+	assert.NotContains(t, labels, ociLabelSource)
+	assert.NotContains(t, labels, ociLabelBaseName)
+	assert.NotContains(t, labels, ociLabelAuthors)
+	assert.NotEmpty(t, labels[ociLabelCreated])
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/skatteetaten/architect/v2/pkg/config"
 	"github.com/skatteetaten/architect/v2/pkg/config/runtime"
 	"github.com/skatteetaten/architect/v2/pkg/docker"
+	"github.com/skatteetaten/architect/v2/pkg/telemetry"
 	"github.com/skatteetaten/architect/v2/pkg/util"
 	"io"
 	"io/ioutil"
@@ -29,6 +30,8 @@ type LayerProvider struct {
 	ContainerConfig *docker.ContainerConfig
 	BaseImage       runtime.DockerImage
 	Layers          []Layer
+	// BaseImageFiles is the base image file system. Nil when not all base image layers are available locally
+	BaseImageFiles util.ImageFileSystem
 }
 
 // Layer represent an image blob
@@ -73,6 +76,7 @@ func (l *LayerBuilder) Pull(ctx context.Context, buildConfig docker.BuildConfig)
 	})
 
 	var layers []Layer
+	pulledLayerPaths := make(map[string]string)
 	for _, layer := range blobs {
 		ok, _ := l.pushRegistry.LayerExists(ctx, l.config.DockerSpec.OutputRepository, layer.Digest)
 		if !ok {
@@ -80,10 +84,13 @@ func (l *LayerBuilder) Pull(ctx context.Context, buildConfig docker.BuildConfig)
 			ok, err := l.pushRegistry.LayerExists(ctx, baseImage.Repository, layer.Digest)
 			if err != nil || !ok {
 
-				missingLayerPath, err := l.pullRegistry.PullLayer(ctx, baseImage.Repository, layer.Digest)
+				pullCtx, span := telemetry.StartSpan(ctx, "pull_layer", telemetry.Attr("digest", layer.Digest))
+				missingLayerPath, err := l.pullRegistry.PullLayer(pullCtx, baseImage.Repository, layer.Digest)
+				span.Finish(err)
 				if err != nil {
 					return nil, errors.Wrapf(err, "Pull: Layer pull failed %s", layer.Digest)
 				}
+				pulledLayerPaths[layer.Digest] = missingLayerPath
 				layers = append(layers, Layer{
 					Digest: layer.Digest,
 					Size:   layer.Size,
@@ -99,16 +106,48 @@ func (l *LayerBuilder) Pull(ctx context.Context, buildConfig docker.BuildConfig)
 		}
 	}
 
+	var baseImageFiles util.ImageFileSystem
+	if len(buildConfig.RemovePaths) > 0 || len(buildConfig.ReplaceDirectories) > 0 {
+		baseImageFiles, err = l.listBaseImageFiles(ctx, baseImage.Repository, manifest, pulledLayerPaths)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &LayerProvider{
 		Manifest:        manifest,
 		ContainerConfig: containerConfig,
 		BaseImage:       baseImage,
 		Layers:          layers,
+		BaseImageFiles:  baseImageFiles,
 	}, nil
 }
 
+// listBaseImageFiles list the file system of the base image. Layers that were not pulled for the build are pulled
+// here, so the removed paths are always validated against every layer
+func (l *LayerBuilder) listBaseImageFiles(ctx context.Context, repository string, manifest *docker.ManifestV2,
+	pulledLayerPaths map[string]string) (util.ImageFileSystem, error) {
+	layerPaths := make([]string, 0, len(manifest.Layers))
+	for _, layer := range manifest.Layers {
+		layerPath, ok := pulledLayerPaths[layer.Digest]
+		if !ok {
+			var err error
+			layerPath, err = l.pullRegistry.PullLayer(ctx, repository, layer.Digest)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Unable to pull layer %s to list the base image files", layer.Digest)
+			}
+		}
+		layerPaths = append(layerPaths, layerPath)
+	}
+	files, err := util.ListLayerFiles(layerPaths)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to list the base image files")
+	}
+	return files, nil
+}
+
 // Build container image
-func (l *LayerBuilder) Build(buildConfig docker.BuildConfig, baseImageLayerProvider *LayerProvider) (*LayerProvider, error) {
+func (l *LayerBuilder) Build(ctx context.Context, buildConfig docker.BuildConfig, baseImageLayerProvider *LayerProvider) (*LayerProvider, error) {
 	buildFolder := buildConfig.BuildFolder
 	layerFolder := filepath.Join(buildFolder, util.LayerFolder)
 
@@ -117,6 +156,30 @@ func (l *LayerBuilder) Build(buildConfig docker.BuildConfig, baseImageLayerProvi
 		return nil, errors.Wrap(err, "Unable to the read the layer folder")
 	}
 
+	ownership := l.config.OwnershipPolicy.WithPaths(buildConfig.FileOwnership)
+	if err := ownership.Validate(); err != nil {
+		return nil, errors.Wrap(err, "Invalid file ownership")
+	}
+
+	whiteouts, err := layerWhiteouts(buildConfig, baseImageLayerProvider.BaseImageFiles, files)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := readLayerContent(layerFolder)
+	if err != nil {
+		return nil, err
+	}
+	if err := content.checkFileSizes(l.config.SizePolicy); err != nil {
+		return nil, err
+	}
+
+	var baseImageSize int64
+	for _, layer := range baseImageLayerProvider.Manifest.Layers {
+		baseImageSize += int64(layer.Size)
+	}
+	var applicationSize int64
+
 	manifest := baseImageLayerProvider.Manifest.CleanCopy()
 	containerConfig := baseImageLayerProvider.ContainerConfig.CleanCopy()
 
@@ -124,31 +187,39 @@ func (l *LayerBuilder) Build(buildConfig docker.BuildConfig, baseImageLayerProvi
 	for _, file := range files {
 		if file.IsDir() {
 
-			layerArchiveName, err := util.CompressLayerTarGz(layerFolder, file.Name(), buildFolder)
+			_, span := telemetry.StartSpan(ctx, "compress", telemetry.Attr("layer", file.Name()))
+			layerArchiveName, err := util.CompressLayerTarGz(ctx, layerFolder, file.Name(), buildFolder, ownership, whiteouts[file.Name()])
+			span.Finish(err)
 			if err != nil {
 				return nil, errors.Wrapf(err, "Compression of layer %s failed", file.Name())
 			}
 
 			layerPath := filepath.Join(buildFolder, layerArchiveName)
 
+			_, span = telemetry.StartSpan(ctx, "digest", telemetry.Attr("layer", file.Name()))
 			contentDigest, err := util.CalculateDigestFromArchive(layerPath)
 			if err != nil {
+				span.Finish(err)
 				return nil, errors.Wrap(err, "Failed to calculate the content digest")
 			}
 
 			reader, err := os.Open(layerPath)
 			if err != nil {
+				span.Finish(err)
 				return nil, errors.Wrapf(err, "Unable to open layer %s file", file)
 			}
 			defer reader.Close()
 
 			stat, err := reader.Stat()
 			if err != nil {
+				span.Finish(err)
 				return nil, errors.Wrapf(err, "Unable to calculate layer size of layer %s ", file)
 			}
 
 			size := int(stat.Size())
+			applicationSize += stat.Size()
 			digest, err := util.CalculateDigestFromFile(layerPath)
+			span.Finish(err)
 			if err != nil {
 				return nil, errors.Wrapf(err, "Unable to calculate layer digest of layer %s", file)
 			}
@@ -178,6 +249,10 @@ func (l *LayerBuilder) Build(buildConfig docker.BuildConfig, baseImageLayerProvi
 		}
 	}
 
+	if err := content.checkImageSize(l.config.SizePolicy, applicationSize, baseImageSize); err != nil {
+		return nil, err
+	}
+
 	cc, err := containerConfig.Create(buildConfig)
 	if err != nil {
 		return nil, err
@@ -208,6 +283,41 @@ func (l *LayerBuilder) Build(buildConfig docker.BuildConfig, baseImageLayerProvi
 	}, nil
 }
 
+// Whiteouts are written to the layer of their top level folder. Whiteouts outside
+// every layer folder are written to the last layer
+func layerWhiteouts(buildConfig docker.BuildConfig, baseImageFiles util.ImageFileSystem, files []os.FileInfo) (map[string][]util.Whiteout, error) {
+	whiteouts := util.NewWhiteouts(buildConfig.RemovePaths, buildConfig.ReplaceDirectories)
+	if len(whiteouts) == 0 {
+		return nil, nil
+	}
+
+	var lastLayer string
+	layerFolders := make(map[string]bool)
+	for _, file := range files {
+		if file.IsDir() {
+			layerFolders[file.Name()] = true
+			lastLayer = file.Name()
+		}
+	}
+	if lastLayer == "" {
+		return nil, errors.New("There is no application layer to remove base image files from")
+	}
+
+	byLayer := make(map[string][]util.Whiteout)
+	for _, whiteout := range whiteouts {
+		if err := whiteout.Validate(baseImageFiles); err != nil {
+			return nil, errors.Wrap(err, "Unable to remove base image file")
+		}
+		layer := whiteout.TopLevelFolder()
+		if !layerFolders[layer] {
+			layer = lastLayer
+		}
+		logrus.Infof("Remove %s from the base image in layer %s", whiteout.Path, layer)
+		byLayer[layer] = append(byLayer[layer], whiteout)
+	}
+	return byLayer, nil
+}
+
 // Push layers and tags
 func (l *LayerBuilder) Push(ctx context.Context, layers *LayerProvider, tag []string) error {
 
@@ -220,7 +330,9 @@ func (l *LayerBuilder) Push(ctx context.Context, layers *LayerProvider, tag []st
 			}
 			defer contentReader.Close()
 
-			err = l.pushRegistry.PushLayer(ctx, contentReader, l.config.DockerSpec.OutputRepository, layer.Digest)
+			pushCtx, span := telemetry.StartSpan(ctx, "push_layer", telemetry.Attr("digest", layer.Digest))
+			err = l.pushRegistry.PushLayer(pushCtx, contentReader, l.config.DockerSpec.OutputRepository, layer.Digest)
+			span.Finish(err)
 			if err != nil {
 				return errors.Wrapf(err, "Failed to push layer %s", layer.Digest)
 			}
@@ -239,7 +351,9 @@ func (l *LayerBuilder) Push(ctx context.Context, layers *LayerProvider, tag []st
 		if err != nil {
 			return errors.Wrap(err, "Manfifest marshal failed")
 		}
-		err = l.pushRegistry.PushManifest(ctx, manifest, l.config.DockerSpec.OutputRepository, shortTag)
+		pushCtx, span := telemetry.StartSpan(ctx, "push_manifest", telemetry.Attr("tag", shortTag))
+		err = l.pushRegistry.PushManifest(pushCtx, manifest, l.config.DockerSpec.OutputRepository, shortTag)
+		span.Finish(err)
 		if err != nil {
 			return errors.Errorf("Failed to push manifest: %v", err)
 		}
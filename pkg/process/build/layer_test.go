@@ -1,12 +1,20 @@
 package process
 
 import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"github.com/golang/mock/gomock"
 	"github.com/sirupsen/logrus"
+	"github.com/skatteetaten/architect/v2/pkg/docker"
+	docker_mock "github.com/skatteetaten/architect/v2/pkg/docker/mocks"
 	"github.com/skatteetaten/architect/v2/pkg/util"
+	"github.com/stretchr/testify/assert"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -47,3 +55,36 @@ func TestDigestCalculations(t *testing.T) {
 		}
 	})
 }
+
+func TestListBaseImageFilesPullsMissingLayers(t *testing.T) {
+	dir := t.TempDir()
+	lower := writeTestLayer(t, filepath.Join(dir, "lower.tar.gz"), "u01/config/default.yaml")
+	upper := writeTestLayer(t, filepath.Join(dir, "upper.tar.gz"), "u01/lib/app.jar")
+
+	mockCtrl := gomock.NewController(t)
+	pullRegistry := docker_mock.NewMockRegistry(mockCtrl)
+	pullRegistry.EXPECT().PullLayer(gomock.Any(), "aurora/wingnut11", "sha256:lower").Return(lower, nil)
+	builder := &LayerBuilder{pullRegistry: pullRegistry}
+
+	manifest := &docker.ManifestV2{
+		Layers: []docker.Layer{{Digest: "sha256:lower"}, {Digest: "sha256:upper"}},
+	}
+	files, err := builder.listBaseImageFiles(context.Background(), "aurora/wingnut11", manifest,
+		map[string]string{"sha256:upper": upper})
+
+	assert.NoError(t, err)
+	assert.Contains(t, files, "/u01/config/default.yaml")
+	assert.Contains(t, files, "/u01/lib/app.jar")
+}
+
+func writeTestLayer(t *testing.T, layerPath string, name string) string {
+	file, err := os.Create(layerPath)
+	assert.NoError(t, err)
+	defer file.Close()
+	gw := gzip.NewWriter(file)
+	defer gw.Close()
+	tw := tar.NewWriter(gw)
+	defer tw.Close()
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg}))
+	return layerPath
+}
@@ -37,18 +37,18 @@ func (m *MockBuilder) EXPECT() *MockBuilderMockRecorder {
 }
 
 // Build mocks base method.
-func (m *MockBuilder) Build(buildConfig docker.BuildConfig, baseimageLayers *process.LayerProvider) (*process.LayerProvider, error) {
+func (m *MockBuilder) Build(ctx context.Context, buildConfig docker.BuildConfig, baseimageLayers *process.LayerProvider) (*process.LayerProvider, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Build", buildConfig, baseimageLayers)
+	ret := m.ctrl.Call(m, "Build", ctx, buildConfig, baseimageLayers)
 	ret0, _ := ret[0].(*process.LayerProvider)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Build indicates an expected call of Build.
-func (mr *MockBuilderMockRecorder) Build(buildConfig, baseimageLayers interface{}) *gomock.Call {
+func (mr *MockBuilderMockRecorder) Build(ctx, buildConfig, baseimageLayers interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Build", reflect.TypeOf((*MockBuilder)(nil).Build), buildConfig, baseimageLayers)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Build", reflect.TypeOf((*MockBuilder)(nil).Build), ctx, buildConfig, baseimageLayers)
 }
 
 // Pull mocks base method.
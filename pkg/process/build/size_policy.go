@@ -0,0 +1,118 @@
+package process
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/skatteetaten/architect/v2/pkg/config"
+	"github.com/skatteetaten/architect/v2/pkg/util"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Number of files and directories listed when a size limit is exceeded
+const sizeReportEntries = 10
+
+type pathSize struct {
+	path string
+	size int64
+}
+
+// layerContent the uncompressed content of the layer folder
+type layerContent struct {
+	files       []pathSize
+	directories []pathSize
+}
+
+func readLayerContent(layerFolder string) (*layerContent, error) {
+	content := &layerContent{}
+	directorySizes := make(map[string]int64)
+	err := filepath.Walk(layerFolder, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		relative, err := filepath.Rel(layerFolder, path)
+		if err != nil {
+			return err
+		}
+		imagePath := "/" + filepath.ToSlash(relative)
+		content.files = append(content.files, pathSize{path: imagePath, size: info.Size()})
+		for dir := filepath.ToSlash(filepath.Dir(imagePath)); dir != "/"; dir = filepath.ToSlash(filepath.Dir(dir)) {
+			directorySizes[dir] += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to read the layer folder")
+	}
+	for dir, size := range directorySizes {
+		content.directories = append(content.directories, pathSize{path: dir, size: size})
+	}
+	sortBySize(content.files)
+	sortBySize(content.directories)
+	return content, nil
+}
+
+func sortBySize(entries []pathSize) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].size == entries[j].size {
+			return entries[i].path < entries[j].path
+		}
+		return entries[i].size > entries[j].size
+	})
+}
+
+// checkFileSizes fail if a single file is larger than allowed
+func (c *layerContent) checkFileSizes(policy config.SizePolicy) error {
+	if policy.MaxFileSize <= 0 || len(c.files) == 0 || c.files[0].size <= policy.MaxFileSize {
+		return nil
+	}
+	var tooLarge []string
+	for _, file := range c.files {
+		if file.size <= policy.MaxFileSize {
+			break
+		}
+		tooLarge = append(tooLarge, file.path)
+	}
+	return c.sizeError(fmt.Sprintf("%s larger than the file size limit %s (MAX_FILE_SIZE)",
+		strings.Join(tooLarge, ", "), util.FormatByteSize(policy.MaxFileSize)))
+}
+
+// checkImageSize fail if the compressed application layers or the complete image are larger than allowed
+func (c *layerContent) checkImageSize(policy config.SizePolicy, applicationSize int64, baseImageSize int64) error {
+	if policy.MaxApplicationLayerSize > 0 && applicationSize > policy.MaxApplicationLayerSize {
+		return c.sizeError(fmt.Sprintf("The application layer is %s, which is larger than the limit %s (MAX_APPLICATION_LAYER_SIZE)",
+			util.FormatByteSize(applicationSize), util.FormatByteSize(policy.MaxApplicationLayerSize)))
+	}
+	imageSize := applicationSize + baseImageSize
+	if policy.MaxImageSize > 0 && imageSize > policy.MaxImageSize {
+		return c.sizeError(fmt.Sprintf("The image is %s (base image %s), which is larger than the limit %s (MAX_IMAGE_SIZE)",
+			util.FormatByteSize(imageSize), util.FormatByteSize(baseImageSize), util.FormatByteSize(policy.MaxImageSize)))
+	}
+	logrus.Infof("Image size %s, application layer %s", util.FormatByteSize(imageSize), util.FormatByteSize(applicationSize))
+	return nil
+}
+
+func (c *layerContent) sizeError(message string) error {
+	report := []string{message, "Largest files:"}
+	for _, file := range largest(c.files) {
+		report = append(report, fmt.Sprintf("  %10s %s", util.FormatByteSize(file.size), file.path))
+	}
+	report = append(report, "Largest directories:")
+	for _, dir := range largest(c.directories) {
+		report = append(report, fmt.Sprintf("  %10s %s", util.FormatByteSize(dir.size), dir.path))
+	}
+	return errors.New(strings.Join(report, "\n"))
+}
+
+func largest(entries []pathSize) []pathSize {
+	if len(entries) > sizeReportEntries {
+		return entries[:sizeReportEntries]
+	}
+	return entries
+}
@@ -0,0 +1,34 @@
+package process
+
+import (
+	"github.com/skatteetaten/architect/v2/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSizePolicy(t *testing.T) {
+	layerFolder := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(layerFolder, "u01", "application", "lib"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(layerFolder, "u01", "application", "lib", "app.jar"), make([]byte, 3000), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(layerFolder, "u01", "application", "heap.hprof"), make([]byte, 9000), 0644))
+
+	content, err := readLayerContent(layerFolder)
+	assert.NoError(t, err)
+	assert.Equal(t, []pathSize{{"/u01/application/heap.hprof", 9000}, {"/u01/application/lib/app.jar", 3000}}, content.files)
+	assert.Equal(t, pathSize{"/u01", 12000}, content.directories[0])
+
+	assert.NoError(t, content.checkFileSizes(config.SizePolicy{}))
+	err = content.checkFileSizes(config.SizePolicy{MaxFileSize: 5000})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "/u01/application/heap.hprof larger than the file size limit")
+	assert.Contains(t, err.Error(), "Largest directories:")
+
+	policy := config.SizePolicy{MaxApplicationLayerSize: 1000, MaxImageSize: 5000}
+	assert.NoError(t, content.checkImageSize(policy, 800, 4000))
+	assert.Error(t, content.checkImageSize(policy, 1200, 0))
+	err = content.checkImageSize(policy, 800, 4500)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "MAX_IMAGE_SIZE")
+}
@@ -0,0 +1,146 @@
+package process
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/skatteetaten/architect/v2/pkg/config"
+	"github.com/skatteetaten/architect/v2/pkg/config/runtime"
+	"github.com/skatteetaten/architect/v2/pkg/docker"
+	"github.com/skatteetaten/architect/v2/pkg/process/tagger"
+	"github.com/skatteetaten/architect/v2/pkg/telemetry"
+	"github.com/skatteetaten/architect/v2/pkg/util"
+	"github.com/skatteetaten/architect/v2/pkg/webhook"
+	"strings"
+)
+
+// Target a registry and repository that gets a copy of the image after it is pushed to the output repository
+type Target struct {
+	Spec     config.OutputTarget
+	Registry docker.Registry
+}
+
+// CheckTargetsForOverwrite check the tags of every target before anything is pushed, so a protected tag in one
+// target does not leave a partly published release
+func CheckTargetsForOverwrite(ctx context.Context, cfg *config.Config, targets []Target,
+	auroraVersion *runtime.AuroraVersion, tagVariables tagger.TemplateVariables) error {
+	for _, target := range targets {
+		buildConfig := docker.BuildConfig{
+			AuroraVersion:    auroraVersion,
+			DockerRepository: target.Spec.Repository,
+		}
+		err := checkAllTagsForOverwrite(ctx, buildConfig, target.Registry, cfg.ForTarget(target.Spec), tagVariables)
+		if err != nil {
+			return errors.Wrapf(err, "Target %s", target.Spec)
+		}
+	}
+	return nil
+}
+
+// pushToTargets push the manifest the layer builder pushed to the output repository to every target
+func pushToTargets(ctx context.Context, cfg *config.Config, pushRegistry docker.Registry, targets []Target,
+	manifest *docker.ManifestV2, auroraVersion *runtime.AuroraVersion, tagVariables tagger.TemplateVariables,
+	notifier webhook.Notifier) error {
+	manifestData, err := json.Marshal(manifest)
+	if err != nil {
+		return errors.Wrap(err, "Manifest marshal failed")
+	}
+	return PushToTargets(ctx, cfg, pushRegistry, targets, manifestData, auroraVersion, tagVariables, notifier)
+}
+
+// PushToTargets copy the image from the output repository to every target, with the tags resolved against the
+// target. The manifest bytes are pushed unchanged, so the image has the same digest in every target. The targets
+// must be checked for overwrite first. Every target is tried, and reported with its own event. Returns an error
+// naming the targets that failed
+func PushToTargets(ctx context.Context, cfg *config.Config, pushRegistry docker.Registry, targets []Target,
+	manifestData []byte, auroraVersion *runtime.AuroraVersion, tagVariables tagger.TemplateVariables,
+	notifier webhook.Notifier) error {
+	if len(targets) == 0 {
+		return nil
+	}
+	manifest := &docker.ManifestV2{}
+	if err := json.Unmarshal(manifestData, manifest); err != nil {
+		return errors.Wrap(err, "Manifest unmarshal failed")
+	}
+	digest := util.CalculateDigest(manifestData)
+
+	var failed []string
+	for _, target := range targets {
+		pushCtx, cancel := stageContext(ctx, cfg, ErrorCategoryPush)
+		pushCtx, span := telemetry.StartSpan(pushCtx, "push_target", telemetry.Attr("target", target.Spec.String()))
+		tags, err := pushToTarget(pushCtx, cfg, pushRegistry, target, manifestData, manifest, auroraVersion,
+			tagVariables)
+		span.Finish(err)
+		cancel()
+		if err != nil {
+			logrus.Errorf("Target %s failed: %v", target.Spec, err)
+			failed = append(failed, target.Spec.String())
+			notifier.Notify(ctx, webhook.Event{
+				Type:          webhook.Failed,
+				Registry:      target.Spec.Registry,
+				Repository:    target.Spec.Repository,
+				Version:       auroraVersion.GetCompleteVersion(),
+				ErrorCategory: ErrorCategoryPush,
+				Stage:         ErrorCategoryPush,
+				Error:         err.Error(),
+			})
+			continue
+		}
+		logrus.Infof("Target %s: pushed %s", target.Spec, strings.Join(tags, ", "))
+		notifier.Notify(ctx, webhook.Event{
+			Type:       webhook.Pushed,
+			Registry:   target.Spec.Registry,
+			Repository: target.Spec.Repository,
+			Version:    auroraVersion.GetCompleteVersion(),
+			Tags:       tags,
+			Digest:     digest,
+		})
+	}
+	if len(failed) > 0 {
+		return errors.Errorf("Push to %d of %d output targets failed: %s", len(failed), len(targets),
+			strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// pushToTarget copy the blobs the target lacks, and push the manifest with every tag of the target
+func pushToTarget(ctx context.Context, cfg *config.Config, pushRegistry docker.Registry, target Target,
+	manifestData []byte, manifest *docker.ManifestV2, auroraVersion *runtime.AuroraVersion,
+	tagVariables tagger.TemplateVariables) ([]string, error) {
+	targetCfg := cfg.ForTarget(target.Spec)
+	buildConfig := docker.BuildConfig{
+		AuroraVersion:    auroraVersion,
+		DockerRepository: target.Spec.Repository,
+	}
+	tags, _, _, err := extractTags(ctx, buildConfig, target.Registry, targetCfg, tagVariables)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to extract tags")
+	}
+
+	blobs := []string{manifest.Config.Digest}
+	for _, layer := range manifest.Layers {
+		blobs = append(blobs, layer.Digest)
+	}
+	// Only a registry can mount from its own repositories
+	mount := target.Spec.Registry == cfg.DockerSpec.OutputRegistry
+	for _, digest := range blobs {
+		err := docker.CopyBlob(ctx, pushRegistry, cfg.DockerSpec.OutputRepository, target.Registry,
+			target.Spec.Repository, digest, mount)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, tag := range tags {
+		shortTag, err := util.FindOutputTagOrHash(tag)
+		if err != nil {
+			return nil, errors.Wrap(err, "Tag failed")
+		}
+		logrus.Infof("Push tag: %s", tag)
+		if err := target.Registry.PushManifest(ctx, manifestData, target.Spec.Repository, shortTag); err != nil {
+			return nil, errors.Wrapf(err, "Failed to push tag %s", tag)
+		}
+	}
+	return tags, nil
+}
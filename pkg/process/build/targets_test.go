@@ -0,0 +1,99 @@
+package process
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/skatteetaten/architect/v2/pkg/config"
+	"github.com/skatteetaten/architect/v2/pkg/config/runtime"
+	"github.com/skatteetaten/architect/v2/pkg/docker"
+	docker_mock "github.com/skatteetaten/architect/v2/pkg/docker/mocks"
+	"github.com/skatteetaten/architect/v2/pkg/process/tagger"
+	"github.com/skatteetaten/architect/v2/pkg/util"
+	"github.com/skatteetaten/architect/v2/pkg/webhook"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type recordingNotifier struct {
+	events []webhook.Event
+}
+
+func (r *recordingNotifier) Notify(ctx context.Context, event webhook.Event) {
+	r.events = append(r.events, event)
+}
+
+func TestPushToTargetsReportsEveryTarget(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{
+		DockerSpec: config.DockerSpec{
+			OutputRegistry:   "registry.example.com",
+			OutputRepository: "aurora/minarch",
+			TagWith:          "1.0.0",
+		},
+	}
+	manifest := &docker.ManifestV2{
+		Config: docker.Layer{Digest: "sha256:config"},
+		Layers: []docker.Layer{{Digest: "sha256:layer"}},
+	}
+	manifestData, err := json.Marshal(manifest)
+	assert.NoError(t, err)
+	auroraVersion := runtime.NewAuroraVersion("1.0.0", false, "1.0.0", runtime.CompleteVersion("1.0.0-b1.2.3"))
+
+	mockCtrl := gomock.NewController(t)
+	pushRegistry := docker_mock.NewMockRegistry(mockCtrl)
+
+	dr := docker_mock.NewMockRegistry(mockCtrl)
+	dr.EXPECT().LayerExists(gomock.Any(), "aurora/minarch", "sha256:config").Return(true, nil)
+	dr.EXPECT().LayerExists(gomock.Any(), "aurora/minarch", "sha256:layer").Return(true, nil)
+	dr.EXPECT().PushManifest(gomock.Any(), manifestData, "aurora/minarch", "1.0.0").Return(nil)
+
+	partner := docker_mock.NewMockRegistry(mockCtrl)
+	partner.EXPECT().LayerExists(gomock.Any(), "minarch", "sha256:config").Return(false, errors.New("unauthorized"))
+
+	targets := []Target{
+		{Spec: config.OutputTarget{Registry: "partner.example.com", Repository: "minarch"}, Registry: partner},
+		{Spec: config.OutputTarget{Registry: "dr.example.com", Repository: "aurora/minarch"}, Registry: dr},
+	}
+	notifier := &recordingNotifier{}
+
+	err = PushToTargets(ctx, cfg, pushRegistry, targets, manifestData, auroraVersion,
+		tagger.NewTemplateVariables(cfg, time.Now()), notifier)
+
+	assert.EqualError(t, err, "Push to 1 of 2 output targets failed: partner.example.com/minarch")
+	assert.Len(t, notifier.events, 2)
+	assert.Equal(t, webhook.Failed, notifier.events[0].Type)
+	assert.Equal(t, "partner.example.com", notifier.events[0].Registry)
+	assert.Equal(t, webhook.Pushed, notifier.events[1].Type)
+	assert.Equal(t, "dr.example.com", notifier.events[1].Registry)
+	assert.Equal(t, []string{"dr.example.com/aurora/minarch:1.0.0"}, notifier.events[1].Tags)
+	assert.Equal(t, util.CalculateDigest(manifestData), notifier.events[1].Digest)
+}
+
+func TestCheckTargetsForOverwrite(t *testing.T) {
+	cfg := &config.Config{
+		DockerSpec: config.DockerSpec{
+			OutputRegistry:   "registry.example.com",
+			OutputRepository: "aurora/minarch",
+		},
+	}
+	auroraVersion := runtime.NewAuroraVersion("1.0.0", false, "1.0.0", runtime.CompleteVersion("1.0.0-b1.2.3"))
+
+	mockCtrl := gomock.NewController(t)
+	dr := docker_mock.NewMockRegistry(mockCtrl)
+	dr.EXPECT().GetTags(gomock.Any(), "aurora/minarch").Return(&docker.TagsAPIResponse{}, nil)
+	partner := docker_mock.NewMockRegistry(mockCtrl)
+	partner.EXPECT().GetTags(gomock.Any(), "minarch").Return(&docker.TagsAPIResponse{Tags: []string{"1.0.0"}}, nil)
+
+	targets := []Target{
+		{Spec: config.OutputTarget{Registry: "dr.example.com", Repository: "aurora/minarch"}, Registry: dr},
+		{Spec: config.OutputTarget{Registry: "partner.example.com", Repository: "minarch"}, Registry: partner},
+	}
+
+	err := CheckTargetsForOverwrite(context.Background(), cfg, targets, auroraVersion,
+		tagger.NewTemplateVariables(cfg, time.Now()))
+
+	assert.EqualError(t, err, "Target partner.example.com/minarch: There is already a build with tag 1.0.0, overwrite not allowed")
+}
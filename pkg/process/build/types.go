@@ -1,6 +1,7 @@
 package process
 
 import (
+	"context"
 	"github.com/skatteetaten/architect/v2/pkg/config"
 	"github.com/skatteetaten/architect/v2/pkg/config/runtime"
 	"github.com/skatteetaten/architect/v2/pkg/docker"
@@ -10,6 +11,7 @@ import (
 // Prepper is a fuction used to prepare a docker image. It is called within the context of
 // the prepare stage
 type Prepper func(
+	ctx context.Context,
 	cfg *config.Config,
 	auroraVersion *runtime.AuroraVersion,
 	deliverable nexus.Deliverable,
@@ -0,0 +1,154 @@
+package promote
+
+import (
+	"context"
+	"github.com/docker/distribution/reference"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/skatteetaten/architect/v2/pkg/docker"
+	process "github.com/skatteetaten/architect/v2/pkg/process/build"
+	"github.com/skatteetaten/architect/v2/pkg/process/retag"
+	"github.com/skatteetaten/architect/v2/pkg/process/tagger"
+)
+
+// Image a repository in a registry. The tag is optional for the destination. A digest wins over the tag
+type Image struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseImage parse registry/repository:tag or registry/repository@digest
+func ParseImage(value string) (Image, error) {
+	named, err := reference.ParseNormalizedNamed(value)
+	if err != nil {
+		return Image{}, errors.Wrapf(err, "Invalid image reference %s", value)
+	}
+	image := Image{
+		Registry:   reference.Domain(named),
+		Repository: reference.Path(named),
+	}
+	if tagged, ok := named.(reference.NamedTagged); ok {
+		image.Tag = tagged.Tag()
+	}
+	if canonical, ok := named.(reference.Canonical); ok {
+		image.Digest = canonical.Digest().String()
+	}
+	return image, nil
+}
+
+// Reference the digest or the tag of the image
+func (m Image) Reference() string {
+	if m.Digest != "" {
+		return m.Digest
+	}
+	return m.Tag
+}
+
+// String registry/repository:tag or registry/repository@digest
+func (m Image) String() string {
+	name := m.Repository
+	if m.Registry != "" {
+		name = m.Registry + "/" + m.Repository
+	}
+	if m.Digest != "" {
+		return name + "@" + m.Digest
+	}
+	if m.Tag != "" {
+		return name + ":" + m.Tag
+	}
+	return name
+}
+
+// Promoter copy an image from one repository to another
+type Promoter struct {
+	Source      docker.Registry
+	Destination docker.Registry
+	Scheme      tagger.VersionScheme
+}
+
+// Promote copy the image to the destination and push the tags of the destination. Without a destination tag the
+// tags are resolved from the version of the image, as in a build. Returns the pushed tags
+func (m *Promoter) Promote(ctx context.Context, from Image, to Image) ([]string, error) {
+	if from.Reference() == "" {
+		return nil, errors.Errorf("The image to promote must have a tag or a digest, was %s", from)
+	}
+	if to.Digest != "" {
+		return nil, errors.Errorf("The destination can not be a digest, was %s", to)
+	}
+
+	imageInfo, err := m.Source.GetImageInfo(ctx, from.Repository, from.Reference())
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get image info of %s", from)
+	}
+	if from.Digest != "" && imageInfo.Digest != from.Digest {
+		return nil, errors.Errorf("The digest of %s is %s", from, imageInfo.Digest)
+	}
+	appVersion, pushExtraTags, err := retag.ImageVersion(imageInfo.Environment)
+	if err != nil {
+		return nil, err
+	}
+
+	// The digest makes sure the manifest is the image of the image info, even if the tag is moved meanwhile
+	manifestData, manifest, err := docker.GetVerifiedManifest(ctx, m.Source, from.Repository, imageInfo.Digest)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get manifest of %s", from)
+	}
+
+	tagsInDestination, err := m.Destination.GetTags(ctx, to.Repository)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get tags of %s", to)
+	}
+
+	var tagResolver tagger.TagResolver
+	if to.Tag == "" {
+		tagResolver = &tagger.NormalTagResolver{
+			Registry:       to.Registry,
+			Repository:     to.Repository,
+			RegistryClient: m.Destination,
+			Scheme:         m.Scheme,
+		}
+	} else {
+		tagResolver = &tagger.SingleTagResolver{
+			Registry:   to.Registry,
+			Repository: to.Repository,
+			Tag:        to.Tag,
+		}
+	}
+	tags, err := tagResolver.ResolveShortTag(ctx, appVersion, pushExtraTags)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to resolve the tags of %s", to)
+	}
+
+	err = process.CheckTagsForOverwrite(appVersion.Snapshot, tagsInDestination.Tags, to.Tag,
+		appVersion.GetGivenVersion(), appVersion.GetCompleteVersion())
+	if err != nil {
+		return nil, err
+	}
+	if m.Scheme != nil && !appVersion.Snapshot {
+		err = m.Scheme.CheckOverwrite(string(appVersion.GetAppVersion()), tagsInDestination.Tags)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	blobs := []string{manifest.Config.Digest}
+	for _, layer := range manifest.Layers {
+		blobs = append(blobs, layer.Digest)
+	}
+	for _, digest := range blobs {
+		if err := docker.CopyBlob(ctx, m.Source, from.Repository, m.Destination, to.Repository, digest,
+			from.Registry == to.Registry); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, tag := range tags {
+		logrus.Infof("Promote %s to %s", from, Image{Registry: to.Registry, Repository: to.Repository, Tag: tag})
+		if err := m.Destination.PushManifest(ctx, manifestData, to.Repository, tag); err != nil {
+			return nil, errors.Wrapf(err, "Failed to push tag %s", tag)
+		}
+	}
+	return tags, nil
+}
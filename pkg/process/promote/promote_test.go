@@ -0,0 +1,128 @@
+package promote
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	"github.com/skatteetaten/architect/v2/pkg/config/runtime"
+	"github.com/skatteetaten/architect/v2/pkg/docker"
+	docker_mock "github.com/skatteetaten/architect/v2/pkg/docker/mocks"
+	"github.com/skatteetaten/architect/v2/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseImage(t *testing.T) {
+	image, err := ParseImage("registry.example.com:5000/aurora/app:1.2.3")
+	assert.NoError(t, err)
+	assert.Equal(t, Image{Registry: "registry.example.com:5000", Repository: "aurora/app", Tag: "1.2.3"}, image)
+
+	image, err = ParseImage("registry.example.com/aurora/app")
+	assert.NoError(t, err)
+	assert.Equal(t, "", image.Tag)
+
+	digest := "sha256:" + strings.Repeat("a", 64)
+	image, err = ParseImage("registry.example.com/aurora/app@" + digest)
+	assert.NoError(t, err)
+	assert.Equal(t, digest, image.Reference())
+	assert.Equal(t, "registry.example.com/aurora/app@"+digest, image.String())
+
+	_, err = ParseImage("registry.example.com/Aurora/app")
+	assert.Error(t, err)
+}
+
+func expectSourceImage(source *docker_mock.MockRegistry) {
+	manifest := []byte(`{"schemaVersion":2,"config":{"digest":"sha256:config"},` +
+		`"layers":[{"digest":"sha256:layer1"},{"digest":"sha256:layer2"}]}`)
+	digest := util.CalculateDigest(manifest)
+	source.EXPECT().GetImageInfo(gomock.Any(), "aurora/app", "1.2.3").Return(&runtime.ImageInfo{
+		Digest: digest,
+		Environment: map[string]string{
+			docker.EnvAuroraVersion: "1.2.3-b1.2.3-wingnut11-1.0.0",
+			docker.EnvAppVersion:    "1.2.3",
+			docker.EnvPushExtraTags: "latest,major,minor,patch",
+		},
+	}, nil)
+	source.EXPECT().GetRawManifest(gomock.Any(), "aurora/app", digest).Return(manifest, nil)
+}
+
+func TestPromoteInRegistry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	registry := docker_mock.NewMockRegistry(ctrl)
+	expectSourceImage(registry)
+
+	registry.EXPECT().GetTags(gomock.Any(), "aurora/app-prod").
+		Return(&docker.TagsAPIResponse{Tags: []string{"1.1.0", "1.1", "1", "latest"}}, nil).Times(2)
+	registry.EXPECT().LayerExists(gomock.Any(), "aurora/app-prod", "sha256:config").Return(true, nil)
+	registry.EXPECT().LayerExists(gomock.Any(), "aurora/app-prod", gomock.Any()).Return(false, nil).Times(2)
+	registry.EXPECT().MountLayer(gomock.Any(), "aurora/app", "aurora/app-prod", "sha256:layer1").Return(nil)
+	registry.EXPECT().MountLayer(gomock.Any(), "aurora/app", "aurora/app-prod", "sha256:layer2").Return(nil)
+	registry.EXPECT().PushManifest(gomock.Any(), gomock.Any(), "aurora/app-prod", gomock.Any()).Return(nil).Times(5)
+
+	promoter := &Promoter{Source: registry, Destination: registry}
+	tags, err := promoter.Promote(context.Background(),
+		Image{Registry: "registry.example.com", Repository: "aurora/app", Tag: "1.2.3"},
+		Image{Registry: "registry.example.com", Repository: "aurora/app-prod"})
+
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"latest", "1", "1.2", "1.2.3", "1.2.3-b1.2.3-wingnut11-1.0.0"}, tags)
+}
+
+func TestPromoteToOtherRegistry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	source := docker_mock.NewMockRegistry(ctrl)
+	destination := docker_mock.NewMockRegistry(ctrl)
+	expectSourceImage(source)
+
+	blob := filepath.Join(t.TempDir(), "blob")
+	assert.NoError(t, os.WriteFile(blob, []byte("blob"), 0644))
+
+	destination.EXPECT().GetTags(gomock.Any(), "aurora/app").
+		Return(&docker.TagsAPIResponse{Tags: []string{"2.0.0"}}, nil).Times(2)
+	destination.EXPECT().LayerExists(gomock.Any(), "aurora/app", gomock.Any()).Return(false, nil).Times(3)
+	source.EXPECT().PullLayer(gomock.Any(), "aurora/app", gomock.Any()).Return(blob, nil).Times(3)
+	destination.EXPECT().PushLayer(gomock.Any(), gomock.Any(), "aurora/app", gomock.Any()).Return(nil).Times(3)
+	destination.EXPECT().PushManifest(gomock.Any(), gomock.Any(), "aurora/app", gomock.Any()).Return(nil).Times(4)
+
+	promoter := &Promoter{Source: source, Destination: destination}
+	tags, err := promoter.Promote(context.Background(),
+		Image{Registry: "registry-test.example.com", Repository: "aurora/app", Tag: "1.2.3"},
+		Image{Registry: "registry.example.com", Repository: "aurora/app"})
+
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"1", "1.2", "1.2.3", "1.2.3-b1.2.3-wingnut11-1.0.0"}, tags)
+}
+
+func TestPromoteDoesNotOverwriteRelease(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	source := docker_mock.NewMockRegistry(ctrl)
+	destination := docker_mock.NewMockRegistry(ctrl)
+	expectSourceImage(source)
+
+	destination.EXPECT().GetTags(gomock.Any(), "aurora/app").
+		Return(&docker.TagsAPIResponse{Tags: []string{"1.2.3"}}, nil).Times(2)
+
+	promoter := &Promoter{Source: source, Destination: destination}
+	_, err := promoter.Promote(context.Background(),
+		Image{Registry: "registry-test.example.com", Repository: "aurora/app", Tag: "1.2.3"},
+		Image{Registry: "registry.example.com", Repository: "aurora/app"})
+
+	assert.Error(t, err)
+}
+
+func TestPromoteVerifiesDigest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	source := docker_mock.NewMockRegistry(ctrl)
+	destination := docker_mock.NewMockRegistry(ctrl)
+	source.EXPECT().GetImageInfo(gomock.Any(), "aurora/app", "sha256:tested").
+		Return(&runtime.ImageInfo{Digest: "sha256:other"}, nil)
+
+	promoter := &Promoter{Source: source, Destination: destination}
+	_, err := promoter.Promote(context.Background(),
+		Image{Registry: "registry-test.example.com", Repository: "aurora/app", Digest: "sha256:tested"},
+		Image{Registry: "registry.example.com", Repository: "aurora/app"})
+
+	assert.EqualError(t, err, "The digest of registry-test.example.com/aurora/app@sha256:tested is sha256:other")
+}
@@ -0,0 +1,147 @@
+package prune
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/skatteetaten/architect/v2/pkg/docker"
+	"github.com/skatteetaten/architect/v2/pkg/util"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The unique snapshot tag is the given version and the last 8 characters of the deliverable hash
+var uniqueSnapshotSuffix = regexp.MustCompile(`^-[0-9a-fA-F]{8}$`)
+
+// Policy the snapshot tags to keep. A tag is kept if any of the rules keeps it
+type Policy struct {
+	// KeepLast the number of unique snapshot tags to keep per given version
+	KeepLast int
+	// KeepYoungerThan keep the unique snapshot tags built within this duration
+	KeepYoungerThan time.Duration
+}
+
+// Tag a tag in the repository
+type Tag struct {
+	Name         string
+	Digest       string
+	GivenVersion string
+	BuildTime    time.Time
+}
+
+// Plan the result of the retention policy
+type Plan struct {
+	Delete []Tag
+	Keep   []Tag
+}
+
+// Digests the manifests to delete. A digest that is referenced by a tag that is kept is never deleted
+func (m *Plan) Digests() []string {
+	kept := make(map[string]bool)
+	for _, tag := range m.Keep {
+		kept[tag.Digest] = true
+	}
+	var digests []string
+	for _, tag := range m.Delete {
+		if !kept[tag.Digest] {
+			kept[tag.Digest] = true
+			digests = append(digests, tag.Digest)
+		}
+	}
+	return digests
+}
+
+// NewPlan apply the policy to the tags of the repository. Only unique snapshot tags are candidates for deletion
+func NewPlan(ctx context.Context, registry docker.Registry, repository string, policy Policy, now time.Time) (*Plan, error) {
+	tagsInRepo, err := registry.GetTags(ctx, repository)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get tags of %s", repository)
+	}
+
+	plan := &Plan{}
+	candidates := make(map[string][]Tag)
+	for _, name := range tagsInRepo.Tags {
+		imageInfo, err := registry.GetImageInfo(ctx, repository, name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to get image info of %s:%s", repository, name)
+		}
+		tag := Tag{
+			Name:         name,
+			Digest:       imageInfo.Digest,
+			GivenVersion: imageInfo.Environment[docker.EnvSnapshotVersion],
+		}
+		if !isUniqueSnapshotTag(tag) {
+			plan.Keep = append(plan.Keep, tag)
+			continue
+		}
+		buildTime, err := time.Parse(time.RFC3339, imageInfo.Environment[docker.ImageBuildTime])
+		if err != nil {
+			logrus.Warnf("Keep %s. Unable to parse %s of the image: %v", name, docker.ImageBuildTime, err)
+			plan.Keep = append(plan.Keep, tag)
+			continue
+		}
+		tag.BuildTime = buildTime
+		candidates[tag.GivenVersion] = append(candidates[tag.GivenVersion], tag)
+	}
+
+	for _, tags := range candidates {
+		sort.Slice(tags, func(i, j int) bool {
+			return tags[i].BuildTime.After(tags[j].BuildTime)
+		})
+		for i, tag := range tags {
+			if i < policy.KeepLast || now.Sub(tag.BuildTime) < policy.KeepYoungerThan {
+				plan.Keep = append(plan.Keep, tag)
+			} else {
+				plan.Delete = append(plan.Delete, tag)
+			}
+		}
+	}
+	sort.Slice(plan.Delete, func(i, j int) bool {
+		return plan.Delete[i].Name < plan.Delete[j].Name
+	})
+	return plan, nil
+}
+
+// isUniqueSnapshotTag tags that look like releases are never candidates, whatever the image is
+func isUniqueSnapshotTag(tag Tag) bool {
+	if tag.GivenVersion == "" || !strings.Contains(tag.GivenVersion, "SNAPSHOT") {
+		return false
+	}
+	if util.IsSemanticVersion(tag.Name) || util.IsFullSemanticVersion(tag.Name) || util.IsPreReleaseVersion(tag.Name) {
+		return false
+	}
+	if !strings.HasPrefix(tag.Name, tag.GivenVersion) {
+		return false
+	}
+	return uniqueSnapshotSuffix.MatchString(strings.TrimPrefix(tag.Name, tag.GivenVersion))
+}
+
+// Prune delete the manifests of the plan. With dryRun the plan is only logged
+func Prune(ctx context.Context, registry docker.Registry, repository string, plan *Plan, dryRun bool) error {
+	digests := plan.Digests()
+	deleted := make(map[string]bool)
+	for _, digest := range digests {
+		deleted[digest] = true
+	}
+	for _, tag := range plan.Delete {
+		if deleted[tag.Digest] {
+			logrus.Infof("Prune %s:%s built %s (%s)", repository, tag.Name, tag.BuildTime.Format(time.RFC3339), tag.Digest)
+		} else {
+			logrus.Infof("Keep %s:%s. The manifest %s has a tag that is kept", repository, tag.Name, tag.Digest)
+		}
+	}
+	if dryRun {
+		logrus.Infof("Dry run. Would delete %d manifests with %d tags, and keep %d tags", len(digests),
+			len(plan.Delete), len(plan.Keep))
+		return nil
+	}
+	for _, digest := range digests {
+		if err := registry.DeleteManifest(ctx, repository, digest); err != nil {
+			return errors.Wrapf(err, "Failed to delete manifest %s", digest)
+		}
+	}
+	logrus.Infof("Deleted %d manifests with %d tags, and kept %d tags", len(digests), len(plan.Delete), len(plan.Keep))
+	return nil
+}
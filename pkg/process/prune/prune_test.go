@@ -0,0 +1,89 @@
+package prune
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	"github.com/skatteetaten/architect/v2/pkg/config/runtime"
+	"github.com/skatteetaten/architect/v2/pkg/docker"
+	docker_mock "github.com/skatteetaten/architect/v2/pkg/docker/mocks"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2022, 6, 30, 12, 0, 0, 0, time.UTC)
+
+type image struct {
+	digest       string
+	givenVersion string
+	buildTime    string
+}
+
+func mockRepository(ctrl *gomock.Controller, images map[string]image) *docker_mock.MockRegistry {
+	registry := docker_mock.NewMockRegistry(ctrl)
+	var tags []string
+	for tag, img := range images {
+		tags = append(tags, tag)
+		env := map[string]string{docker.ImageBuildTime: img.buildTime}
+		if img.givenVersion != "" {
+			env[docker.EnvSnapshotVersion] = img.givenVersion
+		}
+		registry.EXPECT().GetImageInfo(gomock.Any(), "aurora/app", tag).Return(&runtime.ImageInfo{
+			Digest:      img.digest,
+			Environment: env,
+		}, nil)
+	}
+	registry.EXPECT().GetTags(gomock.Any(), "aurora/app").Return(&docker.TagsAPIResponse{Tags: tags}, nil)
+	return registry
+}
+
+func TestPrune(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	registry := mockRepository(ctrl, map[string]image{
+		"1.2.3":                            {digest: "sha256:release"},
+		"latest":                           {digest: "sha256:release"},
+		"feature-SNAPSHOT":                 {digest: "sha256:f4", givenVersion: "feature-SNAPSHOT", buildTime: "2022-06-29T12:00:00Z"},
+		"feature-SNAPSHOT-0000000a":        {digest: "sha256:f1", givenVersion: "feature-SNAPSHOT", buildTime: "2022-01-01T12:00:00Z"},
+		"feature-SNAPSHOT-0000000b":        {digest: "sha256:f2", givenVersion: "feature-SNAPSHOT", buildTime: "2022-02-01T12:00:00Z"},
+		"feature-SNAPSHOT-0000000c":        {digest: "sha256:f3", givenVersion: "feature-SNAPSHOT", buildTime: "2022-03-01T12:00:00Z"},
+		"feature-SNAPSHOT-0000000d":        {digest: "sha256:f4", givenVersion: "feature-SNAPSHOT", buildTime: "2022-06-29T12:00:00Z"},
+		"develop-SNAPSHOT-0000000e":        {digest: "sha256:d1", givenVersion: "develop-SNAPSHOT", buildTime: "2022-06-01T12:00:00Z"},
+		"develop-SNAPSHOT-0000000f":        {digest: "sha256:d2", givenVersion: "develop-SNAPSHOT", buildTime: "2022-06-20T12:00:00Z"},
+		"develop-SNAPSHOT-00000010":        {digest: "sha256:d3", givenVersion: "develop-SNAPSHOT", buildTime: "2022-06-25T12:00:00Z"},
+		"develop-SNAPSHOT-no-build-time-1": {digest: "sha256:d0", givenVersion: "develop-SNAPSHOT"},
+	})
+
+	plan, err := NewPlan(context.Background(), registry, "aurora/app", Policy{KeepLast: 1, KeepYoungerThan: 14 * 24 * time.Hour}, now)
+	assert.NoError(t, err)
+
+	var deleted []string
+	for _, tag := range plan.Delete {
+		deleted = append(deleted, tag.Name)
+	}
+	assert.Equal(t, []string{"develop-SNAPSHOT-0000000e", "feature-SNAPSHOT-0000000a", "feature-SNAPSHOT-0000000b",
+		"feature-SNAPSHOT-0000000c"}, deleted)
+	assert.ElementsMatch(t, []string{"sha256:d1", "sha256:f1", "sha256:f2", "sha256:f3"}, plan.Digests())
+
+	registry.EXPECT().DeleteManifest(gomock.Any(), "aurora/app", gomock.Any()).Return(nil).Times(4)
+	assert.NoError(t, Prune(context.Background(), registry, "aurora/app", plan, false))
+}
+
+func TestPruneNeverDeletesReleases(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	registry := mockRepository(ctrl, map[string]image{
+		// A snapshot image that is tagged like a release
+		"1.2.3":                     {digest: "sha256:s1", givenVersion: "1.2.3-SNAPSHOT", buildTime: "2020-01-01T12:00:00Z"},
+		"1.2.3-SNAPSHOT-0000000a":   {digest: "sha256:s1", givenVersion: "1.2.3-SNAPSHOT", buildTime: "2020-01-01T12:00:00Z"},
+		"1.2.3-SNAPSHOT-0000000b":   {digest: "sha256:s2", givenVersion: "1.2.3-SNAPSHOT", buildTime: "2020-02-01T12:00:00Z"},
+		"1.2.3-SNAPSHOT-0000000c":   {digest: "sha256:s3", givenVersion: "1.2.3-SNAPSHOT", buildTime: "2020-03-01T12:00:00Z"},
+		"1.2.3-SNAPSHOT-not-unique": {digest: "sha256:s4", givenVersion: "1.2.3-SNAPSHOT", buildTime: "2020-01-01T12:00:00Z"},
+	})
+
+	plan, err := NewPlan(context.Background(), registry, "aurora/app", Policy{KeepLast: 1}, now)
+	assert.NoError(t, err)
+	assert.Len(t, plan.Delete, 2)
+	assert.Equal(t, []string{"sha256:s2"}, plan.Digests())
+
+	// Dry run never deletes
+	assert.NoError(t, Prune(context.Background(), registry, "aurora/app", plan, true))
+}
@@ -2,132 +2,324 @@ package retag
 
 import (
 	"context"
+	"github.com/docker/distribution/reference"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/skatteetaten/architect/v2/pkg/config"
 	"github.com/skatteetaten/architect/v2/pkg/config/runtime"
 	"github.com/skatteetaten/architect/v2/pkg/docker"
-	process "github.com/skatteetaten/architect/v2/pkg/process/build"
+	"github.com/skatteetaten/architect/v2/pkg/process/build"
 	"github.com/skatteetaten/architect/v2/pkg/process/tagger"
+	"github.com/skatteetaten/architect/v2/pkg/util"
+	"github.com/skatteetaten/architect/v2/pkg/webhook"
+	"net"
 	"net/url"
+	"strings"
+	"time"
 )
 
 type retagger struct {
-	Config       *config.Config
-	Credentials  *docker.RegistryCredentials
-	PullRegistry docker.Registry
-	Builder      process.Builder
+	Config          *config.Config
+	CredentialsFunc func(string) (*docker.RegistryCredentials, error)
+	PullRegistry    docker.Registry
+	PushRegistry    docker.Registry
+	Notifier        webhook.Notifier
+	// Targets get the tags after the output repository
+	Targets []process.Target
 }
 
-func newRetagger(cfg *config.Config, credentials *docker.RegistryCredentials, pullRegistry docker.Registry, builder process.Builder) *retagger {
+func newRetagger(cfg *config.Config, credentialsFunc func(string) (*docker.RegistryCredentials, error),
+	pullRegistry docker.Registry, pushRegistry docker.Registry, notifier webhook.Notifier) *retagger {
 	return &retagger{
-		Config:       cfg,
-		Credentials:  credentials,
-		PullRegistry: pullRegistry,
-		Builder:      builder,
+		Config:          cfg,
+		CredentialsFunc: credentialsFunc,
+		PullRegistry:    pullRegistry,
+		PushRegistry:    pushRegistry,
+		Notifier:        notifier,
 	}
 }
 
-// Retag image
-func Retag(ctx context.Context, cfg *config.Config, credentials *docker.RegistryCredentials, pullRegistry docker.Registry, builder process.Builder) error {
-	r := newRetagger(cfg, credentials, pullRegistry, builder)
+// Retag image, in the output repository and in the output targets
+func Retag(ctx context.Context, cfg *config.Config, credentials *docker.RegistryCredentials,
+	credentialsFunc func(string) (*docker.RegistryCredentials, error), pullRegistry docker.Registry,
+	notifier webhook.Notifier, targets []process.Target) error {
+	retagRegistryURL := url.URL{
+		Host:   cfg.DockerSpec.OutputRegistry,
+		Scheme: "https",
+	}
+
+	//This in only for the push-registry
+	retagRegistry := docker.RegistryConnectionInfo{
+		Port:        docker.GetPortOrDefault(retagRegistryURL.Port()),
+		Insecure:    docker.InsecureOrDefault(cfg),
+		Host:        retagRegistryURL.Hostname(),
+		Credentials: credentials,
+	}
+	r := newRetagger(cfg, credentialsFunc, pullRegistry, docker.NewRegistryClient(retagRegistry), notifier)
+	r.Targets = targets
 	return r.Retag(ctx)
 }
 
+// ParseSource parse the image to retag. A tag or a digest refers to the output repository, otherwise the value is
+// [registry/]repository:tag or [registry/]repository@digest
+func ParseSource(value string, outputRepository string) (runtime.DockerImage, error) {
+	value = strings.TrimPrefix(value, "@")
+	if strings.HasPrefix(value, "sha256:") || !strings.ContainsAny(value, "/:@") {
+		return runtime.DockerImage{Repository: outputRepository, Tag: value}, nil
+	}
+
+	ref, err := reference.Parse(value)
+	if err != nil {
+		return runtime.DockerImage{}, errors.Wrapf(err, "Invalid image reference %s", value)
+	}
+	named, ok := ref.(reference.Named)
+	if !ok {
+		return runtime.DockerImage{}, errors.Errorf("Image reference %s has no repository", value)
+	}
+	// As in docker, the first component is a registry only if it looks like a host
+	image := runtime.DockerImage{Repository: named.Name()}
+	if i := strings.Index(image.Repository, "/"); i > 0 {
+		host := image.Repository[:i]
+		if strings.ContainsAny(host, ".:") || host == "localhost" {
+			image.Registry, image.Repository = host, image.Repository[i+1:]
+		}
+	}
+	if digested, ok := ref.(reference.Digested); ok {
+		image.Tag = digested.Digest().String()
+	} else if tagged, ok := ref.(reference.Tagged); ok {
+		image.Tag = tagged.Tag()
+	} else {
+		return runtime.DockerImage{}, errors.Errorf("Image reference %s has no tag or digest", value)
+	}
+	return image, nil
+}
+
 // Retag image
 func (m *retagger) Retag(ctx context.Context) error {
-	tag := m.Config.DockerSpec.RetagWith
-	repository := m.Config.DockerSpec.OutputRepository
+	source, err := ParseSource(m.Config.DockerSpec.RetagWith, m.Config.DockerSpec.OutputRepository)
+	if err != nil {
+		return err
+	}
+
+	sourceRegistry, err := m.sourceRegistry(source.Registry)
+	if err != nil {
+		return err
+	}
 
 	logrus.Debug("Get ENV from image manifest")
 
-	imageInfo, err := m.PullRegistry.GetImageInfo(ctx, repository, tag)
+	imageInfo, err := sourceRegistry.GetImageInfo(ctx, source.Repository, source.Tag)
 
 	if err != nil {
 		return errors.Wrap(err, "Failed to retag image")
 	}
 
-	envMap := imageInfo.Environment
+	if strings.HasPrefix(source.Tag, "sha256:") && imageInfo.Digest != source.Tag {
+		return errors.Errorf("The digest of %s@%s is %s", source.Repository, source.Tag, imageInfo.Digest)
+	}
+	if tested := m.Config.DockerSpec.RetagDigest; tested != "" && imageInfo.Digest != tested {
+		return errors.Errorf("The image %s:%s is %s, not the tested digest %s", source.Repository, source.Tag,
+			imageInfo.Digest, tested)
+	}
 
-	// Get AURORA_VERSION
-	auroraVersion, ok := envMap[docker.EnvAuroraVersion]
+	appVersion, pushExtraTags, err := ImageVersion(imageInfo.Environment)
+	if err != nil {
+		return err
+	}
+	auroraVersion := appVersion.GetCompleteVersion()
 
-	if !ok {
-		return errors.Errorf("Failed to extract ENV variable %s from temporary image manifest", docker.EnvAuroraVersion)
+	scheme, err := tagger.NewVersionScheme(m.Config.DockerSpec.VersioningScheme)
+	if err != nil {
+		return err
 	}
+	var t tagger.TagResolver = &tagger.NormalTagResolver{
+		Repository:     m.Config.DockerSpec.OutputRepository,
+		Registry:       m.Config.DockerSpec.OutputRegistry,
+		RegistryClient: m.PushRegistry,
+		Scheme:         scheme,
+	}
+	variables := tagger.NewTemplateVariables(m.Config, time.Now())
+	if len(m.Config.DockerSpec.TagTemplates) > 0 {
+		if err := m.checkTemplateTagsForOverwrite(ctx, m.PushRegistry, m.Config, appVersion, variables); err != nil {
+			return err
+		}
+		// Every target is checked before anything is pushed
+		for _, target := range m.Targets {
+			err := m.checkTemplateTagsForOverwrite(ctx, target.Registry, m.Config.ForTarget(target.Spec), appVersion,
+				variables)
+			if err != nil {
+				return errors.Wrapf(err, "Target %s", target.Spec)
+			}
+		}
+		t = &tagger.TemplateTagResolver{
+			Repository: m.Config.DockerSpec.OutputRepository,
+			Registry:   m.Config.DockerSpec.OutputRegistry,
+			Templates:  m.Config.DockerSpec.TagTemplates,
+			Variables:  variables,
+			Resolver:   t,
+		}
+	}
+	logrus.Debugf("Extract tag info, auroraVersion=%v, appVersion=%v, extraTags=%s", auroraVersion, appVersion,
+		pushExtraTags.ToStringValue())
 
-	appVersionString, ok := envMap[docker.EnvAppVersion]
+	tagsToPush, err := t.ResolveTags(ctx, appVersion, pushExtraTags)
 
-	if !ok {
-		return errors.Errorf("Failed to extract ENV variable %s from temporary image manifest", docker.EnvAppVersion)
+	if err != nil {
+		return err
 	}
 
-	givenVersionString, snapshot := envMap[docker.EnvSnapshotVersion]
-
-	if !snapshot {
-		givenVersionString = appVersionString
+	if err := m.logTagMoves(ctx, m.PushRegistry, tagsToPush, imageInfo.Digest); err != nil {
+		return err
+	}
+	if m.Config.DockerSpec.RetagDryRun {
+		logrus.Info("Dry run. No tags are moved")
+		return nil
 	}
 
-	appVersion := runtime.NewAuroraVersion(appVersionString, snapshot, givenVersionString, runtime.CompleteVersion(auroraVersion))
+	// Copy only the blobs the output repository lacks, and push the manifest bytes unchanged. The retagged image
+	// keeps the digest of the tested image
+	manifestData, manifest, err := docker.GetVerifiedManifest(ctx, sourceRegistry, source.Repository, imageInfo.Digest)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to get manifest of %s@%s", source.Repository, imageInfo.Digest)
+	}
+	blobs := []string{manifest.Config.Digest}
+	for _, layer := range manifest.Layers {
+		blobs = append(blobs, layer.Digest)
+	}
+	for _, digest := range blobs {
+		err := docker.CopyBlob(ctx, sourceRegistry, source.Repository, m.PushRegistry,
+			m.Config.DockerSpec.OutputRepository, digest, sourceRegistry == m.PullRegistry)
+		if err != nil {
+			return err
+		}
+	}
 
-	extratags, ok := envMap[docker.EnvPushExtraTags]
-	if !ok {
-		return errors.Errorf("Failed to extract ENV variable %s from temporary image manifest", docker.EnvPushExtraTags)
+	logrus.Debugf("Retagging temporary image, versionTags=%-v", tagsToPush)
+	for _, tag := range tagsToPush {
+		shortTag, err := util.FindOutputTagOrHash(tag)
+		if err != nil {
+			return errors.Wrap(err, "Tag failed")
+		}
+		logrus.Infof("Tag image %s@%s with alias %s", source.Repository, imageInfo.Digest, tag)
+		err = m.PushRegistry.PushManifest(ctx, manifestData, m.Config.DockerSpec.OutputRepository, shortTag)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to push tag %s", tag)
+		}
 	}
+	m.Notifier.Notify(ctx, webhook.Event{
+		Type:    webhook.Retagged,
+		Version: auroraVersion,
+		Tags:    tagsToPush,
+		Digest:  imageInfo.Digest,
+	})
 
-	pushExtraTags := config.ParseExtraTags(extratags)
+	if len(m.Targets) > 0 {
+		// The blobs are in the output repository now
+		err := process.PushToTargets(ctx, m.Config, m.PushRegistry, m.Targets, manifestData, appVersion, variables,
+			m.Notifier)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	retagRegistryURL := url.URL{
-		Host:   m.Config.DockerSpec.OutputRegistry,
-		Scheme: "https",
+// sourceRegistry the pull registry, unless the image to retag lives in another registry
+func (m *retagger) sourceRegistry(registry string) (docker.Registry, error) {
+	if registry == "" || registry == m.Config.DockerSpec.OutputRegistry ||
+		registry == m.Config.DockerSpec.GetInternalPullRegistryWithoutProtocol() {
+		return m.PullRegistry, nil
 	}
 
-	//This in only for the push-registry
-	retagRegistry := docker.RegistryConnectionInfo{
-		Port:        docker.GetPortOrDefault(retagRegistryURL.Port()),
-		Insecure:    docker.InsecureOrDefault(m.Config),
-		Host:        retagRegistryURL.Hostname(),
-		Credentials: m.Credentials,
+	var credentials *docker.RegistryCredentials
+	if m.CredentialsFunc != nil {
+		var err error
+		credentials, err = m.CredentialsFunc(registry)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Could not read registry credentials for %s", registry)
+		}
 	}
-	t := tagger.NormalTagResolver{
-		Repository:     m.Config.DockerSpec.OutputRepository,
-		Registry:       m.Config.DockerSpec.OutputRegistry,
-		RegistryClient: docker.NewRegistryClient(retagRegistry),
+	host, port, err := net.SplitHostPort(registry)
+	if err != nil {
+		host, port = registry, ""
 	}
-	logrus.Debugf("Extract tag info, auroraVersion=%v, appVersion=%v, extraTags=%s", auroraVersion, appVersion, extratags)
-
-	tagsToPush, err := t.ResolveTags(appVersion, pushExtraTags)
+	return docker.NewRegistryClient(docker.RegistryConnectionInfo{
+		Port:        docker.GetPortOrDefault(port),
+		Host:        host,
+		Insecure:    docker.InsecureOrDefault(m.Config),
+		Credentials: credentials,
+	}), nil
+}
 
+// checkTemplateTagsForOverwrite the template tags are protected against overwrite in the output repository of cfg,
+// as in the build
+func (m *retagger) checkTemplateTagsForOverwrite(ctx context.Context, registry docker.Registry, cfg *config.Config,
+	appVersion *runtime.AuroraVersion, variables tagger.TemplateVariables) error {
+	repository := cfg.DockerSpec.OutputRepository
+	existing, err := registry.GetTags(ctx, repository)
 	if err != nil {
-		return err
+		return errors.Wrapf(err, "Failed to get tags of %s", repository)
 	}
+	return process.CheckTemplateTagsForOverwrite(appVersion.Snapshot, existing.Tags, cfg.DockerSpec.TagTemplates,
+		appVersion, variables)
+}
 
-	//We need to pull to make sure we push the newest image.. We should probably do this directly
-	//on the registry when we get v2 registry!:)
-	pull := runtime.DockerImage{
-		Registry:   m.Config.DockerSpec.GetInternalPullRegistryWithoutProtocol(),
-		Repository: m.Config.DockerSpec.OutputRepository,
-		Tag:        m.Config.DockerSpec.RetagWith,
+// logTagMoves log the digest every tag points to before and after the retag
+func (m *retagger) logTagMoves(ctx context.Context, registry docker.Registry, tags []string, digest string) error {
+	repository := m.Config.DockerSpec.OutputRepository
+	existing, err := registry.GetTags(ctx, repository)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to get tags of %s", repository)
+	}
+	exists := make(map[string]bool, len(existing.Tags))
+	for _, tag := range existing.Tags {
+		exists[tag] = true
 	}
 
-	buildConfig := docker.BuildConfig{
-		Image: pull,
+	for _, tag := range tags {
+		shortTag, err := util.FindOutputTagOrHash(tag)
+		if err != nil {
+			return errors.Wrap(err, "Tag failed")
+		}
+		if !exists[shortTag] {
+			logrus.Infof("tag %s: new -> %s", shortTag, digest)
+			continue
+		}
+		current, err := registry.GetImageInfo(ctx, repository, shortTag)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to get image info of %s:%s", repository, shortTag)
+		}
+		if current.Digest == digest {
+			logrus.Infof("tag %s: unchanged %s", shortTag, digest)
+		} else {
+			logrus.Infof("tag %s: %s -> %s", shortTag, current.Digest, digest)
+		}
 	}
+	return nil
+}
 
-	imageLayers, err := m.Builder.Pull(ctx, buildConfig)
-	if err != nil {
-		return errors.Wrapf(err, "Failed to pull image: %v", pull)
+// ImageVersion the version and the extra tags from the environment of an image built by Architect
+func ImageVersion(envMap map[string]string) (*runtime.AuroraVersion, config.PushExtraTags, error) {
+	auroraVersion, ok := envMap[docker.EnvAuroraVersion]
+	if !ok {
+		return nil, config.PushExtraTags{}, errors.Errorf("Failed to extract ENV variable %s from temporary image manifest", docker.EnvAuroraVersion)
 	}
-	sourceTag := pull.GetCompleteDockerTagName()
-	logrus.Debugf("Retagging temporary image, versionTags=%-v", tagsToPush)
-	for _, tag := range tagsToPush {
-		logrus.Infof("Tag image %s with alias %s", sourceTag, tag)
+
+	appVersionString, ok := envMap[docker.EnvAppVersion]
+	if !ok {
+		return nil, config.PushExtraTags{}, errors.Errorf("Failed to extract ENV variable %s from temporary image manifest", docker.EnvAppVersion)
 	}
 
-	err = m.Builder.Push(ctx, imageLayers, tagsToPush)
-	if err != nil {
-		return errors.Wrapf(err, "Failed to push tag %s", tag)
+	givenVersionString, snapshot := envMap[docker.EnvSnapshotVersion]
+	if !snapshot {
+		givenVersionString = appVersionString
 	}
 
-	return nil
+	appVersion := runtime.NewAuroraVersion(appVersionString, snapshot, givenVersionString, runtime.CompleteVersion(auroraVersion))
+
+	extratags, ok := envMap[docker.EnvPushExtraTags]
+	if !ok {
+		return nil, config.PushExtraTags{}, errors.Errorf("Failed to extract ENV variable %s from temporary image manifest", docker.EnvPushExtraTags)
+	}
+	return appVersion, config.ParseExtraTags(extratags), nil
 }
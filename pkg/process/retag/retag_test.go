@@ -0,0 +1,148 @@
+package retag
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	"github.com/skatteetaten/architect/v2/pkg/config"
+	"github.com/skatteetaten/architect/v2/pkg/config/runtime"
+	"github.com/skatteetaten/architect/v2/pkg/docker"
+	docker_mock "github.com/skatteetaten/architect/v2/pkg/docker/mocks"
+	"github.com/skatteetaten/architect/v2/pkg/process/build"
+	"github.com/skatteetaten/architect/v2/pkg/util"
+	"github.com/skatteetaten/architect/v2/pkg/webhook"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestParseSource(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+
+	image, err := ParseSource("temp-123", "aurora/app")
+	assert.NoError(t, err)
+	assert.Equal(t, runtime.DockerImage{Repository: "aurora/app", Tag: "temp-123"}, image)
+
+	image, err = ParseSource("@"+digest, "aurora/app")
+	assert.NoError(t, err)
+	assert.Equal(t, runtime.DockerImage{Repository: "aurora/app", Tag: digest}, image)
+
+	image, err = ParseSource("aurora/app-test@"+digest, "aurora/app")
+	assert.NoError(t, err)
+	assert.Equal(t, runtime.DockerImage{Repository: "aurora/app-test", Tag: digest}, image)
+
+	image, err = ParseSource("registry-test.example.com:5000/aurora/app:temp-123", "aurora/app")
+	assert.NoError(t, err)
+	assert.Equal(t, runtime.DockerImage{Registry: "registry-test.example.com:5000", Repository: "aurora/app", Tag: "temp-123"}, image)
+
+	_, err = ParseSource("registry-test.example.com/aurora/app", "aurora/app")
+	assert.Error(t, err)
+}
+
+func retagConfig(retagWith string) *config.Config {
+	return &config.Config{
+		DockerSpec: config.DockerSpec{
+			OutputRegistry:   "registry.example.com",
+			OutputRepository: "aurora/app",
+			RetagWith:        retagWith,
+		},
+	}
+}
+
+func expectTemporaryImage(registry *docker_mock.MockRegistry) ([]byte, string) {
+	manifest := []byte(`{"schemaVersion": 2, "config": {"digest": "sha256:config"}, "layers": [{"digest": "sha256:layer1"}]}`)
+	digest := util.CalculateDigest(manifest)
+	registry.EXPECT().GetImageInfo(gomock.Any(), "aurora/app", "temp-123").Return(&runtime.ImageInfo{
+		Digest: digest,
+		Environment: map[string]string{
+			docker.EnvAuroraVersion: "1.2.3-b1.2.3-wingnut11-1.0.0",
+			docker.EnvAppVersion:    "1.2.3",
+			docker.EnvPushExtraTags: "patch",
+		},
+	}, nil)
+	return manifest, digest
+}
+
+func TestRetagPushesManifestUnchanged(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	registry := docker_mock.NewMockRegistry(ctrl)
+	manifest, digest := expectTemporaryImage(registry)
+
+	registry.EXPECT().GetTags(gomock.Any(), "aurora/app").
+		Return(&docker.TagsAPIResponse{Tags: []string{"temp-123"}}, nil).AnyTimes()
+	registry.EXPECT().GetRawManifest(gomock.Any(), "aurora/app", digest).Return(manifest, nil)
+	registry.EXPECT().LayerExists(gomock.Any(), "aurora/app", gomock.Any()).Return(true, nil).Times(2)
+	registry.EXPECT().PushManifest(gomock.Any(), manifest, "aurora/app", "1.2.3").Return(nil)
+	registry.EXPECT().PushManifest(gomock.Any(), manifest, "aurora/app", "1.2.3-b1.2.3-wingnut11-1.0.0").Return(nil)
+
+	cfg := retagConfig("temp-123")
+	err := newRetagger(cfg, nil, registry, registry, webhook.NewClient(cfg)).Retag(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestRetagDryRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	registry := docker_mock.NewMockRegistry(ctrl)
+	_, digest := expectTemporaryImage(registry)
+
+	registry.EXPECT().GetTags(gomock.Any(), "aurora/app").
+		Return(&docker.TagsAPIResponse{Tags: []string{"temp-123"}}, nil).AnyTimes()
+
+	cfg := retagConfig("temp-123")
+	cfg.DockerSpec.RetagDigest = digest
+	cfg.DockerSpec.RetagDryRun = true
+	err := newRetagger(cfg, nil, registry, registry, webhook.NewClient(cfg)).Retag(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestRetagVerifiesTestedDigest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	registry := docker_mock.NewMockRegistry(ctrl)
+	_, digest := expectTemporaryImage(registry)
+
+	cfg := retagConfig("temp-123")
+	cfg.DockerSpec.RetagDigest = "sha256:tested"
+	err := newRetagger(cfg, nil, registry, registry, webhook.NewClient(cfg)).Retag(context.Background())
+	assert.EqualError(t, err, "The image aurora/app:temp-123 is "+digest+", not the tested digest sha256:tested")
+}
+
+func TestRetagProtectsTemplateTags(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	registry := docker_mock.NewMockRegistry(ctrl)
+	expectTemporaryImage(registry)
+
+	registry.EXPECT().GetTags(gomock.Any(), "aurora/app").
+		Return(&docker.TagsAPIResponse{Tags: []string{"temp-123", "1.2.3-stable"}}, nil).AnyTimes()
+
+	cfg := retagConfig("temp-123")
+	cfg.DockerSpec.TagTemplates = []string{"{{.Version}}-stable"}
+	err := newRetagger(cfg, nil, registry, registry, webhook.NewClient(cfg)).Retag(context.Background())
+	assert.EqualError(t, err, "Given value for TagWith=1.2.3-stable have already been build, overwrite not allowed")
+}
+
+func TestRetagPushesToTargets(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	registry := docker_mock.NewMockRegistry(ctrl)
+	manifest, digest := expectTemporaryImage(registry)
+
+	registry.EXPECT().GetTags(gomock.Any(), "aurora/app").
+		Return(&docker.TagsAPIResponse{Tags: []string{"temp-123"}}, nil).AnyTimes()
+	registry.EXPECT().GetRawManifest(gomock.Any(), "aurora/app", digest).Return(manifest, nil)
+	registry.EXPECT().LayerExists(gomock.Any(), "aurora/app", gomock.Any()).Return(true, nil).Times(2)
+	registry.EXPECT().PushManifest(gomock.Any(), manifest, "aurora/app", gomock.Any()).Return(nil).Times(2)
+
+	dr := docker_mock.NewMockRegistry(ctrl)
+	dr.EXPECT().GetTags(gomock.Any(), "aurora/app").Return(&docker.TagsAPIResponse{}, nil).AnyTimes()
+	dr.EXPECT().LayerExists(gomock.Any(), "aurora/app", gomock.Any()).Return(true, nil).Times(2)
+	dr.EXPECT().PushManifest(gomock.Any(), manifest, "aurora/app", "1.2.3").Return(nil)
+	dr.EXPECT().PushManifest(gomock.Any(), manifest, "aurora/app", "1.2.3-b1.2.3-wingnut11-1.0.0").Return(nil)
+
+	cfg := retagConfig("temp-123")
+	r := newRetagger(cfg, nil, registry, registry, webhook.NewClient(cfg))
+	r.Targets = []process.Target{{
+		Spec: config.OutputTarget{Registry: "dr.example.com", Repository: "aurora/app",
+			PushExtraTags: config.PushExtraTags{Patch: true}},
+		Registry: dr,
+	}}
+	err := r.Retag(context.Background())
+	assert.NoError(t, err)
+}
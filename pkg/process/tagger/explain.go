@@ -0,0 +1,81 @@
+package tagger
+
+import (
+	"context"
+	"fmt"
+	"github.com/skatteetaten/architect/v2/pkg/config"
+	"github.com/skatteetaten/architect/v2/pkg/config/runtime"
+	"github.com/skatteetaten/architect/v2/pkg/docker"
+)
+
+// TagDecision if a candidate tag is pushed, and why
+type TagDecision struct {
+	Tag    string
+	Pushed bool
+	Reason string
+}
+
+// String e.g. "excluded latest: 2.4.0 exists in repository and is greater than 2.3.5"
+func (m TagDecision) String() string {
+	if m.Pushed {
+		return fmt.Sprintf("pushed %s: %s", m.Tag, m.Reason)
+	}
+	return fmt.Sprintf("excluded %s: %s", m.Tag, m.Reason)
+}
+
+// PushedTags the tags of the decisions that are pushed
+func PushedTags(decisions []TagDecision) []string {
+	tags := make([]string, 0, len(decisions))
+	for _, decision := range decisions {
+		if decision.Pushed {
+			tags = appendUnique(tags, decision.Tag)
+		}
+	}
+	return tags
+}
+
+// DecidedTagResolver resolve the pushed tags of decisions that are already made, without asking the registry again
+type DecidedTagResolver struct {
+	Registry   string
+	Repository string
+	Decisions  []TagDecision
+}
+
+// ResolveTags create the pushed tags of the decisions as registry/repository:tag
+func (m *DecidedTagResolver) ResolveTags(_ context.Context, _ *runtime.AuroraVersion, _ config.PushExtraTags) ([]string, error) {
+	return docker.CreateImageNameFromSpecAndTags(PushedTags(m.Decisions), m.Registry, m.Repository), nil
+}
+
+// ResolveShortTag the pushed tags of the decisions
+func (m *DecidedTagResolver) ResolveShortTag(_ context.Context, _ *runtime.AuroraVersion, _ config.PushExtraTags) ([]string, error) {
+	return PushedTags(m.Decisions), nil
+}
+
+// Explain the decisions for the candidate tags, using the tags in the repository
+func (m *NormalTagResolver) Explain(ctx context.Context, appVersion *runtime.AuroraVersion, pushExtratags config.PushExtraTags) ([]TagDecision, error) {
+	return explainCandidateTags(ctx, appVersion, m.Repository, pushExtratags, m.RegistryClient, m.Scheme)
+}
+
+func pushed(tag string, reason string) TagDecision {
+	return TagDecision{Tag: tag, Pushed: true, Reason: reason}
+}
+
+func excluded(tag string, reason string) TagDecision {
+	return TagDecision{Tag: tag, Pushed: false, Reason: reason}
+}
+
+func notRequested(tag string, extraTag string) TagDecision {
+	return excluded(tag, fmt.Sprintf("%s is not in the extra tags", extraTag))
+}
+
+// floatingDecision a floating tag is excluded when a newer version is in the repository
+func floatingDecision(tag string, newer string, appVersion string, meta string, scope string) TagDecision {
+	var metaNote string
+	if meta != "" {
+		metaNote = fmt.Sprintf(" (metadata mismatch: only comparing +%s tags)", meta)
+	}
+	if newer != "" {
+		return excluded(tag, fmt.Sprintf("%s exists in repository and is greater than %s%s", newer, appVersion, metaNote))
+	}
+	return pushed(tag, fmt.Sprintf("no greater %s in repository%s", scope, metaNote))
+}
@@ -0,0 +1,59 @@
+package tagger
+
+import (
+	"github.com/skatteetaten/architect/v2/pkg/config"
+	"github.com/skatteetaten/architect/v2/pkg/config/runtime"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func explain(t *testing.T, version string, extraTags string, repositoryTags []string) []string {
+	decisions, err := ExplainTags(runtime.NewAuroraVersion(version, false, version, "COMPLETE"),
+		config.ParseExtraTags(extraTags), repositoryTags, nil)
+	assert.NoError(t, err)
+	var lines []string
+	for _, decision := range decisions {
+		lines = append(lines, decision.String())
+	}
+	return lines
+}
+
+func TestExplainTags(t *testing.T) {
+	assert.Equal(t, []string{
+		"excluded latest: 2.4.0 exists in repository and is greater than 2.3.5",
+		"excluded 2: 2.4.0 exists in repository and is greater than 2.3.5",
+		"pushed 2.3: no greater 2.3.x version in repository",
+		"pushed 2.3.5: the version of the deliverable",
+		"pushed COMPLETE: the complete version is always pushed",
+	}, explain(t, "2.3.5", "latest,major,minor,patch", []string{"latest", "2.4.0", "2.3.4", "2.3.9+java17"}))
+
+	assert.Equal(t, []string{
+		"excluded latest: version has metadata +java17, latest is only moved by versions without metadata",
+		"pushed 2+java17: no greater 2.x version in repository (metadata mismatch: only comparing +java17 tags)",
+		"excluded 2.3+java17: minor is not in the extra tags",
+		"pushed 2.3.5+java17: the version of the deliverable",
+		"pushed COMPLETE: the complete version is always pushed",
+	}, explain(t, "2.3.5+java17", "latest,major,patch", []string{"2.4.0", "2.3.4+java17"}))
+
+	assert.Equal(t, []string{
+		"excluded latest: a pre-release never moves latest",
+		"excluded rc: 2.3.0-rc.3 exists in repository and is greater than 2.3.0-rc.2",
+		"excluded 2.3-rc: minor is not in the extra tags",
+		"pushed 2.3.0-rc.2: the version of the deliverable",
+		"pushed COMPLETE: the complete version is always pushed",
+	}, explain(t, "2.3.0-rc.2", "latest,patch", []string{"2.3.0-rc.3"}))
+
+	assert.Equal(t, []string{
+		"pushed COMPLETE: feature-x is not a release version, only the complete version is pushed",
+	}, explain(t, "feature-x", "latest,major,minor,patch", nil))
+}
+
+func TestPushedTags(t *testing.T) {
+	decisions := []TagDecision{
+		{Tag: "latest", Pushed: false},
+		{Tag: "1.2.3", Pushed: true},
+		{Tag: "COMPLETE", Pushed: true},
+		{Tag: "1.2.3", Pushed: true},
+	}
+	assert.Equal(t, []string{"1.2.3", "COMPLETE"}, PushedTags(decisions))
+}
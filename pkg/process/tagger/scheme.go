@@ -0,0 +1,190 @@
+package tagger
+
+import (
+	"github.com/pkg/errors"
+	"github.com/skatteetaten/architect/v2/pkg/config"
+	"github.com/skatteetaten/architect/v2/pkg/config/runtime"
+	"github.com/skatteetaten/architect/v2/pkg/docker"
+	"github.com/skatteetaten/architect/v2/pkg/util"
+	"regexp"
+	"strings"
+)
+
+// VersionScheme how the release versions of an application are tagged
+type VersionScheme interface {
+	// IsRelease check if the version is a release in the scheme. Only releases get floating tags
+	IsRelease(version string) bool
+	// ExplainReleaseTags decide for every candidate tag of a release if it is pushed. A floating tag is excluded
+	// when the repository has a newer release
+	ExplainReleaseTags(version *runtime.AuroraVersion, extraTags config.PushExtraTags, repositoryTags []string) ([]TagDecision, error)
+	// CheckOverwrite fail if the repository has a release that is the same version in the scheme
+	CheckOverwrite(version string, repositoryTags []string) error
+}
+
+// NewVersionScheme the versioning scheme with the given name. Empty is semver
+func NewVersionScheme(name string) (VersionScheme, error) {
+	scheme, err := config.ParseVersioningScheme(name)
+	if err != nil {
+		return nil, err
+	}
+	switch scheme {
+	case config.VersioningSchemeFourPart:
+		return FourPartScheme, nil
+	case config.VersioningSchemeCalver:
+		return CalverScheme, nil
+	default:
+		return SemverScheme, nil
+	}
+}
+
+// SemverScheme X.Y.Z with optional build metadata. Floating tags are latest, X and X.Y
+var SemverScheme VersionScheme = semverScheme{}
+
+// FourPartScheme W.X.Y.Z. Floating tags are latest, W, W.X and W.X.Y. The patch extra tag pushes both W.X.Y and
+// W.X.Y.Z, as it pushes X.Y.Z in semver
+var FourPartScheme VersionScheme = &segmentedScheme{
+	release:  regexp.MustCompile(`^[0-9]+\.[0-9]+\.[0-9]+\.[0-9]+$`),
+	floating: []floatingLevel{majorLevel, minorLevel, patchLevel},
+}
+
+// CalverScheme YYYY.MM.MICRO, e.g. 2024.10.3. Floating tags are latest, the year and the month, e.g. 2024 and 2024.10
+var CalverScheme VersionScheme = &segmentedScheme{
+	release:  regexp.MustCompile(`^[0-9]{4}\.[0-9]{1,2}\.[0-9]+$`),
+	floating: []floatingLevel{majorLevel, minorLevel},
+}
+
+type semverScheme struct{}
+
+func (semverScheme) IsRelease(version string) bool {
+	return util.IsFullSemanticVersion(version)
+}
+
+func (semverScheme) ExplainReleaseTags(version *runtime.AuroraVersion, extraTags config.PushExtraTags, repositoryTags []string) ([]TagDecision, error) {
+	decisions, err := explainSemanticVersionTags(version, extraTags, repositoryTags)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error in FilterVersionTags, app_version=%v, repositoryTags=%v",
+			version, repositoryTags)
+	}
+	return decisions, nil
+}
+
+func (semverScheme) CheckOverwrite(version string, repositoryTags []string) error {
+	for _, tag := range repositoryTags {
+		if strings.EqualFold(docker.ConvertTagToRepositoryTag(tag), version) {
+			return errors.Errorf("There is already a build with tag %s, overwrite not allowed", version)
+		}
+	}
+	return nil
+}
+
+// segmentedScheme numeric segments separated by dots. The floating tag of level i is the first i+1 segments
+type segmentedScheme struct {
+	release  *regexp.Regexp
+	floating []floatingLevel
+}
+
+// floatingLevel the extra tag that pushes a floating tag
+type floatingLevel struct {
+	extraTag  string
+	requested func(config.PushExtraTags) bool
+}
+
+var (
+	majorLevel = floatingLevel{extraTag: "major", requested: func(t config.PushExtraTags) bool { return t.Major }}
+	minorLevel = floatingLevel{extraTag: "minor", requested: func(t config.PushExtraTags) bool { return t.Minor }}
+	patchLevel = floatingLevel{extraTag: "patch", requested: func(t config.PushExtraTags) bool { return t.Patch }}
+)
+
+func (m *segmentedScheme) IsRelease(version string) bool {
+	return m.release.MatchString(version)
+}
+
+func (m *segmentedScheme) ExplainReleaseTags(version *runtime.AuroraVersion, extraTags config.PushExtraTags, repositoryTags []string) ([]TagDecision, error) {
+	appVersion := string(version.GetAppVersion())
+	if !m.IsRelease(appVersion) {
+		return nil, errors.Errorf("%s is not a release version", appVersion)
+	}
+	segments := normalizeSegments(strings.Split(appVersion, "."))
+
+	decisions := make([]TagDecision, 0, len(m.floating)+3)
+	if extraTags.Latest {
+		decisions = append(decisions, floatingDecision("latest", m.superseded(segments, 0, repositoryTags), appVersion, "", "release"))
+	} else {
+		decisions = append(decisions, notRequested("latest", "latest"))
+	}
+	for i, f := range m.floating {
+		tag := strings.Join(segments[:i+1], ".")
+		if f.requested(extraTags) {
+			decisions = append(decisions, floatingDecision(tag, m.superseded(segments, i+1, repositoryTags), appVersion, "",
+				tag+".x release"))
+		} else {
+			decisions = append(decisions, notRequested(tag, f.extraTag))
+		}
+	}
+	if extraTags.Patch {
+		decisions = append(decisions, pushed(appVersion, "the version of the deliverable"))
+	} else {
+		decisions = append(decisions, notRequested(appVersion, "patch"))
+	}
+	return append(decisions, pushed(version.GetCompleteVersion(), "the complete version is always pushed")), nil
+}
+
+func (m *segmentedScheme) CheckOverwrite(version string, repositoryTags []string) error {
+	if !m.IsRelease(version) {
+		return nil
+	}
+	segments := strings.Split(version, ".")
+	for _, tag := range repositoryTags {
+		if m.IsRelease(tag) && compareSegments(strings.Split(tag, "."), segments) == 0 {
+			return errors.Errorf("There is already a build of %s with tag %s, overwrite not allowed", version, tag)
+		}
+	}
+	return nil
+}
+
+// superseded a newer release in the repository with the same first segments, empty if there is none
+func (m *segmentedScheme) superseded(segments []string, prefixLength int, repositoryTags []string) string {
+	for _, tag := range repositoryTags {
+		if !m.IsRelease(tag) {
+			continue
+		}
+		tagSegments := strings.Split(tag, ".")
+		if compareSegments(tagSegments[:prefixLength], segments[:prefixLength]) != 0 {
+			continue
+		}
+		if compareSegments(tagSegments, segments) > 0 {
+			return tag
+		}
+	}
+	return ""
+}
+
+// normalizeSegments strip the leading zeros, so the floating tags of 2024.09.1 and 2024.9.2 are the same
+func normalizeSegments(segments []string) []string {
+	normalized := make([]string, len(segments))
+	for i, segment := range segments {
+		normalized[i] = strings.TrimLeft(segment, "0")
+		if normalized[i] == "" {
+			normalized[i] = "0"
+		}
+	}
+	return normalized
+}
+
+// compareSegments compare numeric segments without parsing them, so 2024.01 equals 2024.1
+func compareSegments(a []string, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		x := strings.TrimLeft(a[i], "0")
+		y := strings.TrimLeft(b[i], "0")
+		if len(x) != len(y) {
+			if len(x) < len(y) {
+				return -1
+			}
+			return 1
+		}
+		if c := strings.Compare(x, y); c != 0 {
+			return c
+		}
+	}
+	return len(a) - len(b)
+}
@@ -0,0 +1,99 @@
+package tagger
+
+import (
+	"github.com/skatteetaten/architect/v2/pkg/config"
+	"github.com/skatteetaten/architect/v2/pkg/config/runtime"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewVersionScheme(t *testing.T) {
+	scheme, err := NewVersionScheme("")
+	assert.NoError(t, err)
+	assert.Equal(t, SemverScheme, scheme)
+
+	scheme, err = NewVersionScheme("CalVer")
+	assert.NoError(t, err)
+	assert.Equal(t, CalverScheme, scheme)
+
+	_, err = NewVersionScheme("romver")
+	assert.Error(t, err)
+}
+
+func TestFilterTagsWithFourPartScheme(t *testing.T) {
+	r := repositoryTester{
+		t: t,
+		tagResolver: &NormalTagResolver{
+			Registry:   "testregistry",
+			Repository: "aurora/test",
+			RegistryClient: &RegistryMock{
+				tagsFromRegistry: []string{"latest", "1", "1.2", "1.2.3", "1.2.3.4", "1.2.4.0", "1.3.0.1", "2.0.0", "abc"},
+			},
+			Scheme: FourPartScheme,
+		},
+	}
+
+	r.testTagFiltering(
+		runtime.NewAuroraVersion("1.2.3.5", false, "1.2.3.5", "COMPLETE"),
+		[]string{"1.2.3", "1.2.3.5", "COMPLETE"})
+
+	r.testTagFiltering(
+		runtime.NewAuroraVersion("1.3.0.2", false, "1.3.0.2", "COMPLETE"),
+		[]string{"latest", "1", "1.3", "1.3.0", "1.3.0.2", "COMPLETE"})
+
+	// A semver release is not a release in the four-part scheme
+	r.testTagFiltering(
+		runtime.NewAuroraVersion("2.0.1", false, "2.0.1", "COMPLETE"),
+		[]string{"COMPLETE"})
+}
+
+func TestFilterTagsWithCalverScheme(t *testing.T) {
+	r := repositoryTester{
+		t: t,
+		tagResolver: &NormalTagResolver{
+			Registry:   "testregistry",
+			Repository: "aurora/test",
+			RegistryClient: &RegistryMock{
+				tagsFromRegistry: []string{"latest", "2024", "2024.10", "2024.10.3", "2024.9.12", "2023.12.1", "12345.1.1"},
+			},
+			Scheme: CalverScheme,
+		},
+	}
+
+	r.testTagFiltering(
+		runtime.NewAuroraVersion("2024.10.4", false, "2024.10.4", "COMPLETE"),
+		[]string{"latest", "2024", "2024.10", "2024.10.4", "COMPLETE"})
+
+	r.testTagFiltering(
+		runtime.NewAuroraVersion("2024.09.13", false, "2024.09.13", "COMPLETE"),
+		[]string{"2024.09.13", "2024.9", "COMPLETE"})
+
+	r.testTagFiltering(
+		runtime.NewAuroraVersion("2023.12.2", false, "2023.12.2", "COMPLETE"),
+		[]string{"2023", "2023.12", "2023.12.2", "COMPLETE"})
+}
+
+func TestCheckOverwrite(t *testing.T) {
+	assert.EqualError(t, CalverScheme.CheckOverwrite("2024.09.13", []string{"latest", "2024.9", "2024.9.13"}),
+		"There is already a build of 2024.09.13 with tag 2024.9.13, overwrite not allowed")
+	assert.NoError(t, CalverScheme.CheckOverwrite("2024.09.14", []string{"2024.9", "2024.9.13"}))
+	assert.Error(t, FourPartScheme.CheckOverwrite("1.2.3.4", []string{"1.2.3.04"}))
+	assert.NoError(t, FourPartScheme.CheckOverwrite("1.2.3", []string{"1.2.3"}))
+	assert.EqualError(t, SemverScheme.CheckOverwrite("1.2.3", []string{"1.2.3"}),
+		"There is already a build with tag 1.2.3, overwrite not allowed")
+}
+
+func TestFourPartSchemePatchExtraTag(t *testing.T) {
+	version := runtime.NewAuroraVersion("1.2.3.4", false, "1.2.3.4", "COMPLETE")
+
+	decisions, err := FourPartScheme.ExplainReleaseTags(version,
+		config.PushExtraTags{Latest: true, Major: true, Minor: true, Patch: false}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"latest", "1", "1.2", "COMPLETE"}, PushedTags(decisions))
+	assert.Contains(t, decisions, notRequested("1.2.3", "patch"))
+	assert.Contains(t, decisions, notRequested("1.2.3.4", "patch"))
+
+	decisions, err = FourPartScheme.ExplainReleaseTags(version, config.PushExtraTags{Patch: true}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1.2.3", "1.2.3.4", "COMPLETE"}, PushedTags(decisions))
+}
@@ -10,15 +10,13 @@ import (
 	"github.com/skatteetaten/architect/v2/pkg/config/runtime"
 	"github.com/skatteetaten/architect/v2/pkg/docker"
 	"github.com/skatteetaten/architect/v2/pkg/util"
-	"regexp"
-	"sort"
 	"strings"
 )
 
 // TagResolver interface
 type TagResolver interface {
-	ResolveTags(appVersion *runtime.AuroraVersion, pushExtratags config.PushExtraTags) ([]string, error)
-	ResolveShortTag(appVersion *runtime.AuroraVersion, pushExtratags config.PushExtraTags) ([]string, error)
+	ResolveTags(ctx context.Context, appVersion *runtime.AuroraVersion, pushExtratags config.PushExtraTags) ([]string, error)
+	ResolveShortTag(ctx context.Context, appVersion *runtime.AuroraVersion, pushExtratags config.PushExtraTags) ([]string, error)
 }
 
 // SingleTagResolver resolve single tag´
@@ -29,12 +27,12 @@ type SingleTagResolver struct {
 }
 
 // ResolveTags create single tag of sbomFormat registry/repository:tag
-func (m *SingleTagResolver) ResolveTags(_ *runtime.AuroraVersion, _ config.PushExtraTags) ([]string, error) {
+func (m *SingleTagResolver) ResolveTags(_ context.Context, _ *runtime.AuroraVersion, _ config.PushExtraTags) ([]string, error) {
 	return docker.CreateImageNameFromSpecAndTags([]string{m.Tag}, m.Registry, m.Repository), nil
 }
 
 // ResolveShortTag resolve short tag e.g latest
-func (m *SingleTagResolver) ResolveShortTag(_ *runtime.AuroraVersion, _ config.PushExtraTags) ([]string, error) {
+func (m *SingleTagResolver) ResolveShortTag(_ context.Context, _ *runtime.AuroraVersion, _ config.PushExtraTags) ([]string, error) {
 	return []string{m.Tag}, nil
 }
 
@@ -43,128 +41,162 @@ type NormalTagResolver struct {
 	Registry       string
 	Repository     string
 	RegistryClient docker.Registry
+	// Scheme the versioning scheme of the application, semver if nil
+	Scheme VersionScheme
 }
 
 // ResolveTags create tags from runtime.AuroraVersion
-func (m *NormalTagResolver) ResolveTags(appVersion *runtime.AuroraVersion, pushExtratags config.PushExtraTags) ([]string, error) {
-	tags, err := findCandidateTags(appVersion, m.Repository, pushExtratags, m.RegistryClient)
+func (m *NormalTagResolver) ResolveTags(ctx context.Context, appVersion *runtime.AuroraVersion, pushExtratags config.PushExtraTags) ([]string, error) {
+	tags, err := findCandidateTags(ctx, appVersion, m.Repository, pushExtratags, m.RegistryClient, m.Scheme)
 	if err != nil {
 		return nil, err
 	}
-	tags = append(tags)
 	return docker.CreateImageNameFromSpecAndTags(tags, m.Registry, m.Repository), nil
 }
 
 // ResolveShortTag create short tags from runtime.AuroraVersion
-func (m *NormalTagResolver) ResolveShortTag(appVersion *runtime.AuroraVersion, pushExtratags config.PushExtraTags) ([]string, error) {
-	tags, err := findCandidateTags(appVersion, m.Repository, pushExtratags, m.RegistryClient)
+func (m *NormalTagResolver) ResolveShortTag(ctx context.Context, appVersion *runtime.AuroraVersion, pushExtratags config.PushExtraTags) ([]string, error) {
+	tags, err := findCandidateTags(ctx, appVersion, m.Repository, pushExtratags, m.RegistryClient, m.Scheme)
 	if err != nil {
 		return nil, err
 	}
 	return tags, nil
 }
 
-func findCandidateTags(appVersion *runtime.AuroraVersion, outputRepository string, pushExtraTags config.PushExtraTags, provider docker.Registry) ([]string, error) {
+func findCandidateTags(ctx context.Context, appVersion *runtime.AuroraVersion, outputRepository string,
+	pushExtraTags config.PushExtraTags, provider docker.Registry, scheme VersionScheme) ([]string, error) {
+	decisions, err := explainCandidateTags(ctx, appVersion, outputRepository, pushExtraTags, provider, scheme)
+	if err != nil {
+		return nil, err
+	}
+	return PushedTags(decisions), nil
+}
+
+func explainCandidateTags(ctx context.Context, appVersion *runtime.AuroraVersion, outputRepository string,
+	pushExtraTags config.PushExtraTags, provider docker.Registry, scheme VersionScheme) ([]TagDecision, error) {
 	logrus.Debugf("Version is:%s, meta is:%s", appVersion.GetCompleteVersion(), util.GetVersionMetadata(string(appVersion.GetAppVersion())))
 
-	if appVersion.IsSemanticReleaseVersion() {
-		tagsInRepo, err := provider.GetTags(context.Background(), outputRepository)
+	var repositoryTags []string
+	if needsRepositoryTags(appVersion, scheme) {
+		tagsInRepo, err := provider.GetTags(ctx, outputRepository)
 		if err != nil {
 			return nil, errors.Wrapf(err, "Error in ResolveShortTag, repository=%s", outputRepository)
 		}
 		logrus.Debug("Tags in repository ", tagsInRepo.Tags)
-
-		logrus.Debugf("%s is semantic version. Filter tags", string(appVersion.GetAppVersion()))
-		candidateTags, err := getSemanticVersionTags(appVersion, pushExtraTags)
-		if err != nil {
-			return nil, errors.Wrapf(err, "Error in FilterVersionTags, app_version=%v, repositoryTags=%v",
-				appVersion, tagsInRepo.Tags)
-		}
-		filteredTags, err := filterTagsFromRepository(appVersion, candidateTags, tagsInRepo.Tags)
-		if err != nil {
-			return nil, errors.Wrapf(err, "Error in FilterVersionTags, app_version=%v, "+
-				"candidateTags=%v, repositoryTags=%v", appVersion, candidateTags, tagsInRepo.Tags)
-		}
-		return filteredTags, nil
+		repositoryTags = tagsInRepo.Tags
 	}
+	return ExplainTags(appVersion, pushExtraTags, repositoryTags, scheme)
+}
 
-	logrus.Debug("Is not semantic version. Append only complete version and given version")
-	var versions []string
+// needsRepositoryTags only the floating tags of releases and pre-releases depend on the tags in the repository
+func needsRepositoryTags(appVersion *runtime.AuroraVersion, scheme VersionScheme) bool {
+	if scheme == nil {
+		scheme = SemverScheme
+	}
 	if appVersion.Snapshot {
-		if appVersion.GetUniqueSnapshotVersion() != "" {
-			versions = append(versions, appVersion.GetUniqueSnapshotVersion())
-		}
-		versions = append(versions, appVersion.GetGivenVersion())
-		versions = append(versions, appVersion.GetCompleteSnapshotVersion())
-	} else {
-		versions = append(versions, appVersion.GetCompleteVersion())
+		return false
 	}
-	return versions, nil
+	return scheme.IsRelease(string(appVersion.GetAppVersion())) || appVersion.IsPreReleaseVersion()
 }
 
-func filterTagsFromRepository(version *runtime.AuroraVersion, candidateTags []string,
-	repositoryTags []string) ([]string, error) {
-
-	var excludeMinor, excludeMajor, excludeLatest = true, true, true
-
-	minorTagName, err := getMinor(string(version.GetAppVersion()), true)
-	if err != nil {
-		return nil, err
+// ExplainTags decide for every candidate tag of the version if it is pushed, given the tags in the repository
+func ExplainTags(appVersion *runtime.AuroraVersion, pushExtraTags config.PushExtraTags, repositoryTags []string,
+	scheme VersionScheme) ([]TagDecision, error) {
+	if scheme == nil {
+		scheme = SemverScheme
 	}
-	excludeMinor, err = tagCompare("> "+string(version.GetAppVersion())+", < "+minorTagName, repositoryTags, version)
-	if err != nil {
-		return nil, err
+	version := string(appVersion.GetAppVersion())
+
+	if !appVersion.Snapshot && scheme.IsRelease(version) {
+		logrus.Debugf("%s is a release version. Filter tags", version)
+		return scheme.ExplainReleaseTags(appVersion, pushExtraTags, repositoryTags)
 	}
-	logrus.Debugf("Minor tag name: %s. Exclude: %t", minorTagName, excludeMinor)
 
-	majorTagName, err := getMajor(string(version.GetAppVersion()), true)
-	if err != nil {
-		return nil, err
+	if appVersion.IsPreReleaseVersion() {
+		logrus.Debugf("%s is a pre-release. Add channel tags", version)
+		return explainPreReleaseTags(appVersion, pushExtraTags, repositoryTags)
 	}
 
-	excludeMajor, err = tagCompare("> "+string(version.GetAppVersion())+", < "+majorTagName, repositoryTags, version)
-	if err != nil {
-		return nil, err
+	logrus.Debug("Is not semantic version. Append only complete version and given version")
+	var decisions []TagDecision
+	if appVersion.Snapshot {
+		if appVersion.GetUniqueSnapshotVersion() != "" {
+			decisions = append(decisions, pushed(appVersion.GetUniqueSnapshotVersion(), "unique snapshot tag of the deliverable"))
+		}
+		decisions = append(decisions, pushed(appVersion.GetGivenVersion(), "snapshot version, always moved to the newest build"))
+		decisions = append(decisions, pushed(appVersion.GetCompleteSnapshotVersion(), "complete snapshot version"))
+	} else {
+		decisions = append(decisions, pushed(appVersion.GetCompleteVersion(),
+			fmt.Sprintf("%s is not a release version, only the complete version is pushed", version)))
 	}
-	logrus.Debugf("Major tag name: %s. Exclude: %t", majorTagName, excludeMajor)
+	return decisions, nil
+}
 
-	// If meta in tag we exlude latest.
-	if !util.IsSemanticVersionWithMeta(string(version.GetAppVersion())) {
-		excludeLatest, err = tagCompare("> "+string(version.GetAppVersion()), repositoryTags, version)
+func explainSemanticVersionTags(version *runtime.AuroraVersion, extraTags config.PushExtraTags,
+	repositoryTags []string) ([]TagDecision, error) {
+	appVersion := string(version.GetAppVersion())
+	meta := util.GetVersionMetadata(appVersion)
+	decisions := make([]TagDecision, 0, 5)
+
+	if !extraTags.Latest {
+		decisions = append(decisions, notRequested("latest", "latest"))
+	} else if util.IsSemanticVersionWithMeta(appVersion) {
+		// If meta in tag we exlude latest.
+		decisions = append(decisions, excluded("latest",
+			fmt.Sprintf("version has metadata +%s, latest is only moved by versions without metadata", meta)))
+	} else {
+		newer, err := tagCompare("> "+appVersion, repositoryTags, version)
 		if err != nil {
 			return nil, err
 		}
+		decisions = append(decisions, floatingDecision("latest", newer, appVersion, meta, "version"))
 	}
-	logrus.Debugf("Exclude latest: %t", excludeLatest)
-
-	versions := make([]string, 0, 10)
-	sort.StringSlice(candidateTags).Sort()
-	for _, tag := range candidateTags {
-		logrus.Debugf("Looping and checking candidate tag %s", tag)
-		if strings.EqualFold(strings.TrimSpace(tag), "latest") {
-			if !excludeLatest {
-				versions = append(versions, tag)
-			}
-		} else if isMinor(tag) {
-			if !excludeMinor {
-				versions = append(versions, tag)
-			}
-		} else if isMajor(tag) {
-			if !excludeMajor {
-				versions = append(versions, tag)
-			}
-		} else {
-			versions = append(versions, tag)
+	logrus.Debugf("Latest: %+v", decisions[len(decisions)-1])
+
+	floating := []struct {
+		requested bool
+		extraTag  string
+		tagName   func(string, bool) (string, error)
+	}{
+		{requested: extraTags.Major, extraTag: "major", tagName: getMajor},
+		{requested: extraTags.Minor, extraTag: "minor", tagName: getMinor},
+	}
+	for _, f := range floating {
+		tag, err := f.tagName(appVersion, false)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to get %s version", f.extraTag)
 		}
+		if !f.requested {
+			decisions = append(decisions, notRequested(tag, f.extraTag))
+			continue
+		}
+		next, err := f.tagName(appVersion, true)
+		if err != nil {
+			return nil, err
+		}
+		newer, err := tagCompare("> "+appVersion+", < "+next, repositoryTags, version)
+		if err != nil {
+			return nil, err
+		}
+		decisions = append(decisions, floatingDecision(tag, newer, appVersion, meta, util.GetVersionWithoutMetadata(tag)+".x version"))
+		logrus.Debugf("%s tag name: %s. Exclude: %t", f.extraTag, tag, newer != "")
+	}
+
+	if extraTags.Patch {
+		decisions = append(decisions, pushed(appVersion, "the version of the deliverable"))
+	} else {
+		decisions = append(decisions, notRequested(appVersion, "patch"))
 	}
-	return versions, nil
+	decisions = append(decisions, pushed(version.GetCompleteVersion(), "the complete version is always pushed"))
+	return decisions, nil
 }
 
-func tagCompare(versionConstraint string, tags []string, version *runtime.AuroraVersion) (bool, error) {
+// tagCompare the first semantic version in the repository that matches the constraint, empty if there is none
+func tagCompare(versionConstraint string, tags []string, version *runtime.AuroraVersion) (string, error) {
 	c, err := extVersion.NewConstraint(versionConstraint)
 
 	if err != nil {
-		return false, errors.Wrapf(err, "Could not create version constraint %s", versionConstraint)
+		return "", errors.Wrapf(err, "Could not create version constraint %s", versionConstraint)
 	}
 	for _, tag := range tags {
 		if !util.IsSemanticVersion(tag) {
@@ -177,45 +209,82 @@ func tagCompare(versionConstraint string, tags []string, version *runtime.Aurora
 		v, err := extVersion.NewVersion(tag)
 
 		if err != nil {
-			return false, errors.Wrapf(err, "Error parsing version %s", tag)
+			return "", errors.Wrapf(err, "Error parsing version %s", tag)
 		}
 
 		if c.Check(v) {
-			return true, nil
+			return tag, nil
 		}
 	}
 
-	return false, nil
+	return "", nil
 }
 
-func getSemanticVersionTags(version *runtime.AuroraVersion, extraTags config.PushExtraTags) ([]string, error) {
-	versions := make([]string, 0, 10)
+// explainPreReleaseTags never move latest, major or minor. The channel tag, e.g. rc, and the minor channel tag,
+// e.g. 2.3-rc, are only moved when no release or pre-release in the same channel has higher precedence
+func explainPreReleaseTags(version *runtime.AuroraVersion, extraTags config.PushExtraTags, repositoryTags []string) ([]TagDecision, error) {
+	appVersion := string(version.GetAppVersion())
+	channel := util.GetPreReleaseChannel(appVersion)
+	minor := util.GetPreReleaseMinor(appVersion)
 
+	decisions := make([]TagDecision, 0, 5)
 	if extraTags.Latest {
-		versions = append(versions, "latest")
-	}
-
-	if extraTags.Major {
-		majorVersion, err := getMajor(string(version.GetAppVersion()), false)
+		decisions = append(decisions, excluded("latest", "a pre-release never moves latest"))
+		newer, err := preReleaseSuperseded(appVersion, "", repositoryTags)
 		if err != nil {
-			return nil, errors.Wrap(err, "Failed to get major version")
+			return nil, err
 		}
-		versions = append(versions, majorVersion)
+		decisions = append(decisions, floatingDecision(channel, newer, appVersion, "", channel+" pre-release or release"))
+	} else {
+		decisions = append(decisions, notRequested(channel, "latest"))
 	}
 	if extraTags.Minor {
-		minorVersion, err := getMinor(string(version.GetAppVersion()), false)
+		newer, err := preReleaseSuperseded(appVersion, minor+".", repositoryTags)
 		if err != nil {
-			return nil, errors.Wrap(err, "Failed to get minor version")
+			return nil, err
 		}
-		versions = append(versions, minorVersion)
+		decisions = append(decisions, floatingDecision(minor+"-"+channel, newer, appVersion, "",
+			minor+".x-"+channel+" pre-release or "+minor+".x release"))
+	} else {
+		decisions = append(decisions, notRequested(minor+"-"+channel, "minor"))
 	}
 	if extraTags.Patch {
-		versions = append(versions, string(version.GetAppVersion()))
+		decisions = append(decisions, pushed(appVersion, "the version of the deliverable"))
+	} else {
+		decisions = append(decisions, notRequested(appVersion, "patch"))
 	}
+	decisions = append(decisions, pushed(version.GetCompleteVersion(), "the complete version is always pushed"))
+	return decisions, nil
+}
 
-	versions = append(versions, version.GetCompleteVersion())
-
-	return versions, nil
+// preReleaseSuperseded a release, or a pre-release in the same channel, with the prefix and higher precedence.
+// Empty if there is none
+func preReleaseSuperseded(appVersion string, prefix string, repositoryTags []string) (string, error) {
+	current, err := extVersion.NewVersion(appVersion)
+	if err != nil {
+		return "", errors.Wrapf(err, "Error parsing version %s", appVersion)
+	}
+	channel := util.GetPreReleaseChannel(appVersion)
+	for _, tag := range repositoryTags {
+		if util.IsPreReleaseVersion(tag) {
+			if util.GetPreReleaseChannel(tag) != channel {
+				continue
+			}
+		} else if !util.IsFullSemanticVersion(tag) || util.GetVersionMetadata(tag) != "" {
+			continue
+		}
+		if !strings.HasPrefix(tag, prefix) {
+			continue
+		}
+		v, err := extVersion.NewVersion(tag)
+		if err != nil {
+			return "", errors.Wrapf(err, "Error parsing version %s", tag)
+		}
+		if v.GreaterThan(current) {
+			return tag, nil
+		}
+	}
+	return "", nil
 }
 
 func getMajor(version string, increment bool) (string, error) {
@@ -250,21 +319,3 @@ func getMinor(version string, increment bool) (string, error) {
 	}
 	return fmt.Sprintf("%d.%d", buildVersion.Segments()[0], versionMinor), nil
 }
-
-func isMinor(version string) bool {
-	var validStr = regexp.MustCompile(`^[0-9]+.[0-9]+$`)
-	versionOnly := util.GetVersionWithoutMetadata(version)
-	if validStr.MatchString(versionOnly) {
-		return true
-	}
-	return false
-}
-
-func isMajor(version string) bool {
-	var validStr = regexp.MustCompile(`^[0-9]+$`)
-	versionOnly := util.GetVersionWithoutMetadata(version)
-	if validStr.MatchString(versionOnly) {
-		return true
-	}
-	return false
-}
@@ -80,7 +80,7 @@ var tagger = NormalTagResolver{
 
 func TestTagInfoRelease(t *testing.T) {
 	appVersion := runtime.NewAuroraVersion(AppVersion, false, AppVersion, AuroraVersion)
-	tags, err := tagger.ResolveTags(appVersion, config.ParseExtraTags(CfgPushExtraTags))
+	tags, err := tagger.ResolveTags(context.Background(), appVersion, config.ParseExtraTags(CfgPushExtraTags))
 	if err != nil {
 		t.Fatalf("Failed to create target VersionInfo %v", err)
 	}
@@ -92,7 +92,7 @@ func TestTagInfoRelease(t *testing.T) {
 
 func TestTagInfoSnapshot(t *testing.T) {
 	appVersion := runtime.NewAuroraVersion(SnapshotAppVersion, true, SnapshotGivenVersion, SnapshotAuroraVersion)
-	tags, err := tagger.ResolveTags(appVersion, config.ParseExtraTags(CfgPushExtraTags))
+	tags, err := tagger.ResolveTags(context.Background(), appVersion, config.ParseExtraTags(CfgPushExtraTags))
 	if err != nil {
 		t.Fatalf("Failed to create target VersionInfo %v", err)
 	}
@@ -202,13 +202,50 @@ func TestFilterTagsWithWeirdTagsInRepo(t *testing.T) {
 		[]string{"1.106.1", "1.106", "1", "latest", "COMPLETE"})
 }
 
+var taggerWithPreReleases = NormalTagResolver{
+	Registry:   "testregistry",
+	Repository: "aurora/test",
+	RegistryClient: &RegistryMock{
+		tagsFromRegistry: []string{"latest", "2", "2.2", "2.2.0", "rc", "2.3-rc", "2.3.0-rc.2", "2.3.0-beta.1",
+			"2.4.0-beta.1", "2.2.1+meta"},
+	},
+}
+
+func TestFilterTagsWithPreRelease(t *testing.T) {
+	r := repositoryTester{
+		t:           t,
+		tagResolver: &taggerWithPreReleases,
+	}
+
+	r.testTagFiltering(
+		runtime.NewAuroraVersion("2.3.0-rc.10", false, "2.3.0-rc.10", "COMPLETE"),
+		[]string{"rc", "2.3-rc", "2.3.0-rc.10", "COMPLETE"})
+
+	r.testTagFiltering(
+		runtime.NewAuroraVersion("2.3.0-rc.1", false, "2.3.0-rc.1", "COMPLETE"),
+		[]string{"2.3.0-rc.1", "COMPLETE"})
+
+	r.testTagFiltering(
+		runtime.NewAuroraVersion("2.3.0-beta.2", false, "2.3.0-beta.2", "COMPLETE"),
+		[]string{"2.3-beta", "2.3.0-beta.2", "COMPLETE"})
+
+	r.testTagFiltering(
+		runtime.NewAuroraVersion("2.2.0-rc.3", false, "2.2.0-rc.3", "COMPLETE"),
+		[]string{"2.2.0-rc.3", "COMPLETE"})
+
+	// Pre-releases in the repository never hold back the release tags
+	r.testTagFiltering(
+		runtime.NewAuroraVersion("2.3.0", false, "2.3.0", "COMPLETE"),
+		[]string{"latest", "2", "2.3", "2.3.0", "COMPLETE"})
+}
+
 type repositoryTester struct {
 	t           *testing.T
 	tagResolver TagResolver
 }
 
 func (m repositoryTester) testTagFiltering(auroraVersion *runtime.AuroraVersion, excpectedFilteringResult []string) {
-	tagsToPush, err := m.tagResolver.ResolveTags(auroraVersion, config.ParseExtraTags("latest major minor patch"))
+	tagsToPush, err := m.tagResolver.ResolveTags(context.Background(), auroraVersion, config.ParseExtraTags("latest major minor patch"))
 	assert.NoError(m.t, err)
 	verifyTagListContent(m.t, tagsToPush, excpectedFilteringResult)
 }
@@ -267,7 +304,7 @@ func convertTagsToRepositoryTags(tags []string) []string {
 func (m repositoryTester) testTagFilteringAppend(appversion string, completeversion string, excpectedFilteringResult []string, tags []string) []string {
 	tagsAppend = append(tagsAppend, tags...)
 	auroraVersion := runtime.NewAuroraVersion(appversion, false, appversion, runtime.CompleteVersion(completeversion))
-	tagsToPush, err := m.tagResolver.ResolveTags(auroraVersion, config.ParseExtraTags("latest major minor patch"))
+	tagsToPush, err := m.tagResolver.ResolveTags(context.Background(), auroraVersion, config.ParseExtraTags("latest major minor patch"))
 	assert.NoError(m.t, err)
 	verifyTagListContent(m.t, tagsToPush, convertTagsToRepositoryTags(excpectedFilteringResult))
 	tagsOnly := make([]string, 0, len(tagsToPush))
@@ -303,6 +340,10 @@ func (registry *RegistryMock) PushManifest(ctx context.Context, manifest []byte,
 	return nil
 }
 
+func (registry *RegistryMock) DeleteManifest(ctx context.Context, repository string, digest string) error {
+	return nil
+}
+
 func (registry *RegistryMockAppend) GetManifest(ctx context.Context, repository string, digest string) (*docker.ManifestV2, error) {
 	return nil, nil
 }
@@ -325,6 +366,10 @@ func (registry *RegistryMockAppend) PushManifest(ctx context.Context, manifest [
 	return nil
 }
 
+func (registry *RegistryMockAppend) DeleteManifest(ctx context.Context, repository string, digest string) error {
+	return nil
+}
+
 func (registry *RegistryMock) GetContainerConfig(ctx context.Context, repository string, digest string) (*docker.ContainerConfig, error) {
 	return nil, nil
 }
@@ -333,6 +378,14 @@ func (registry *RegistryMockAppend) GetContainerConfig(ctx context.Context, repo
 	return nil, nil
 }
 
+func (registry *RegistryMock) GetRawManifest(ctx context.Context, repository string, reference string) ([]byte, error) {
+	return nil, nil
+}
+
+func (registry *RegistryMockAppend) GetRawManifest(ctx context.Context, repository string, reference string) ([]byte, error) {
+	return nil, nil
+}
+
 func (registry *RegistryMockAppend) GetTags(ctx context.Context, repository string) (*docker.TagsAPIResponse, error) {
 	return &docker.TagsAPIResponse{Name: "jalla", Tags: docker.ConvertRepositoryTagsToTags(tagsAppend)}, nil
 }
@@ -0,0 +1,165 @@
+package tagger
+
+import (
+	"bytes"
+	"context"
+	"github.com/pkg/errors"
+	"github.com/skatteetaten/architect/v2/pkg/config"
+	"github.com/skatteetaten/architect/v2/pkg/config/runtime"
+	"github.com/skatteetaten/architect/v2/pkg/docker"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// The tag grammar of the distribution spec
+var validTag = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+
+var invalidTagCharacters = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// TemplateVariables the values available in tag templates
+type TemplateVariables struct {
+	// Version the version of the deliverable, e.g. 1.2.3 or SNAPSHOT-feature-20220101.120000-1
+	Version         string
+	GivenVersion    string
+	CompleteVersion string
+	Snapshot        bool
+	GroupID         string
+	ArtifactID      string
+	BaseImage       string
+	BaseVersion     string
+	GitCommit       string
+	GitCommitShort  string
+	// Date and Timestamp of the build in UTC, e.g. 20220131 and 20220131T120000Z
+	Date      string
+	Timestamp string
+	env       map[string]string
+}
+
+// NewTemplateVariables the variables of the build config. The version is added when the tags are resolved
+func NewTemplateVariables(cfg *config.Config, buildTime time.Time) TemplateVariables {
+	gitCommitShort := cfg.SourceSpec.Revision
+	if len(gitCommitShort) > 7 {
+		gitCommitShort = gitCommitShort[:7]
+	}
+	buildTime = buildTime.UTC()
+	return TemplateVariables{
+		GroupID:        cfg.ApplicationSpec.MavenGav.GroupID,
+		ArtifactID:     cfg.ApplicationSpec.MavenGav.ArtifactID,
+		BaseImage:      cfg.ApplicationSpec.BaseImageSpec.BaseImage,
+		BaseVersion:    cfg.ApplicationSpec.BaseImageSpec.BaseVersion,
+		GitCommit:      cfg.SourceSpec.Revision,
+		GitCommitShort: gitCommitShort,
+		Date:           buildTime.Format("20060102"),
+		Timestamp:      buildTime.Format("20060102T150405Z"),
+		env:            cfg.BuildEnv,
+	}
+}
+
+func (v TemplateVariables) withVersion(appVersion *runtime.AuroraVersion) TemplateVariables {
+	v.Version = string(appVersion.GetAppVersion())
+	v.GivenVersion = appVersion.GetGivenVersion()
+	v.CompleteVersion = appVersion.GetCompleteVersion()
+	v.Snapshot = appVersion.Snapshot
+	return v
+}
+
+// The build config wins over the environment of the builder pod
+func (v TemplateVariables) lookupEnv(name string) string {
+	if value, ok := v.env[name]; ok {
+		return value
+	}
+	return os.Getenv(name)
+}
+
+// ValidateTag check the tag against the registry tag grammar
+func ValidateTag(tag string) error {
+	if !validTag.MatchString(tag) {
+		return errors.Errorf("%q is not a valid tag. A tag is at most 128 characters of [A-Za-z0-9_.-], "+
+			"and can not start with . or -", tag)
+	}
+	return nil
+}
+
+// RenderTagTemplates render and validate the tag templates
+func RenderTagTemplates(templates []string, appVersion *runtime.AuroraVersion, variables TemplateVariables) ([]string, error) {
+	variables = variables.withVersion(appVersion)
+	functions := template.FuncMap{
+		"env": variables.lookupEnv,
+		// sanitize replace the characters that are not allowed in a tag, e.g. the / in a branch name
+		"sanitize": func(value string) string {
+			return strings.Trim(invalidTagCharacters.ReplaceAllString(value, "-"), ".-")
+		},
+		"lower": strings.ToLower,
+	}
+
+	var tags []string
+	for _, text := range templates {
+		tmpl, err := template.New("tag").Option("missingkey=error").Funcs(functions).Parse(text)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid tag template %s", text)
+		}
+		var buffer bytes.Buffer
+		if err := tmpl.Execute(&buffer, variables); err != nil {
+			return nil, errors.Wrapf(err, "Unable to render tag template %s", text)
+		}
+		tag := strings.TrimSpace(buffer.String())
+		if err := ValidateTag(tag); err != nil {
+			return nil, errors.Wrapf(err, "Tag template %s", text)
+		}
+		tags = appendUnique(tags, tag)
+	}
+	return tags, nil
+}
+
+// TemplateTagResolver add the tags rendered from templates to the tags of another resolver
+type TemplateTagResolver struct {
+	Registry   string
+	Repository string
+	Templates  []string
+	Variables  TemplateVariables
+	Resolver   TagResolver
+}
+
+// ResolveTags create the complete tag names
+func (m *TemplateTagResolver) ResolveTags(ctx context.Context, appVersion *runtime.AuroraVersion, pushExtratags config.PushExtraTags) ([]string, error) {
+	tags, err := m.Resolver.ResolveTags(ctx, appVersion, pushExtratags)
+	if err != nil {
+		return nil, err
+	}
+	templateTags, err := RenderTagTemplates(m.Templates, appVersion, m.Variables)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range docker.CreateImageNameFromSpecAndTags(templateTags, m.Registry, m.Repository) {
+		tags = appendUnique(tags, tag)
+	}
+	return tags, nil
+}
+
+// ResolveShortTag create the tags without registry and repository
+func (m *TemplateTagResolver) ResolveShortTag(ctx context.Context, appVersion *runtime.AuroraVersion, pushExtratags config.PushExtraTags) ([]string, error) {
+	tags, err := m.Resolver.ResolveShortTag(ctx, appVersion, pushExtratags)
+	if err != nil {
+		return nil, err
+	}
+	templateTags, err := RenderTagTemplates(m.Templates, appVersion, m.Variables)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range templateTags {
+		tags = appendUnique(tags, tag)
+	}
+	return tags, nil
+}
+
+func appendUnique(tags []string, tag string) []string {
+	for _, existing := range tags {
+		if existing == tag {
+			return tags
+		}
+	}
+	return append(tags, tag)
+}
@@ -0,0 +1,89 @@
+package tagger
+
+import (
+	"context"
+	"github.com/skatteetaten/architect/v2/pkg/config"
+	"github.com/skatteetaten/architect/v2/pkg/config/runtime"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func templateConfig() *config.Config {
+	return &config.Config{
+		ApplicationSpec: config.ApplicationSpec{
+			MavenGav: config.MavenGav{
+				GroupID:    "no.skatteetaten.aurora",
+				ArtifactID: "architect",
+			},
+			BaseImageSpec: config.DockerBaseImageSpec{
+				BaseImage:   "aurora/wingnut11",
+				BaseVersion: "1.2.3",
+			},
+		},
+		SourceSpec: config.SourceSpec{Revision: "4f2a9c1d0e8b7a6f5e4d3c2b1a0f9e8d7c6b5a49"},
+		BuildEnv:   map[string]string{"BRANCH": "feature/AOS-123"},
+	}
+}
+
+func TestRenderTagTemplates(t *testing.T) {
+	variables := NewTemplateVariables(templateConfig(), time.Date(2022, 1, 31, 12, 0, 0, 0, time.UTC))
+	appVersion := runtime.NewAuroraVersion(AppVersion, false, AppVersion, AuroraVersion)
+
+	tags, err := RenderTagTemplates([]string{
+		"{{.Version}}-{{.GitCommitShort}}",
+		"{{.Date}}",
+		"{{.BaseVersion}}-{{.Version}}",
+		`{{env "BRANCH" | sanitize | lower}}`,
+		"{{.Version}}-{{.GitCommitShort}}",
+	}, appVersion, variables)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2.4.5-4f2a9c1", "20220131", "1.2.3-2.4.5", "feature-aos-123"}, tags)
+}
+
+func TestRenderTagTemplatesRejectsInvalidTags(t *testing.T) {
+	variables := NewTemplateVariables(templateConfig(), time.Now())
+	appVersion := runtime.NewAuroraVersion(AppVersion, false, AppVersion, AuroraVersion)
+
+	_, err := RenderTagTemplates([]string{"{{.BaseImage}}-{{.BaseVersion}}"}, appVersion, variables)
+	assert.Error(t, err, "aurora/wingnut11 contains a /")
+
+	_, err = RenderTagTemplates([]string{"{{.Branch}}"}, appVersion, variables)
+	assert.Error(t, err)
+
+	_, err = RenderTagTemplates([]string{`{{env "UNSET_IN_TEST"}}`}, appVersion, variables)
+	assert.Error(t, err, "empty tag")
+}
+
+func TestValidateTag(t *testing.T) {
+	assert.NoError(t, ValidateTag("1.2.3-b1.11.0-oracle8-1.2.3"))
+	assert.NoError(t, ValidateTag("_internal"))
+	assert.Error(t, ValidateTag(".hidden"))
+	assert.Error(t, ValidateTag("-dash"))
+	assert.Error(t, ValidateTag("has space"))
+	assert.Error(t, ValidateTag(string(make([]byte, 129))))
+}
+
+func TestTemplateTagResolver(t *testing.T) {
+	resolver := &TemplateTagResolver{
+		Registry:   "testregistry",
+		Repository: "aurora/test",
+		Templates:  []string{"{{.Version}}-{{.GitCommitShort}}"},
+		Variables:  NewTemplateVariables(templateConfig(), time.Now()),
+		Resolver: &SingleTagResolver{
+			Registry:   "testregistry",
+			Repository: "aurora/test",
+			Tag:        "temporary",
+		},
+	}
+	appVersion := runtime.NewAuroraVersion(AppVersion, false, AppVersion, AuroraVersion)
+
+	tags, err := resolver.ResolveTags(context.Background(), appVersion, config.PushExtraTags{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"testregistry/aurora/test:temporary", "testregistry/aurora/test:2.4.5-4f2a9c1"}, tags)
+
+	shortTags, err := resolver.ResolveShortTag(context.Background(), appVersion, config.PushExtraTags{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"temporary", "2.4.5-4f2a9c1"}, shortTags)
+}
@@ -0,0 +1,56 @@
+package results
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"github.com/skatteetaten/architect/v2/pkg/config"
+	"github.com/skatteetaten/architect/v2/pkg/webhook"
+	"os"
+	"path/filepath"
+)
+
+// Tekton results
+const (
+	ImageDigest = "IMAGE_DIGEST"
+	ImageURL    = "IMAGE_URL"
+)
+
+// NewNotifier write the digest and the digest reference of the pushed or retagged image to the Tekton results
+// directory of the build config, and pass every event on to the notifier. Nothing is written when the directory does
+// not exist. The results are the image in the output repository, so the events of the output targets are not written.
+// In a base image matrix the results are the image of the first base image
+func NewNotifier(notifier webhook.Notifier, cfg *config.Config) webhook.Notifier {
+	return &resultsNotifier{
+		notifier:   notifier,
+		resultsDir: cfg.ResultsDir,
+		image:      cfg.DockerSpec.OutputRegistry + "/" + cfg.DockerSpec.OutputRepository,
+	}
+}
+
+type resultsNotifier struct {
+	notifier   webhook.Notifier
+	resultsDir string
+	image      string
+	written    bool
+}
+
+// Notify write the results, and send the event
+func (r *resultsNotifier) Notify(ctx context.Context, event webhook.Event) {
+	if (event.Type == webhook.Pushed || event.Type == webhook.Retagged) && event.Registry == "" && event.Digest != "" && !r.written {
+		r.write(ImageDigest, event.Digest)
+		r.write(ImageURL, r.image+"@"+event.Digest)
+		r.written = true
+	}
+	r.notifier.Notify(ctx, event)
+}
+
+// Write failures are logged. They never fail the build
+func (r *resultsNotifier) write(name string, value string) {
+	if _, err := os.Stat(r.resultsDir); err != nil {
+		logrus.Debugf("No results directory %s. Not writing %s", r.resultsDir, name)
+		return
+	}
+	if err := os.WriteFile(filepath.Join(r.resultsDir, name), []byte(value), 0644); err != nil {
+		logrus.Warnf("Unable to write result %s: %v", name, err)
+	}
+}
@@ -0,0 +1,111 @@
+package results
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/skatteetaten/architect/v2/pkg/config"
+	"github.com/skatteetaten/architect/v2/pkg/webhook"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingNotifier struct {
+	events []webhook.Event
+}
+
+func (r *recordingNotifier) Notify(ctx context.Context, event webhook.Event) {
+	r.events = append(r.events, event)
+}
+
+func resultsConfig(dir string) *config.Config {
+	return &config.Config{
+		ResultsDir: dir,
+		DockerSpec: config.DockerSpec{
+			OutputRegistry:   "registry.example.com",
+			OutputRepository: "aurora/minarch",
+		},
+	}
+}
+
+func TestNotifierWritesPushedImage(t *testing.T) {
+	dir := t.TempDir()
+	next := &recordingNotifier{}
+	notifier := NewNotifier(next, resultsConfig(dir))
+
+	notifier.Notify(context.Background(), webhook.Event{Type: webhook.Started})
+	_, err := os.Stat(filepath.Join(dir, ImageDigest))
+	assert.True(t, os.IsNotExist(err))
+
+	notifier.Notify(context.Background(), webhook.Event{
+		Type:   webhook.Pushed,
+		Tags:   []string{"registry.example.com/aurora/minarch:1.0.0", "registry.example.com/aurora/minarch:1.0"},
+		Digest: "sha256:abc",
+	})
+
+	digest, err := os.ReadFile(filepath.Join(dir, ImageDigest))
+	assert.NoError(t, err)
+	assert.Equal(t, "sha256:abc", string(digest))
+	url, err := os.ReadFile(filepath.Join(dir, ImageURL))
+	assert.NoError(t, err)
+	assert.Equal(t, "registry.example.com/aurora/minarch@sha256:abc", string(url))
+	assert.Len(t, next.events, 2)
+
+	// The image of the next base image in a matrix does not overwrite the results
+	notifier.Notify(context.Background(), webhook.Event{
+		Type:   webhook.Pushed,
+		Tags:   []string{"registry.example.com/aurora/minarch:1.0.0-jdk17"},
+		Digest: "sha256:def",
+	})
+	digest, err = os.ReadFile(filepath.Join(dir, ImageDigest))
+	assert.NoError(t, err)
+	assert.Equal(t, "sha256:abc", string(digest))
+}
+
+func TestNotifierWithoutResultsDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "missing")
+	next := &recordingNotifier{}
+
+	NewNotifier(next, resultsConfig(dir)).Notify(context.Background(), webhook.Event{Type: webhook.Retagged, Tags: []string{"a:1"}, Digest: "sha256:abc"})
+
+	_, err := os.Stat(dir)
+	assert.True(t, os.IsNotExist(err))
+	assert.Len(t, next.events, 1)
+}
+
+func TestNotifierIgnoresOutputTargets(t *testing.T) {
+	dir := t.TempDir()
+
+	NewNotifier(&recordingNotifier{}, resultsConfig(dir)).Notify(context.Background(), webhook.Event{
+		Type:     webhook.Pushed,
+		Registry: "dr-registry.example.com",
+		Tags:     []string{"dr-registry.example.com/aurora/minarch:1.0.0"},
+		Digest:   "sha256:abc",
+	})
+
+	_, err := os.Stat(filepath.Join(dir, ImageDigest))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestNotifierKeepsResultsAfterFailure(t *testing.T) {
+	dir := t.TempDir()
+	next := &recordingNotifier{}
+	notifier := NewNotifier(next, resultsConfig(dir))
+
+	notifier.Notify(context.Background(), webhook.Event{Type: webhook.Pushed, Digest: "sha256:abc"})
+	notifier.Notify(context.Background(), webhook.Event{
+		Type:          webhook.Failed,
+		Digest:        "sha256:def",
+		ErrorCategory: "push",
+		Error:         "push to a target failed",
+	})
+
+	digest, err := os.ReadFile(filepath.Join(dir, ImageDigest))
+	assert.NoError(t, err)
+	assert.Equal(t, "sha256:abc", string(digest))
+	url, err := os.ReadFile(filepath.Join(dir, ImageURL))
+	assert.NoError(t, err)
+	assert.Equal(t, "registry.example.com/aurora/minarch@sha256:abc", string(url))
+	assert.Len(t, next.events, 2)
+}
@@ -5,6 +5,7 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -38,42 +39,42 @@ func (m *MockSporingslogger) EXPECT() *MockSporingsloggerMockRecorder {
 }
 
 // ScanImage mocks base method.
-func (m *MockSporingslogger) ScanImage(buildFolder string) ([]sporingslogger.Dependency, error) {
+func (m *MockSporingslogger) ScanImage(ctx context.Context, buildFolder string) ([]sporingslogger.Dependency, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ScanImage", buildFolder)
+	ret := m.ctrl.Call(m, "ScanImage", ctx, buildFolder)
 	ret0, _ := ret[0].([]sporingslogger.Dependency)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ScanImage indicates an expected call of ScanImage.
-func (mr *MockSporingsloggerMockRecorder) ScanImage(buildFolder interface{}) *gomock.Call {
+func (mr *MockSporingsloggerMockRecorder) ScanImage(ctx, buildFolder interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScanImage", reflect.TypeOf((*MockSporingslogger)(nil).ScanImage), buildFolder)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScanImage", reflect.TypeOf((*MockSporingslogger)(nil).ScanImage), ctx, buildFolder)
 }
 
 // SendBaseImageMetadata mocks base method.
-func (m *MockSporingslogger) SendBaseImageMetadata(application config.ApplicationSpec, imageInfo *runtime.ImageInfo, containerConfig *docker.ContainerConfig) {
+func (m *MockSporingslogger) SendBaseImageMetadata(ctx context.Context, application config.ApplicationSpec, imageInfo *runtime.ImageInfo, containerConfig *docker.ContainerConfig) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "SendBaseImageMetadata", application, imageInfo, containerConfig)
+	m.ctrl.Call(m, "SendBaseImageMetadata", ctx, application, imageInfo, containerConfig)
 }
 
 // SendBaseImageMetadata indicates an expected call of SendBaseImageMetadata.
-func (mr *MockSporingsloggerMockRecorder) SendBaseImageMetadata(application, imageInfo, containerConfig interface{}) *gomock.Call {
+func (mr *MockSporingsloggerMockRecorder) SendBaseImageMetadata(ctx, application, imageInfo, containerConfig interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendBaseImageMetadata", reflect.TypeOf((*MockSporingslogger)(nil).SendBaseImageMetadata), application, imageInfo, containerConfig)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendBaseImageMetadata", reflect.TypeOf((*MockSporingslogger)(nil).SendBaseImageMetadata), ctx, application, imageInfo, containerConfig)
 }
 
 // SendImageMetadata mocks base method.
-func (m *MockSporingslogger) SendImageMetadata(data interface{}) error {
+func (m *MockSporingslogger) SendImageMetadata(ctx context.Context, data interface{}) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SendImageMetadata", data)
+	ret := m.ctrl.Call(m, "SendImageMetadata", ctx, data)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SendImageMetadata indicates an expected call of SendImageMetadata.
-func (mr *MockSporingsloggerMockRecorder) SendImageMetadata(data interface{}) *gomock.Call {
+func (mr *MockSporingsloggerMockRecorder) SendImageMetadata(ctx, data interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendImageMetadata", reflect.TypeOf((*MockSporingslogger)(nil).SendImageMetadata), data)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendImageMetadata", reflect.TypeOf((*MockSporingslogger)(nil).SendImageMetadata), ctx, data)
 }
@@ -1,6 +1,7 @@
 package sporingslogger_test
 
 import (
+	"context"
 	"github.com/skatteetaten/architect/v2/pkg/config"
 	"github.com/skatteetaten/architect/v2/pkg/config/runtime"
 	"github.com/skatteetaten/architect/v2/pkg/java/prepare"
@@ -12,7 +13,7 @@ import (
 
 func TestScanImage(t *testing.T) {
 	prepper := prepare.Prepper()
-	buildConfig, err := prepper(&config.Config{
+	buildConfig, err := prepper(context.Background(), &config.Config{
 		ApplicationType: "",
 		ApplicationSpec: config.ApplicationSpec{},
 		DockerSpec: config.DockerSpec{
@@ -44,7 +45,7 @@ func TestScanImage(t *testing.T) {
 	)
 	assert.NoError(t, err)
 	var traceClient = sporingslogger.NewClient("url")
-	dependencies, err := traceClient.ScanImage(buildConfig.BuildFolder)
+	dependencies, err := traceClient.ScanImage(context.Background(), buildConfig.BuildFolder)
 	assert.NoError(t, err)
 	var dep1 = sporingslogger.Dependency{Purl: "pkg:maven/org.slf4j/slf4j-api@1.7.6",
 		DependencyId:      "93824bab1fb3d6e0",
@@ -21,9 +21,9 @@ import (
 
 // Sporingslogger interface
 type Sporingslogger interface {
-	SendImageMetadata(data interface{}) error
-	SendBaseImageMetadata(application config.ApplicationSpec, imageInfo *runtime.ImageInfo, containerConfig *docker.ContainerConfig)
-	ScanImage(buildFolder string) ([]Dependency, error)
+	SendImageMetadata(ctx context.Context, data interface{}) error
+	SendBaseImageMetadata(ctx context.Context, application config.ApplicationSpec, imageInfo *runtime.ImageInfo, containerConfig *docker.ContainerConfig)
+	ScanImage(ctx context.Context, buildFolder string) ([]Dependency, error)
 }
 
 // NewClient create new Sporingslogger client
@@ -40,8 +40,7 @@ type sporingsloggerClient struct {
 }
 
 // SendImageMetadata send image metadata to sporingslogger
-func (sporingsloggerClient *sporingsloggerClient) SendImageMetadata(data interface{}) error {
-	ctx := context.Background()
+func (sporingsloggerClient *sporingsloggerClient) SendImageMetadata(ctx context.Context, data interface{}) error {
 	timeoutIn := time.Now().Add(5 * time.Second)
 	ctx, cancelFunc := context.WithDeadline(ctx, timeoutIn)
 	defer cancelFunc()
@@ -51,8 +50,6 @@ func (sporingsloggerClient *sporingsloggerClient) SendImageMetadata(data interfa
 		return errors.Wrapf(err, "Unable to unmarshal image metadata")
 	}
 	return sporingsloggerClient.send(ctx, string(d))
-
-	return nil
 }
 
 func (sporingsloggerClient *sporingsloggerClient) send(ctx context.Context, jsonStr string) error {
@@ -67,22 +64,22 @@ func (sporingsloggerClient *sporingsloggerClient) send(ctx context.Context, json
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
-	defer resp.Body.Close()
-
 	if err != nil {
 		logrus.Warnf("Request failed: %s", err)
 		return errors.Wrapf(err, "Request failed")
 	}
+	defer resp.Body.Close()
+
 	if resp.StatusCode >= 300 {
 		errorBody, _ := httputil.DumpResponse(resp, true)
 		logrus.Warnf("Request failed, error from Sporingslogger:  %s", errorBody)
-		return errors.Wrapf(err, "Request failed %d", resp.StatusCode)
+		return errors.Errorf("Request failed %d", resp.StatusCode)
 	}
 	return nil
 }
 
 // SendBaseImageMetadata send baseimage metadata to sporingslogger
-func (sporingsloggerClient *sporingsloggerClient) SendBaseImageMetadata(application config.ApplicationSpec, imageInfo *runtime.ImageInfo, containerConfig *docker.ContainerConfig) {
+func (sporingsloggerClient *sporingsloggerClient) SendBaseImageMetadata(ctx context.Context, application config.ApplicationSpec, imageInfo *runtime.ImageInfo, containerConfig *docker.ContainerConfig) {
 	payload := BaseImage{
 		Type:        "baseImage",
 		Name:        application.BaseImageSpec.BaseImage,
@@ -91,12 +88,18 @@ func (sporingsloggerClient *sporingsloggerClient) SendBaseImageMetadata(applicat
 		ImageConfig: containerConfig,
 	}
 	logrus.Debugf("Pushing sporingslogger data %v", payload)
-	sporingsloggerClient.SendImageMetadata(payload)
+	if err := sporingsloggerClient.SendImageMetadata(ctx, payload); err != nil {
+		logrus.Warnf("Unable to send base image metadata: %v", err)
+	}
 
 }
 
 // use syft to discover packages + distro only
-func (sporingsloggerClient *sporingsloggerClient) ScanImage(buildFolder string) ([]Dependency, error) {
+func (sporingsloggerClient *sporingsloggerClient) ScanImage(ctx context.Context, buildFolder string) ([]Dependency, error) {
+	// syft can not be cancelled, so check before the scan is started
+	if err := ctx.Err(); err != nil {
+		return nil, errors.Wrap(err, "Image scan cancelled")
+	}
 
 	imageUrl := "dir:" + buildFolder
 	input, err := source.ParseInput(imageUrl, "", false)
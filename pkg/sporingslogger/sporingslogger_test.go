@@ -1,6 +1,7 @@
 package sporingslogger
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/stretchr/testify/assert"
 	"net/http"
@@ -22,7 +23,7 @@ func TestCreateAppReg(t *testing.T) {
 
 	tags := make(map[string]string)
 	tags["a"] = "c"
-	client2.SendImageMetadata(DeployableImage{
+	client2.SendImageMetadata(context.Background(), DeployableImage{
 		Type:       "deployableImage",
 		Digest:     "manifest.Digest",
 		Name:       "buildConfig.DockerRepository",
@@ -0,0 +1,225 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const instrumentationScope = "github.com/skatteetaten/architect"
+
+// OTLP span status codes and the cumulative aggregation temporality
+const (
+	otlpStatusOk              = 1
+	otlpStatusError           = 2
+	otlpSpanKindInternal      = 1
+	otlpTemporalityCumulative = 2
+)
+
+// OTLPExporter send spans and histograms with OTLP/HTTP and JSON encoding to a collector
+type OTLPExporter struct {
+	Endpoint string
+	Client   *http.Client
+}
+
+// NewOTLPExporter create an exporter for a collector endpoint, e.g. http://localhost:4318
+func NewOTLPExporter(endpoint string) *OTLPExporter {
+	return &OTLPExporter{
+		Endpoint: strings.TrimSuffix(endpoint, "/"),
+		Client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type otlpKeyValue struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpHistogramDataPoint struct {
+	Attributes        []otlpKeyValue `json:"attributes"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	TimeUnixNano      string         `json:"timeUnixNano"`
+	Count             string         `json:"count"`
+	Sum               float64        `json:"sum"`
+	BucketCounts      []string       `json:"bucketCounts"`
+	ExplicitBounds    []float64      `json:"explicitBounds"`
+}
+
+type otlpMetric struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Unit        string `json:"unit"`
+	Histogram   struct {
+		DataPoints             []otlpHistogramDataPoint `json:"dataPoints"`
+		AggregationTemporality int                      `json:"aggregationTemporality"`
+	} `json:"histogram"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpMetrics struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+// Export send the spans to /v1/traces and the histograms to /v1/metrics
+func (e *OTLPExporter) Export(ctx context.Context, recorder *Recorder) error {
+	if err := e.post(ctx, "/v1/traces", recorder.otlpTraces()); err != nil {
+		return err
+	}
+	return e.post(ctx, "/v1/metrics", recorder.otlpMetrics())
+}
+
+func (e *OTLPExporter) post(ctx context.Context, path string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "Unable to marshal OTLP payload")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.Endpoint+path, bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrap(err, "Unable to create OTLP request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.Client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to export to %s", e.Endpoint+path)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("Export to %s failed with status %d: %s", e.Endpoint+path, resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+func (r *Recorder) otlpResource() otlpResource {
+	attributes := []Attribute{Attr("service.name", "architect")}
+	return otlpResource{Attributes: otlpAttributes(append(attributes, r.resource...))}
+}
+
+func (r *Recorder) otlpTraces() otlpTraces {
+	var spans []otlpSpan
+	for _, span := range r.Spans() {
+		status := otlpStatus{Code: otlpStatusOk}
+		if span.Err != nil {
+			status = otlpStatus{Code: otlpStatusError, Message: span.Err.Error()}
+		}
+		spans = append(spans, otlpSpan{
+			TraceID:           span.TraceID,
+			SpanID:            span.SpanID,
+			ParentSpanID:      span.ParentID,
+			Name:              span.Name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: unixNano(span.Start),
+			EndTimeUnixNano:   unixNano(span.End),
+			Attributes:        otlpAttributes(span.Attributes),
+			Status:            status,
+		})
+	}
+
+	return otlpTraces{ResourceSpans: []otlpResourceSpans{{
+		Resource:   r.otlpResource(),
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: instrumentationScope}, Spans: spans}},
+	}}}
+}
+
+func (r *Recorder) otlpMetrics() otlpMetrics {
+	metric := otlpMetric{
+		Name:        stageDurationMetric,
+		Description: "Duration of the Architect build stages",
+		Unit:        "s",
+	}
+	metric.Histogram.AggregationTemporality = otlpTemporalityCumulative
+	now := unixNano(time.Now())
+	for _, histogram := range r.Histograms() {
+		// OTLP bucket counts are per bucket, not cumulative like in Prometheus
+		bucketCounts := make([]string, len(histogram.BucketCounts))
+		var previous uint64
+		for i, cumulative := range histogram.BucketCounts {
+			bucketCounts[i] = strconv.FormatUint(cumulative-previous, 10)
+			previous = cumulative
+		}
+		metric.Histogram.DataPoints = append(metric.Histogram.DataPoints, otlpHistogramDataPoint{
+			Attributes:        otlpAttributes([]Attribute{Attr("stage", histogram.Stage)}),
+			StartTimeUnixNano: unixNano(r.startTime),
+			TimeUnixNano:      now,
+			Count:             strconv.FormatUint(histogram.Count, 10),
+			Sum:               histogram.Sum,
+			BucketCounts:      bucketCounts,
+			ExplicitBounds:    DurationBuckets,
+		})
+	}
+
+	return otlpMetrics{ResourceMetrics: []otlpResourceMetrics{{
+		Resource:     r.otlpResource(),
+		ScopeMetrics: []otlpScopeMetrics{{Scope: otlpScope{Name: instrumentationScope}, Metrics: []otlpMetric{metric}}},
+	}}}
+}
+
+func otlpAttributes(attributes []Attribute) []otlpKeyValue {
+	values := make([]otlpKeyValue, len(attributes))
+	for i, attribute := range attributes {
+		values[i].Key = attribute.Key
+		values[i].Value.StringValue = attribute.Value
+	}
+	return values
+}
+
+func unixNano(t time.Time) string {
+	return fmt.Sprintf("%d", t.UnixNano())
+}
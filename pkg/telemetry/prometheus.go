@@ -0,0 +1,73 @@
+package telemetry
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/pkg/errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const stageDurationMetric = "architect_stage_duration_seconds"
+
+// WritePrometheusTextfile write the stage histograms in the Prometheus text format. The file is replaced
+// atomically, since the node-exporter textfile collector may read it at any time
+func (r *Recorder) WritePrometheusTextfile(path string) error {
+	var buffer bytes.Buffer
+	r.writePrometheus(&buffer)
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return errors.Wrapf(err, "Unable to create metrics file in %s", filepath.Dir(path))
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buffer.Bytes()); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "Unable to write metrics")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "Unable to write metrics")
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return errors.Wrap(err, "Unable to write metrics")
+	}
+	return errors.Wrapf(os.Rename(tmp.Name(), path), "Unable to write metrics file %s", path)
+}
+
+func (r *Recorder) writePrometheus(buffer *bytes.Buffer) {
+	fmt.Fprintf(buffer, "# HELP %s Duration of the Architect build stages\n", stageDurationMetric)
+	fmt.Fprintf(buffer, "# TYPE %s histogram\n", stageDurationMetric)
+	for _, histogram := range r.Histograms() {
+		labels := r.prometheusLabels(histogram.Stage)
+		for i, bound := range DurationBuckets {
+			fmt.Fprintf(buffer, "%s_bucket{%s,le=\"%s\"} %d\n", stageDurationMetric, labels,
+				strconv.FormatFloat(bound, 'g', -1, 64), histogram.BucketCounts[i])
+		}
+		fmt.Fprintf(buffer, "%s_bucket{%s,le=\"+Inf\"} %d\n", stageDurationMetric, labels, histogram.Count)
+		fmt.Fprintf(buffer, "%s_sum{%s} %s\n", stageDurationMetric, labels, strconv.FormatFloat(histogram.Sum, 'f', -1, 64))
+		fmt.Fprintf(buffer, "%s_count{%s} %d\n", stageDurationMetric, labels, histogram.Count)
+	}
+}
+
+func (r *Recorder) prometheusLabels(stage string) string {
+	labels := []string{fmt.Sprintf("stage=\"%s\"", escapeLabelValue(stage))}
+	for _, attribute := range r.resource {
+		labels = append(labels, fmt.Sprintf("%s=\"%s\"", prometheusName(attribute.Key), escapeLabelValue(attribute.Value)))
+	}
+	return strings.Join(labels, ",")
+}
+
+func prometheusName(key string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, key)
+}
+
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
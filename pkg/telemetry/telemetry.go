@@ -0,0 +1,180 @@
+package telemetry
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DurationBuckets upper bounds in seconds of the stage duration histograms
+var DurationBuckets = []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600}
+
+// Attribute a key value pair describing a span
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// Attr create an Attribute
+func Attr(key string, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span a timed build stage
+type Span struct {
+	recorder   *Recorder
+	TraceID    string
+	SpanID     string
+	ParentID   string
+	Name       string
+	Attributes []Attribute
+	Start      time.Time
+	End        time.Time
+	Err        error
+}
+
+// Finish end the span. A non nil error marks the span and the stage as failed
+func (s *Span) Finish(err error) {
+	if s == nil || s.recorder == nil {
+		return
+	}
+	s.End = time.Now()
+	s.Err = err
+	s.recorder.finish(s)
+}
+
+// Histogram stage durations with the buckets in DurationBuckets
+type Histogram struct {
+	Stage        string
+	BucketCounts []uint64
+	Count        uint64
+	Sum          float64
+}
+
+func (h *Histogram) observe(seconds float64) {
+	for i, bound := range DurationBuckets {
+		if seconds <= bound {
+			h.BucketCounts[i]++
+		}
+	}
+	// The last bucket is +Inf
+	h.BucketCounts[len(DurationBuckets)]++
+	h.Count++
+	h.Sum += seconds
+}
+
+// Recorder collect spans and stage histograms for one build
+type Recorder struct {
+	mutex      sync.Mutex
+	traceID    string
+	startTime  time.Time
+	resource   []Attribute
+	spans      []*Span
+	histograms map[string]*Histogram
+}
+
+// NewRecorder create a Recorder. The resource attributes describe the build, e.g. the application type
+func NewRecorder(resource ...Attribute) *Recorder {
+	return &Recorder{
+		traceID:    randomID(16),
+		startTime:  time.Now(),
+		resource:   resource,
+		histograms: make(map[string]*Histogram),
+	}
+}
+
+type spanKey struct{}
+
+var (
+	defaultMutex    sync.RWMutex
+	defaultRecorder *Recorder
+)
+
+// SetDefault set the recorder used by StartSpan. A nil recorder disables recording
+func SetDefault(recorder *Recorder) {
+	defaultMutex.Lock()
+	defer defaultMutex.Unlock()
+	defaultRecorder = recorder
+}
+
+// Default the recorder used by StartSpan. Nil if recording is disabled
+func Default() *Recorder {
+	defaultMutex.RLock()
+	defer defaultMutex.RUnlock()
+	return defaultRecorder
+}
+
+// StartSpan start a stage with the default recorder. The span is a child of the span in ctx.
+// When recording is disabled the returned span is a no-op
+func StartSpan(ctx context.Context, name string, attributes ...Attribute) (context.Context, *Span) {
+	recorder := Default()
+	if recorder == nil {
+		return ctx, &Span{}
+	}
+	return recorder.StartSpan(ctx, name, attributes...)
+}
+
+// StartSpan start a stage. The span is a child of the span in ctx
+func (r *Recorder) StartSpan(ctx context.Context, name string, attributes ...Attribute) (context.Context, *Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	span := &Span{
+		recorder:   r,
+		TraceID:    r.traceID,
+		SpanID:     randomID(8),
+		Name:       name,
+		Attributes: attributes,
+		Start:      time.Now(),
+	}
+	if parent, ok := ctx.Value(spanKey{}).(*Span); ok && parent.recorder == r {
+		span.ParentID = parent.SpanID
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (r *Recorder) finish(span *Span) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.spans = append(r.spans, span)
+	histogram, ok := r.histograms[span.Name]
+	if !ok {
+		histogram = &Histogram{Stage: span.Name, BucketCounts: make([]uint64, len(DurationBuckets)+1)}
+		r.histograms[span.Name] = histogram
+	}
+	histogram.observe(span.End.Sub(span.Start).Seconds())
+}
+
+// Spans the finished spans in the order they finished
+func (r *Recorder) Spans() []*Span {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	spans := make([]*Span, len(r.spans))
+	copy(spans, r.spans)
+	return spans
+}
+
+// Histograms the stage histograms sorted by stage
+func (r *Recorder) Histograms() []Histogram {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	histograms := make([]Histogram, 0, len(r.histograms))
+	for _, histogram := range r.histograms {
+		copied := *histogram
+		copied.BucketCounts = append([]uint64(nil), histogram.BucketCounts...)
+		histograms = append(histograms, copied)
+	}
+	sort.Slice(histograms, func(i, j int) bool {
+		return histograms[i].Stage < histograms[j].Stage
+	})
+	return histograms
+}
+
+func randomID(length int) string {
+	id := make([]byte, length)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,94 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSpansAndHistograms(t *testing.T) {
+	recorder := NewRecorder(Attr("apptype", "JavaLeveransepakke"))
+
+	ctx, build := recorder.StartSpan(context.Background(), "build")
+	_, download := recorder.StartSpan(ctx, "download")
+	download.Finish(nil)
+	_, push := recorder.StartSpan(ctx, "push_layer", Attr("digest", "sha256:1"))
+	push.Finish(errors.New("unauthorized"))
+	_, push = recorder.StartSpan(ctx, "push_layer", Attr("digest", "sha256:2"))
+	push.Finish(nil)
+	build.Finish(nil)
+
+	spans := recorder.Spans()
+	assert.Len(t, spans, 4)
+	assert.Equal(t, build.SpanID, spans[0].ParentID)
+	assert.Empty(t, spans[3].ParentID)
+	assert.Equal(t, spans[0].TraceID, spans[3].TraceID)
+
+	histograms := recorder.Histograms()
+	assert.Equal(t, []string{"build", "download", "push_layer"},
+		[]string{histograms[0].Stage, histograms[1].Stage, histograms[2].Stage})
+	assert.Equal(t, uint64(2), histograms[2].Count)
+	assert.Equal(t, uint64(2), histograms[2].BucketCounts[0])
+}
+
+func TestStartSpanWithoutRecorder(t *testing.T) {
+	SetDefault(nil)
+	ctx, span := StartSpan(context.Background(), "download")
+	assert.NotNil(t, ctx)
+	span.Finish(nil)
+}
+
+func TestWritePrometheusTextfile(t *testing.T) {
+	recorder := NewRecorder(Attr("apptype", "NodeJsLeveransepakke"))
+	_, span := recorder.StartSpan(context.Background(), "compress")
+	span.Finish(nil)
+
+	path := filepath.Join(t.TempDir(), "architect.prom")
+	assert.NoError(t, recorder.WritePrometheusTextfile(path))
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "# TYPE architect_stage_duration_seconds histogram")
+	assert.Contains(t, string(data), `architect_stage_duration_seconds_bucket{stage="compress",apptype="NodeJsLeveransepakke",le="0.1"} 1`)
+	assert.Contains(t, string(data), `architect_stage_duration_seconds_count{stage="compress",apptype="NodeJsLeveransepakke"} 1`)
+}
+
+func TestOTLPExport(t *testing.T) {
+	received := make(map[string][]byte)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received[r.URL.Path] = body
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	recorder := NewRecorder()
+	_, span := recorder.StartSpan(context.Background(), "push_manifest", Attr("tag", "latest"))
+	span.Finish(errors.New("denied"))
+
+	assert.NoError(t, NewOTLPExporter(server.URL+"/").Export(context.Background(), recorder))
+
+	var traces otlpTraces
+	assert.NoError(t, json.Unmarshal(received["/v1/traces"], &traces))
+	exported := traces.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	assert.Equal(t, "push_manifest", exported.Name)
+	assert.Equal(t, otlpStatusError, exported.Status.Code)
+	assert.Len(t, exported.TraceID, 32)
+
+	var metrics otlpMetrics
+	assert.NoError(t, json.Unmarshal(received["/v1/metrics"], &metrics))
+	dataPoint := metrics.ResourceMetrics[0].ScopeMetrics[0].Metrics[0].Histogram.DataPoints[0]
+	assert.Equal(t, "1", dataPoint.Count)
+	assert.Equal(t, "1", dataPoint.BucketCounts[0])
+	assert.Equal(t, "0", dataPoint.BucketCounts[1])
+	assert.True(t, bytes.Contains(received["/v1/metrics"], []byte(`"aggregationTemporality":2`)))
+}
@@ -2,6 +2,7 @@ package util
 
 import (
 	"archive/zip"
+	"context"
 	"github.com/pkg/errors"
 	"io"
 	"os"
@@ -22,7 +23,7 @@ const (
 )
 
 // ExtractAndRenameDeliverable extract and rename
-func ExtractAndRenameDeliverable(dockerBuildFolder string, deliverablePath string) error {
+func ExtractAndRenameDeliverable(ctx context.Context, dockerBuildFolder string, deliverablePath string) error {
 
 	applicationRoot := filepath.Join(dockerBuildFolder, DockerfileApplicationFolder)
 	renamedApplicationFolder := filepath.Join(dockerBuildFolder, ApplicationBuildFolder)
@@ -30,7 +31,7 @@ func ExtractAndRenameDeliverable(dockerBuildFolder string, deliverablePath strin
 		return errors.Wrap(err, "Failed to create application directory in Docker context")
 	}
 
-	if err := ExtractDeliverable(deliverablePath, applicationRoot); err != nil {
+	if err := ExtractDeliverable(ctx, deliverablePath, applicationRoot); err != nil {
 		return errors.Wrapf(err, "Failed to extract application archive")
 	}
 
@@ -41,8 +42,8 @@ func ExtractAndRenameDeliverable(dockerBuildFolder string, deliverablePath strin
 
 }
 
-// ExtractDeliverable extract archive to dest
-func ExtractDeliverable(archivePath string, extractedDirPath string) error {
+// ExtractDeliverable extract archive to dest. Stops between the entries when ctx is done
+func ExtractDeliverable(ctx context.Context, archivePath string, extractedDirPath string) error {
 
 	zipReader, err := zip.OpenReader(archivePath)
 
@@ -53,6 +54,9 @@ func ExtractDeliverable(archivePath string, extractedDirPath string) error {
 	defer zipReader.Close()
 
 	for _, zipEntry := range zipReader.File {
+		if err := ctx.Err(); err != nil {
+			return errors.Wrapf(err, "Extraction of %s cancelled", archivePath)
+		}
 
 		extractedPath := filepath.Join(extractedDirPath, zipEntry.Name)
 
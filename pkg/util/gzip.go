@@ -3,15 +3,20 @@ package util
 import (
 	"archive/tar"
 	"compress/gzip"
+	"context"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
 
-// CompressLayerTarGz compress folder
-func CompressLayerTarGz(src string, folder string, destination string) (string, error) {
+// CompressLayerTarGz compress folder. The ownership policy is applied to every tar header. The whiteouts are written
+// before the other entries of their directory, and whiteouts in directories outside the folder are written first.
+// Stops between the files when ctx is done
+func CompressLayerTarGz(ctx context.Context, src string, folder string, destination string, ownership OwnershipPolicy, whiteouts []Whiteout) (string, error) {
 
 	name := folder + "-layer.tar.gz"
 	file, err := os.Create(destination + "/" + name)
@@ -26,12 +31,29 @@ func CompressLayerTarGz(src string, folder string, destination string) (string,
 	defer tw.Close()
 
 	targetFolder := src + "/" + folder
+	// The whiteouts of each directory in the folder, written after the header of the directory
+	pending := make(map[string][]Whiteout)
+	for _, whiteout := range whiteouts {
+		dir := path.Dir(whiteout.TarName())
+		if info, err := os.Lstat(filepath.Join(src, dir)); err == nil && info.IsDir() &&
+			(dir == folder || strings.HasPrefix(dir, folder+"/")) {
+			pending[dir] = append(pending[dir], whiteout)
+			continue
+		}
+		if err := writeWhiteouts(tw, []Whiteout{whiteout}); err != nil {
+			return name, err
+		}
+	}
+
 	err = filepath.Walk(targetFolder,
 		func(path string, info os.FileInfo, err error) error {
 			// return on any error
 			if err != nil {
 				return err
 			}
+			if err := ctx.Err(); err != nil {
+				return errors.Wrapf(err, "Compression of %s cancelled", targetFolder)
+			}
 			isSymlink := info.Mode()&os.ModeSymlink == os.ModeSymlink
 
 			link := path
@@ -51,10 +73,20 @@ func CompressLayerTarGz(src string, folder string, destination string) (string,
 
 			header.Name = strings.TrimPrefix(strings.Replace(path, src, "", -1), string(filepath.Separator))
 
+			if err := ownership.Apply(header); err != nil {
+				return err
+			}
+
 			// write the header
 			if err := tw.WriteHeader(header); err != nil {
 				return err
 			}
+			if info.IsDir() {
+				if err := writeWhiteouts(tw, pending[header.Name]); err != nil {
+					return err
+				}
+				delete(pending, header.Name)
+			}
 
 			if !info.Mode().IsRegular() {
 				return nil
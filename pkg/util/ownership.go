@@ -0,0 +1,159 @@
+package util
+
+import (
+	"archive/tar"
+	"github.com/pkg/errors"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// OwnershipPreserve keep uid, gid and permissions from the build context
+	OwnershipPreserve = "preserve"
+	// OwnershipArbitraryUID uid 0, gid 0 and group permissions equal to user permissions.
+	// Files are then usable by the random uid OpenShift runs the container as, since that uid is always in group 0
+	OwnershipArbitraryUID = "arbitrary-uid"
+)
+
+// OwnershipPolicy decides the uid, gid and permissions written to the layer tar headers.
+// The zero value preserves ownership and permissions from the build context.
+type OwnershipPolicy struct {
+	Override    bool
+	UID         int
+	GID         int
+	GroupAsUser bool
+	Paths       []PathOwnership
+}
+
+// PathOwnership overrides the ownership policy for a path, and everything below it if the path is a directory.
+// Owner is given as uid:gid and Mode as an octal permission string. Both are optional.
+type PathOwnership struct {
+	Path  string `json:"path"`
+	Owner string `json:"owner"`
+	Mode  string `json:"mode"`
+}
+
+// ParseOwnershipPolicy parse preserve, arbitrary-uid or uid:gid
+func ParseOwnershipPolicy(value string) (OwnershipPolicy, error) {
+	value = strings.TrimSpace(value)
+	switch strings.ToLower(value) {
+	case "", OwnershipPreserve:
+		return OwnershipPolicy{}, nil
+	case OwnershipArbitraryUID:
+		return OwnershipPolicy{Override: true, UID: 0, GID: 0, GroupAsUser: true}, nil
+	}
+	uid, gid, err := parseOwner(value)
+	if err != nil {
+		return OwnershipPolicy{}, errors.Wrapf(err, "Unknown ownership policy %s. Use %s, %s or uid:gid",
+			value, OwnershipPreserve, OwnershipArbitraryUID)
+	}
+	return OwnershipPolicy{Override: true, UID: uid, GID: gid}, nil
+}
+
+// WithPaths return a copy of the policy with additional path overrides
+func (p OwnershipPolicy) WithPaths(paths []PathOwnership) OwnershipPolicy {
+	merged := make([]PathOwnership, 0, len(p.Paths)+len(paths))
+	merged = append(merged, p.Paths...)
+	merged = append(merged, paths...)
+	p.Paths = merged
+	return p
+}
+
+// Validate check the path overrides
+func (p OwnershipPolicy) Validate() error {
+	for _, override := range p.Paths {
+		if !strings.HasPrefix(override.Path, "/") {
+			return errors.Errorf("Ownership override path %s must be absolute", override.Path)
+		}
+		if override.Owner != "" {
+			if _, _, err := parseOwner(override.Owner); err != nil {
+				return errors.Wrapf(err, "Invalid owner for %s", override.Path)
+			}
+		}
+		if override.Mode != "" {
+			if _, err := parseMode(override.Mode); err != nil {
+				return errors.Wrapf(err, "Invalid mode for %s", override.Path)
+			}
+		}
+	}
+	return nil
+}
+
+// Apply the policy to a tar header. The header name is relative to the image root
+func (p OwnershipPolicy) Apply(header *tar.Header) error {
+	if p.Override {
+		setOwner(header, p.UID, p.GID)
+		if p.GroupAsUser {
+			userBits := header.Mode & 0700
+			header.Mode = header.Mode&^0070 | userBits>>3
+		}
+	}
+
+	imagePath := path.Clean("/" + header.Name)
+	for _, override := range p.matchingPaths(imagePath) {
+		if override.Owner != "" {
+			uid, gid, err := parseOwner(override.Owner)
+			if err != nil {
+				return errors.Wrapf(err, "Invalid owner for %s", override.Path)
+			}
+			setOwner(header, uid, gid)
+		}
+		if override.Mode != "" && header.Typeflag != tar.TypeSymlink {
+			mode, err := parseMode(override.Mode)
+			if err != nil {
+				return errors.Wrapf(err, "Invalid mode for %s", override.Path)
+			}
+			header.Mode = header.Mode&^0o7777 | mode
+		}
+	}
+	return nil
+}
+
+func (p OwnershipPolicy) matchingPaths(imagePath string) []PathOwnership {
+	var matches []PathOwnership
+	for _, override := range p.Paths {
+		overridePath := path.Clean(override.Path)
+		if imagePath == overridePath || strings.HasPrefix(imagePath, strings.TrimSuffix(overridePath, "/")+"/") {
+			matches = append(matches, override)
+		}
+	}
+	// The most specific path is applied last, so /u01/application/bin wins over /u01/application
+	sort.SliceStable(matches, func(i, j int) bool {
+		return len(path.Clean(matches[i].Path)) < len(path.Clean(matches[j].Path))
+	})
+	return matches
+}
+
+func setOwner(header *tar.Header, uid int, gid int) {
+	header.Uid = uid
+	header.Gid = gid
+	// The names are looked up on the build host and are meaningless in the image
+	header.Uname = ""
+	header.Gname = ""
+}
+
+func parseOwner(owner string) (int, int, error) {
+	parts := strings.Split(owner, ":")
+	if len(parts) != 2 {
+		return 0, 0, errors.Errorf("Owner %s must be on the form uid:gid", owner)
+	}
+	uid, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || uid < 0 {
+		return 0, 0, errors.Errorf("Owner %s has an invalid uid", owner)
+	}
+	gid, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil || gid < 0 {
+		return 0, 0, errors.Errorf("Owner %s has an invalid gid", owner)
+	}
+	return uid, gid, nil
+}
+
+func parseMode(mode string) (int64, error) {
+	value, err := strconv.ParseInt(strings.TrimSpace(mode), 8, 64)
+	if err != nil || value < 0 || value > 0o7777 {
+		return 0, errors.Errorf("Mode %s must be an octal permission between 0000 and 7777", mode)
+	}
+	return value, nil
+}
@@ -0,0 +1,76 @@
+package util_test
+
+import (
+	"archive/tar"
+	"github.com/skatteetaten/architect/v2/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseOwnershipPolicy(t *testing.T) {
+	policy, err := util.ParseOwnershipPolicy("")
+	assert.NoError(t, err)
+	assert.False(t, policy.Override)
+
+	policy, err = util.ParseOwnershipPolicy("arbitrary-uid")
+	assert.NoError(t, err)
+	assert.True(t, policy.Override)
+	assert.True(t, policy.GroupAsUser)
+	assert.Equal(t, 0, policy.UID)
+	assert.Equal(t, 0, policy.GID)
+
+	policy, err = util.ParseOwnershipPolicy("1001:0")
+	assert.NoError(t, err)
+	assert.True(t, policy.Override)
+	assert.False(t, policy.GroupAsUser)
+	assert.Equal(t, 1001, policy.UID)
+
+	_, err = util.ParseOwnershipPolicy("root")
+	assert.Error(t, err)
+	_, err = util.ParseOwnershipPolicy("1001:-1")
+	assert.Error(t, err)
+}
+
+func TestOwnershipPolicyApply(t *testing.T) {
+	policy, _ := util.ParseOwnershipPolicy("arbitrary-uid")
+
+	header := &tar.Header{Name: "u01/application/lib/app.jar", Mode: 0644, Uid: 1500, Gid: 1500, Uname: "jenkins"}
+	assert.NoError(t, policy.Apply(header))
+	assert.Equal(t, 0, header.Uid)
+	assert.Equal(t, 0, header.Gid)
+	assert.Equal(t, "", header.Uname)
+	assert.Equal(t, int64(0664), header.Mode)
+
+	header = &tar.Header{Name: "u01/bin/run", Mode: 0750}
+	assert.NoError(t, policy.Apply(header))
+	assert.Equal(t, int64(0770), header.Mode)
+}
+
+func TestOwnershipPolicyPathOverrides(t *testing.T) {
+	policy, _ := util.ParseOwnershipPolicy("arbitrary-uid")
+	policy = policy.WithPaths([]util.PathOwnership{
+		{Path: "/u01/application/bin/start.sh", Mode: "0555"},
+		{Path: "/u01/application", Owner: "1001:0"},
+		{Path: "/u01/secrets", Mode: "0700"},
+	})
+	assert.NoError(t, policy.Validate())
+
+	header := &tar.Header{Name: "u01/application/bin/start.sh", Mode: 0644}
+	assert.NoError(t, policy.Apply(header))
+	assert.Equal(t, 1001, header.Uid)
+	assert.Equal(t, int64(0555), header.Mode)
+
+	header = &tar.Header{Name: "u01/application/lib", Mode: 0755}
+	assert.NoError(t, policy.Apply(header))
+	assert.Equal(t, 1001, header.Uid)
+	assert.Equal(t, int64(0775), header.Mode)
+
+	header = &tar.Header{Name: "u01/secrets-other/file", Mode: 0644}
+	assert.NoError(t, policy.Apply(header))
+	assert.Equal(t, int64(0664), header.Mode)
+
+	invalid := util.OwnershipPolicy{}.WithPaths([]util.PathOwnership{{Path: "u01/relative", Mode: "0644"}})
+	assert.Error(t, invalid.Validate())
+	invalid = util.OwnershipPolicy{}.WithPaths([]util.PathOwnership{{Path: "/u01", Mode: "rwx"}})
+	assert.Error(t, invalid.Validate())
+}
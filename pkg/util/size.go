@@ -0,0 +1,54 @@
+package util
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var byteSizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30},
+	{"KB", 1000}, {"MB", 1000 * 1000}, {"GB", 1000 * 1000 * 1000},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30},
+	{"B", 1},
+}
+
+// ParseByteSize parse sizes like 512MiB, 2GB or 1048576. Zero means no limit
+func ParseByteSize(value string) (int64, error) {
+	number := strings.TrimSpace(value)
+	multiplier := int64(1)
+	for _, unit := range byteSizeUnits {
+		if strings.HasSuffix(strings.ToUpper(number), strings.ToUpper(unit.suffix)) {
+			number = strings.TrimSpace(number[:len(number)-len(unit.suffix)])
+			multiplier = unit.multiplier
+			break
+		}
+	}
+	size, err := strconv.ParseFloat(number, 64)
+	if err != nil || math.IsNaN(size) || math.IsInf(size, 0) || size < 0 {
+		return 0, errors.Errorf("Size %s must be a positive number with an optional unit, e.g. 512MiB", value)
+	}
+	bytes := size * float64(multiplier)
+	if bytes >= math.MaxInt64 {
+		return 0, errors.Errorf("Size %s is too large", value)
+	}
+	return int64(bytes), nil
+}
+
+// FormatByteSize format a size with a binary unit
+func FormatByteSize(size int64) string {
+	switch {
+	case size >= 1<<30:
+		return fmt.Sprintf("%.1fGiB", float64(size)/(1<<30))
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1fMiB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1fKiB", float64(size)/(1<<10))
+	}
+	return fmt.Sprintf("%dB", size)
+}
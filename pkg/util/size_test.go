@@ -0,0 +1,37 @@
+package util_test
+
+import (
+	"github.com/skatteetaten/architect/v2/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseByteSize(t *testing.T) {
+	for value, expected := range map[string]int64{
+		"1048576": 1048576,
+		"512MiB":  512 << 20,
+		"2gb":     2000000000,
+		"1.5G":    3 << 29,
+		"10 KiB":  10 << 10,
+		"0":       0,
+	} {
+		size, err := util.ParseByteSize(value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, expected, size, value)
+	}
+
+	_, err := util.ParseByteSize("big")
+	assert.Error(t, err)
+	_, err = util.ParseByteSize("-1MB")
+	assert.Error(t, err)
+	for _, value := range []string{"NaN", "Inf", "+InfGiB", "-Inf", "1e30GiB"} {
+		_, err = util.ParseByteSize(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestFormatByteSize(t *testing.T) {
+	assert.Equal(t, "512B", util.FormatByteSize(512))
+	assert.Equal(t, "1.5MiB", util.FormatByteSize(3<<19))
+	assert.Equal(t, "2.0GiB", util.FormatByteSize(2<<30))
+}
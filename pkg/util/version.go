@@ -1,6 +1,7 @@
 package util
 
 import (
+	"github.com/pkg/errors"
 	"regexp"
 	"strings"
 )
@@ -10,6 +11,9 @@ var versionWithOptionalMinorAndPatch = regexp.MustCompile(`^[0-9]{1,5}(\.[0-9]+(
 var versionWithMinorAndPatch = regexp.MustCompile(`^[0-9]+\.[0-9]+\.[0-9]+$|^[0-9]+\.[0-9]+\.[0-9]+\+([0-9A-Za-z]+)$`)
 var versionMeta = regexp.MustCompile(`\+([0-9A-Za-z]+)$`)
 
+// The pre-release must start with a letter, e.g. 2.3.0-rc.1 or 2.3.0-beta2. The letters are the release channel
+var preReleaseVersion = regexp.MustCompile(`^([0-9]+\.[0-9]+)\.[0-9]+-([A-Za-z]+)[0-9A-Za-z-]*(\.[0-9A-Za-z-]+)*$`)
+
 // IsFullSemanticVersion check version
 func IsFullSemanticVersion(versionString string) bool {
 	if versionWithMinorAndPatch.MatchString(versionString) {
@@ -18,6 +22,28 @@ func IsFullSemanticVersion(versionString string) bool {
 	return false
 }
 
+// IsPreReleaseVersion check if the version is a pre-release of a full semantic version. SNAPSHOT is not a pre-release
+func IsPreReleaseVersion(versionString string) bool {
+	matches := preReleaseVersion.FindStringSubmatch(versionString)
+	return matches != nil && !strings.EqualFold(matches[2], "SNAPSHOT")
+}
+
+// GetPreReleaseChannel the lower case release channel, e.g. rc for 2.3.0-rc.1. Empty if not a pre-release
+func GetPreReleaseChannel(versionString string) string {
+	if !IsPreReleaseVersion(versionString) {
+		return ""
+	}
+	return strings.ToLower(preReleaseVersion.FindStringSubmatch(versionString)[2])
+}
+
+// GetPreReleaseMinor the major and minor version of a pre-release, e.g. 2.3 for 2.3.0-rc.1
+func GetPreReleaseMinor(versionString string) string {
+	if !IsPreReleaseVersion(versionString) {
+		return ""
+	}
+	return preReleaseVersion.FindStringSubmatch(versionString)[1]
+}
+
 // IsSemanticVersion check version
 func IsSemanticVersion(versionString string) bool {
 	if versionWithOptionalMinorAndPatch.MatchString(versionString) {
@@ -43,6 +69,16 @@ func GetVersionWithoutMetadata(versionString string) string {
 	return strings.Replace(versionString, "+"+matches[1], "", -1)
 }
 
+// AddVersionMetadata append build metadata to the version, e.g. 1.2.3+wingnut17. The tagger only accepts a single
+// metadata identifier, so a version that already has metadata is an error
+func AddVersionMetadata(versionString string, metadata string) (string, error) {
+	if strings.Contains(versionString, "+") {
+		return "", errors.Errorf("Version %s already has build metadata, and can not get the metadata %s",
+			versionString, metadata)
+	}
+	return versionString + "+" + metadata, nil
+}
+
 // GetVersionMetadata get version metadata
 func GetVersionMetadata(versionString string) string {
 	matches := versionMeta.FindStringSubmatch(versionString)
@@ -74,3 +74,33 @@ func TestGetVersionOnly(t *testing.T) {
 	assert.Equal(t, "2.a.b", util.GetVersionWithoutMetadata("2.a.b"))
 	assert.Equal(t, "2.a.b", util.GetVersionWithoutMetadata("2.a.b+metadata"))
 }
+
+func TestPreReleaseVersion(t *testing.T) {
+	assert.True(t, util.IsPreReleaseVersion("2.3.0-rc.1"))
+	assert.True(t, util.IsPreReleaseVersion("2.3.0-beta2"))
+	assert.True(t, util.IsPreReleaseVersion("2.3.0-alpha.1.x-y"))
+	assert.False(t, util.IsPreReleaseVersion("2.3.0"))
+	assert.False(t, util.IsPreReleaseVersion("2.3-rc.1"))
+	assert.False(t, util.IsPreReleaseVersion("12.11.12-23"))
+	assert.False(t, util.IsPreReleaseVersion("2.3.0-SNAPSHOT"))
+	assert.False(t, util.IsPreReleaseVersion("2.3.0-rc..1"))
+
+	assert.Equal(t, "rc", util.GetPreReleaseChannel("2.3.0-RC.1"))
+	assert.Equal(t, "beta", util.GetPreReleaseChannel("2.3.0-beta2"))
+	assert.Equal(t, "", util.GetPreReleaseChannel("2.3.0"))
+	assert.Equal(t, "2.3", util.GetPreReleaseMinor("2.3.0-rc.1"))
+}
+
+func TestAddVersionMetadata(t *testing.T) {
+	version, err := util.AddVersionMetadata("1.2.3", "wingnut17")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.2.3+wingnut17", version)
+	assert.True(t, util.IsFullSemanticVersion(version))
+	assert.Equal(t, "wingnut17", util.GetVersionMetadata(version))
+
+	// The tagger does not accept 1.2.3+build.wingnut17
+	assert.False(t, util.IsFullSemanticVersion("1.2.3+build.wingnut17"))
+	assert.Equal(t, "", util.GetVersionMetadata("1.2.3+build.wingnut17"))
+	_, err = util.AddVersionMetadata("1.2.3+build", "wingnut17")
+	assert.EqualError(t, err, "Version 1.2.3+build already has build metadata, and can not get the metadata wingnut17")
+}
@@ -0,0 +1,159 @@
+package util
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	// WhiteoutPrefix marks a file in a lower layer as deleted
+	WhiteoutPrefix = ".wh."
+	// WhiteoutOpaque hides all content of a directory in the lower layers
+	WhiteoutOpaque = WhiteoutPrefix + WhiteoutPrefix + ".opq"
+)
+
+// Whiteout a path from the base image. An opaque whiteout keeps the directory,
+// but hides everything the lower layers put in it
+type Whiteout struct {
+	Path   string
+	Opaque bool
+}
+
+// NewWhiteouts create whiteouts for removed paths and opaque whiteouts for replaced directories
+func NewWhiteouts(remove []string, replaceDirectories []string) []Whiteout {
+	whiteouts := make([]Whiteout, 0, len(remove)+len(replaceDirectories))
+	for _, p := range remove {
+		whiteouts = append(whiteouts, Whiteout{Path: p})
+	}
+	for _, p := range replaceDirectories {
+		whiteouts = append(whiteouts, Whiteout{Path: p, Opaque: true})
+	}
+	return whiteouts
+}
+
+// ImagePath the absolute and cleaned path in the image
+func (w Whiteout) ImagePath() string {
+	return path.Clean(w.Path)
+}
+
+// TopLevelFolder the first path element, e.g. u01 for /u01/application
+func (w Whiteout) TopLevelFolder() string {
+	return strings.SplitN(strings.TrimPrefix(w.ImagePath(), "/"), "/", 2)[0]
+}
+
+// TarName the name of the whiteout entry in the layer tar
+func (w Whiteout) TarName() string {
+	relative := strings.TrimPrefix(w.ImagePath(), "/")
+	if w.Opaque {
+		return path.Join(relative, WhiteoutOpaque)
+	}
+	return path.Join(path.Dir(relative), WhiteoutPrefix+path.Base(relative))
+}
+
+// Validate the whiteout path, and check it against the base image file system if known
+func (w Whiteout) Validate(baseImageFiles ImageFileSystem) error {
+	if !strings.HasPrefix(w.Path, "/") {
+		return errors.Errorf("Path %s must be absolute", w.Path)
+	}
+	if w.ImagePath() == "/" {
+		return errors.Errorf("The root directory can not be removed")
+	}
+	if strings.HasPrefix(path.Base(w.ImagePath()), WhiteoutPrefix) {
+		return errors.Errorf("Path %s can not start with %s", w.Path, WhiteoutPrefix)
+	}
+	if baseImageFiles == nil {
+		return nil
+	}
+	isDir, exists := baseImageFiles[w.ImagePath()]
+	if !exists {
+		return errors.Errorf("Path %s does not exist in the base image", w.Path)
+	}
+	if w.Opaque && !isDir {
+		return errors.Errorf("Path %s is not a directory in the base image", w.Path)
+	}
+	return nil
+}
+
+func (w Whiteout) header() *tar.Header {
+	return &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     w.TarName(),
+		Mode:     0,
+		ModTime:  time.Unix(0, 0),
+	}
+}
+
+func writeWhiteouts(tw *tar.Writer, whiteouts []Whiteout) error {
+	for _, whiteout := range whiteouts {
+		if err := tw.WriteHeader(whiteout.header()); err != nil {
+			return errors.Wrapf(err, "Failed to write whiteout for %s", whiteout.Path)
+		}
+	}
+	return nil
+}
+
+// ImageFileSystem absolute paths in an image, mapped to whether the path is a directory
+type ImageFileSystem map[string]bool
+
+// ListLayerFiles list the resulting file system of gzipped layer tars, applied bottom up
+func ListLayerFiles(layerPaths []string) (ImageFileSystem, error) {
+	fileSystem := make(ImageFileSystem)
+	for _, layerPath := range layerPaths {
+		if err := fileSystem.applyLayer(layerPath); err != nil {
+			return nil, errors.Wrapf(err, "Failed to list files in layer %s", layerPath)
+		}
+	}
+	return fileSystem, nil
+}
+
+func (fs ImageFileSystem) applyLayer(layerPath string) error {
+	file, err := os.Open(layerPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := path.Clean("/" + header.Name)
+		base := path.Base(name)
+		switch {
+		case base == WhiteoutOpaque:
+			fs.removeChildren(path.Dir(name))
+		case strings.HasPrefix(base, WhiteoutPrefix):
+			removed := path.Join(path.Dir(name), strings.TrimPrefix(base, WhiteoutPrefix))
+			delete(fs, removed)
+			fs.removeChildren(removed)
+		default:
+			fs[name] = header.Typeflag == tar.TypeDir
+		}
+	}
+}
+
+func (fs ImageFileSystem) removeChildren(dir string) {
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	for name := range fs {
+		if strings.HasPrefix(name, prefix) {
+			delete(fs, name)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package util_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"github.com/skatteetaten/architect/v2/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWhiteoutTarName(t *testing.T) {
+	assert.Equal(t, "u01/config/.wh.default.yaml", util.Whiteout{Path: "/u01/config/default.yaml"}.TarName())
+	assert.Equal(t, "u01/sample/.wh..wh..opq", util.Whiteout{Path: "/u01/sample/", Opaque: true}.TarName())
+	assert.Equal(t, "u01", util.Whiteout{Path: "/u01/sample"}.TopLevelFolder())
+}
+
+func TestWhiteoutValidate(t *testing.T) {
+	files := util.ImageFileSystem{"/u01": true, "/u01/sample": true, "/u01/config.yaml": false}
+
+	assert.NoError(t, util.Whiteout{Path: "/u01/config.yaml"}.Validate(files))
+	assert.NoError(t, util.Whiteout{Path: "/u01/sample", Opaque: true}.Validate(files))
+	assert.NoError(t, util.Whiteout{Path: "/not/known"}.Validate(nil))
+	assert.Error(t, util.Whiteout{Path: "/u01/missing"}.Validate(files))
+	assert.Error(t, util.Whiteout{Path: "/u01/config.yaml", Opaque: true}.Validate(files))
+	assert.Error(t, util.Whiteout{Path: "u01/relative"}.Validate(nil))
+	assert.Error(t, util.Whiteout{Path: "/"}.Validate(nil))
+}
+
+func TestListLayerFilesAppliesWhiteouts(t *testing.T) {
+	dir := t.TempDir()
+	lower := writeLayer(t, dir, "lower.tar.gz", []*tar.Header{
+		{Name: "u01/", Typeflag: tar.TypeDir},
+		{Name: "u01/sample/", Typeflag: tar.TypeDir},
+		{Name: "u01/sample/index.html", Typeflag: tar.TypeReg},
+		{Name: "u01/lib/", Typeflag: tar.TypeDir},
+		{Name: "u01/lib/vulnerable.jar", Typeflag: tar.TypeReg},
+	})
+	upper := writeLayer(t, dir, "upper.tar.gz", []*tar.Header{
+		{Name: "u01/lib/.wh.vulnerable.jar", Typeflag: tar.TypeReg},
+		{Name: "u01/sample/.wh..wh..opq", Typeflag: tar.TypeReg},
+	})
+
+	files, err := util.ListLayerFiles([]string{lower, upper})
+	assert.NoError(t, err)
+	assert.Equal(t, util.ImageFileSystem{"/u01": true, "/u01/sample": true, "/u01/lib": true}, files)
+}
+
+func TestCompressLayerTarGzWritesWhiteouts(t *testing.T) {
+	src := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(src, "u01", "config"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(src, "u01", "config", "app.yaml"), []byte("app"), 0644))
+
+	destination := t.TempDir()
+	name, err := util.CompressLayerTarGz(context.Background(), src, "u01", destination, util.OwnershipPolicy{},
+		util.NewWhiteouts([]string{"/u01/config/default.yaml"}, []string{"/u01/sample"}))
+	assert.NoError(t, err)
+
+	file, err := os.Open(filepath.Join(destination, name))
+	assert.NoError(t, err)
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	assert.NoError(t, err)
+	tr := tar.NewReader(gz)
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		names = append(names, header.Name)
+	}
+	assert.Equal(t, []string{"u01/sample/.wh..wh..opq", "u01", "u01/config", "u01/config/.wh.default.yaml",
+		"u01/config/app.yaml"}, names)
+}
+
+func writeLayer(t *testing.T, dir string, name string, headers []*tar.Header) string {
+	layerPath := filepath.Join(dir, name)
+	file, err := os.Create(layerPath)
+	assert.NoError(t, err)
+	defer file.Close()
+	gw := gzip.NewWriter(file)
+	defer gw.Close()
+	tw := tar.NewWriter(gw)
+	defer tw.Close()
+	for _, header := range headers {
+		assert.NoError(t, tw.WriteHeader(header))
+	}
+	return layerPath
+}
+
+func TestCompressLayerTarGzCancelled(t *testing.T) {
+	src := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(src, "u01"), 0755))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := util.CompressLayerTarGz(ctx, src, "u01", t.TempDir(), util.OwnershipPolicy{}, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,200 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/skatteetaten/architect/v2/pkg/config"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	Started   = "started"
+	Prepared  = "prepared"
+	Pushed    = "pushed"
+	Failed    = "failed"
+	Retagged  = "retagged"
+	userAgent = "architect-webhook"
+)
+
+// SignatureHeader hex encoded HMAC-SHA256 of the request body, prefixed with sha256=
+const SignatureHeader = "X-Architect-Signature-256"
+
+// EventHeader the event type
+const EventHeader = "X-Architect-Event"
+
+// Event a build lifecycle event
+type Event struct {
+	Type            string   `json:"type"`
+	Time            string   `json:"time"`
+	BuildID         string   `json:"buildId,omitempty"`
+	ApplicationType string   `json:"applicationType"`
+	Registry        string   `json:"registry"`
+	Repository      string   `json:"repository"`
+	Version         string   `json:"version,omitempty"`
+	Tags            []string `json:"tags,omitempty"`
+	Digest          string   `json:"digest,omitempty"`
+	TagDecisions    []string `json:"tagDecisions,omitempty"`
+	ErrorCategory   string   `json:"errorCategory,omitempty"`
+	Stage           string   `json:"stage,omitempty"`
+	Error           string   `json:"error,omitempty"`
+}
+
+// Notifier send build lifecycle events. Delivery failures are logged, and never returned
+type Notifier interface {
+	Notify(ctx context.Context, event Event)
+}
+
+// NewClient create a Notifier for the webhooks in the build config. Nothing is sent when there are no webhooks
+func NewClient(cfg *config.Config) Notifier {
+	return &webhookClient{
+		endpoints:       cfg.Webhooks,
+		buildID:         cfg.OwnerReferenceUUID,
+		applicationType: string(cfg.ApplicationType),
+		registry:        cfg.DockerSpec.OutputRegistry,
+		repository:      cfg.DockerSpec.OutputRepository,
+		client:          &http.Client{},
+		attempts:        3,
+		attemptTimeout:  5 * time.Second,
+		backoff:         time.Second,
+		budget:          30 * time.Second,
+	}
+}
+
+type webhookClient struct {
+	endpoints       []config.WebhookEndpoint
+	buildID         string
+	applicationType string
+	registry        string
+	repository      string
+	client          *http.Client
+	attempts        int
+	attemptTimeout  time.Duration
+	backoff         time.Duration
+	// budget the total time the build may spend delivering events. Events are dropped when it is used
+	budget time.Duration
+	mu     sync.Mutex
+	spent  time.Duration
+}
+
+// Notify send the event to every webhook
+func (c *webhookClient) Notify(ctx context.Context, event Event) {
+	if len(c.endpoints) == 0 {
+		return
+	}
+	event.Time = time.Now().UTC().Format(time.RFC3339)
+	event.BuildID = c.buildID
+	event.ApplicationType = c.applicationType
+	// Events of the output targets name their own registry and repository
+	if event.Registry == "" {
+		event.Registry = c.registry
+		event.Repository = c.repository
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		logrus.Warnf("Unable to marshal %s event: %v", event.Type, err)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, endpoint := range c.endpoints {
+		remaining := c.budget - c.spent
+		if remaining <= 0 {
+			logrus.Warnf("The webhook delivery time of %s is used. Not sending %s event to %s", c.budget,
+				event.Type, endpoint.URL)
+			continue
+		}
+		// The build may have been cancelled, but a failed event must still be delivered
+		deliverCtx, cancel := context.WithTimeout(detach(ctx), remaining)
+		start := time.Now()
+		err := c.deliver(deliverCtx, endpoint, event.Type, body)
+		c.spent += time.Since(start)
+		cancel()
+		if err != nil {
+			logrus.Warnf("Unable to send %s event to %s: %v", event.Type, endpoint.URL, err)
+		}
+	}
+}
+
+func (c *webhookClient) deliver(ctx context.Context, endpoint config.WebhookEndpoint, eventType string, body []byte) error {
+	var err error
+	for attempt := 1; attempt <= c.attempts; attempt++ {
+		var retry bool
+		retry, err = c.post(ctx, endpoint, eventType, body)
+		if err == nil || !retry {
+			return err
+		}
+		if attempt < c.attempts {
+			logrus.Debugf("Webhook %s failed, attempt %d of %d: %v", endpoint.URL, attempt, c.attempts, err)
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(c.backoff * time.Duration(attempt)):
+			}
+		}
+	}
+	return err
+}
+
+// post returns true if the request should be retried
+func (c *webhookClient) post(ctx context.Context, endpoint config.WebhookEndpoint, eventType string, body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.attemptTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, errors.Wrap(err, "Unable to create request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(EventHeader, eventType)
+	if endpoint.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(endpoint.Secret, body))
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return true, errors.Wrap(err, "Request failed")
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		return true, errors.Errorf("Got http status %d", resp.StatusCode)
+	}
+	if resp.StatusCode >= 300 {
+		return false, errors.Errorf("Got http status %d", resp.StatusCode)
+	}
+	return false, nil
+}
+
+// Sign create the signature header value for a body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// detach keep the values of ctx, but not the deadline or cancellation
+func detach(ctx context.Context) context.Context {
+	return detachedContext{parent: ctx}
+}
+
+type detachedContext struct {
+	parent context.Context
+}
+
+func (d detachedContext) Deadline() (time.Time, bool)       { return time.Time{}, false }
+func (d detachedContext) Done() <-chan struct{}             { return nil }
+func (d detachedContext) Err() error                        { return nil }
+func (d detachedContext) Value(key interface{}) interface{} { return d.parent.Value(key) }
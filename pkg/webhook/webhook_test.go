@@ -0,0 +1,115 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/skatteetaten/architect/v2/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testClient(urls ...string) *webhookClient {
+	var endpoints []config.WebhookEndpoint
+	for _, url := range urls {
+		endpoints = append(endpoints, config.WebhookEndpoint{URL: url, Secret: "s3cret"})
+	}
+	client := NewClient(&config.Config{
+		ApplicationType: config.JavaLeveransepakke,
+		Webhooks:        endpoints,
+		DockerSpec: config.DockerSpec{
+			OutputRegistry:   "registry:5000",
+			OutputRepository: "aurora/app",
+		},
+	}).(*webhookClient)
+	client.backoff = time.Millisecond
+	return client
+}
+
+func TestNotifySignsEvent(t *testing.T) {
+	var received Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, Sign("s3cret", body), r.Header.Get(SignatureHeader))
+		assert.Equal(t, Pushed, r.Header.Get(EventHeader))
+		assert.NoError(t, json.Unmarshal(body, &received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	testClient(server.URL).Notify(context.Background(), Event{
+		Type:   Pushed,
+		Tags:   []string{"1.2.3", "latest"},
+		Digest: "sha256:abc",
+	})
+
+	assert.Equal(t, Pushed, received.Type)
+	assert.Equal(t, "aurora/app", received.Repository)
+	assert.Equal(t, "JavaLeveransepakke", received.ApplicationType)
+	assert.Equal(t, []string{"1.2.3", "latest"}, received.Tags)
+	assert.NotEmpty(t, received.Time)
+}
+
+func TestNotifyRetriesServerErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	testClient(server.URL).Notify(context.Background(), Event{Type: Started})
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestNotifyDoesNotRetryClientErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	testClient(server.URL).Notify(context.Background(), Event{Type: Started})
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestNotifyIgnoresCancelledBuild(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	testClient(server.URL).Notify(ctx, Event{Type: Failed, ErrorCategory: "timeout"})
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestNotifyStopsWhenTheDeliveryTimeIsUsed(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := testClient(server.URL, server.URL)
+	client.backoff = 50 * time.Millisecond
+	client.budget = 20 * time.Millisecond
+
+	start := time.Now()
+	client.Notify(context.Background(), Event{Type: Started})
+	client.Notify(context.Background(), Event{Type: Failed})
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}